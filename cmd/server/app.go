@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+// component is one part of the server with a lifecycle. run, if set, serves
+// until stop is called and only returns an error if serving failed. stop, if
+// set, shuts the component down, giving up when ctx ends.
+type component struct {
+	name string
+	run  func() error
+	stop func(ctx context.Context) error
+}
+
+// App owns the server's components. It runs them together and stops them in
+// the reverse of the order they were added, so each is stopped before the
+// things it depends on: servers first, then background workers, then pending
+// writes, then connections.
+type App struct {
+	cfg        *config.Config
+	components []component
+}
+
+func newApp(cfg *config.Config) *App {
+	return &App{cfg: cfg}
+}
+
+func (a *App) add(c component) {
+	a.components = append(a.components, c)
+}
+
+// Run starts every component and blocks until ctx is cancelled or one of
+// them fails. It then shuts down within SHUTDOWN_TIMEOUT and returns the
+// failure, if any, along with anything that did not stop cleanly.
+func (a *App) Run(ctx context.Context) error {
+	failed := make(chan error, len(a.components))
+	for _, c := range a.components {
+		if c.run == nil {
+			continue
+		}
+		go func() {
+			if err := c.run(); err != nil {
+				failed <- fmt.Errorf("%s: %w", c.name, err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down", "timeout", a.cfg.Server.ShutdownTimeout)
+	case runErr = <-failed:
+		slog.Error("Shutting down after a failure", "err", runErr)
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(runErr, a.Shutdown(stopCtx))
+}
+
+// Shutdown stops the components in reverse order. Every component is asked
+// to stop even once ctx has ended, so connections are still closed when
+// draining overran.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+	for i := len(a.components) - 1; i >= 0; i-- {
+		c := a.components[i]
+		if c.stop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := c.stop(ctx); err != nil {
+			slog.Error("Failed to stop cleanly", "component", c.name, "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		slog.Info("Stopped", "component", c.name, "duration_ms", time.Since(start).Milliseconds())
+	}
+	return errors.Join(errs...)
+}
+
+// httpComponent serves srv on lis. Stopping it refuses new connections and
+// waits for requests in flight; those still running when ctx ends are cut
+// off.
+func httpComponent(name string, srv *http.Server, lis net.Listener) component {
+	return component{
+		name: name,
+		run: func() error {
+			slog.Info("HTTP server listening", "addr", lis.Addr().String())
+			if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// grpcComponent serves srv on lis, draining calls in flight like
+// httpComponent.
+func grpcComponent(name string, srv *grpc.Server, lis net.Listener) component {
+	return component{
+		name: name,
+		run: func() error {
+			slog.Info("gRPC server listening", "addr", lis.Addr().String())
+			return srv.Serve(lis)
+		},
+		stop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			if err := waitFor(ctx, stopped); err != nil {
+				srv.Stop()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// workerComponent runs work until stopped by cancelling its context; work
+// must return once that context ends.
+func workerComponent(name string, work func(ctx context.Context)) component {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	return component{
+		name: name,
+		run: func() error {
+			defer close(done)
+			work(ctx)
+			return nil
+		},
+		stop: func(stopCtx context.Context) error {
+			cancel()
+			return waitFor(stopCtx, done)
+		},
+	}
+}
+
+// waitFor waits for done to be closed or ctx to end.
+func waitFor(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+// stopLog records the order components are stopped in.
+type stopLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (l *stopLog) component(name string) component {
+	return component{name: name, stop: func(context.Context) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.names = append(l.names, name)
+		return nil
+	}}
+}
+
+func (l *stopLog) order() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.names...)
+}
+
+func testApp(shutdownTimeout time.Duration) *App {
+	return newApp(&config.Config{Server: config.ServerConfig{ShutdownTimeout: shutdownTimeout}})
+}
+
+func TestApp_ShutdownOrder(t *testing.T) {
+	var log stopLog
+	app := testApp(time.Second)
+	app.add(log.component("postgres"))
+	app.add(log.component("request logs"))
+	app.add(workerComponent("job workers", func(ctx context.Context) {
+		<-ctx.Done()
+		log.component("job workers").stop(ctx)
+	}))
+	app.add(log.component("HTTP server"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.Run(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"HTTP server", "job workers", "request logs", "postgres"}
+	if got := log.order(); !slices.Equal(got, want) {
+		t.Fatalf("expected stop order %v, got %v", want, got)
+	}
+}
+
+func TestApp_ComponentFailure(t *testing.T) {
+	var log stopLog
+	app := testApp(time.Second)
+	app.add(log.component("postgres"))
+	boom := errors.New("address in use")
+	app.add(component{name: "gRPC server", run: func() error { return boom }})
+
+	// The context never ends; the failure alone stops the app.
+	err := app.Run(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected the failure to be returned, got %v", err)
+	}
+	if got := log.order(); !slices.Equal(got, []string{"postgres"}) {
+		t.Fatalf("expected the rest to be stopped, got %v", got)
+	}
+}
+
+func TestApp_ShutdownDeadline(t *testing.T) {
+	var log stopLog
+	app := testApp(50 * time.Millisecond)
+	app.add(log.component("postgres"))
+	app.add(component{name: "stuck", stop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := app.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the overrun to be reported, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown ignored its deadline: took %v", elapsed)
+	}
+	if got := log.order(); !slices.Equal(got, []string{"postgres"}) {
+		t.Fatalf("expected later components to be stopped anyway, got %v", got)
+	}
+}
+
+func TestApp_DrainsHTTPBeforeFlushing(t *testing.T) {
+	var log stopLog
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := testApp(5 * time.Second)
+	app.add(log.component("request logs"))
+	app.add(httpComponent("HTTP server", srv, lis))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- app.Run(ctx) }()
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + lis.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+
+	// The request in flight holds up everything behind the HTTP server.
+	time.Sleep(50 * time.Millisecond)
+	if got := log.order(); len(got) != 0 {
+		t.Fatalf("expected nothing stopped while a request is in flight, got %v", got)
+	}
+	close(release)
+	if got := <-body; got != "done" {
+		t.Fatalf("expected the request to complete, got %q", got)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := log.order(); !slices.Equal(got, []string{"request logs"}) {
+		t.Fatalf("expected logs flushed after draining, got %v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/willexm1/go-llm-nexus/internal/adapters/batch"
+	myHttp "github.com/willexm1/go-llm-nexus/internal/adapters/handler/http"
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// runBatch implements the batch subcommand and returns the exit code:
+//
+//	server batch -in requests.jsonl [-out results.jsonl] [-concurrency 8] [-rate openai=600]
+//
+// Interrupting it is safe; running it again with the same files resumes.
+func runBatch(app *App, args []string) int {
+	cfg := app.cfg
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	in := fs.String("in", "", "JSONL file of /generate request bodies (required)")
+	out := fs.String("out", "", "JSONL file for results (default: <in>.results.jsonl)")
+	concurrency := fs.Int("concurrency", cfg.Batch.Concurrency, "requests in flight at once")
+	rates := fs.String("rate", "", "requests per minute per provider, e.g. openai=600,gemini=300 (default: BATCH_RATE_LIMITS)")
+	summaryPath := fs.String("summary", "", "also write the summary report to this file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "batch: -in is required")
+		fs.Usage()
+		return 2
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*in, ".jsonl") + ".results.jsonl"
+	}
+	rateLimits := cfg.Batch.RateLimits
+	if *rates != "" {
+		var err error
+		if rateLimits, err = config.ParseProviderInts("-rate", *rates); err != nil {
+			fmt.Fprintf(os.Stderr, "batch: %v\n", err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	llmService := app.newService().llm
+	slog.Info("Running batch", "input", *in, "output", *out)
+	summary, err := batch.Run(ctx, llmService, myHttp.DecodeBatchLine, *in, *out, services.BatchOptions{
+		Concurrency: *concurrency,
+		RateLimits:  rateLimits,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Warn("Batch interrupted; run the same command again to resume")
+		} else {
+			slog.Error("Batch failed", "err", err)
+		}
+		return 1
+	}
+
+	report, _ := json.MarshalIndent(summary, "", "  ")
+	fmt.Println(string(report))
+	if *summaryPath != "" {
+		if err := os.WriteFile(*summaryPath, append(report, '\n'), 0o644); err != nil {
+			slog.Error("Failed to write batch summary", "err", err)
+			return 1
+		}
+	}
+	return 0
+}
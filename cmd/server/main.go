@@ -1,58 +1,305 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"github.com/willexm1/go-llm-nexus/internal/adapters/batch"
+	myGrpc "github.com/willexm1/go-llm-nexus/internal/adapters/handler/grpc"
 	myHttp "github.com/willexm1/go-llm-nexus/internal/adapters/handler/http"
+	"github.com/willexm1/go-llm-nexus/internal/adapters/handler/openaicompat"
+	"github.com/willexm1/go-llm-nexus/internal/adapters/metrics"
 	"github.com/willexm1/go-llm-nexus/internal/adapters/repository"
+	"github.com/willexm1/go-llm-nexus/internal/adapters/tracing"
 	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
 	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	"github.com/willexm1/go-llm-nexus/internal/core/tools"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
+	nexusv1 "github.com/willexm1/go-llm-nexus/proto/nexus/v1"
 )
 
 func main() {
 	// 1. Load Config
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkCORS(cfg.CORS); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid CORS config: %v\n", err)
+		os.Exit(1)
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	app := newApp(cfg)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("Failed to set up tracing", "err", err)
+	}
+	app.add(component{name: "tracing", stop: shutdownTracing})
+
+	// "server batch ..." runs a JSONL file offline instead of serving.
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		code := runBatch(app, os.Args[2:])
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		err := app.Shutdown(ctx)
+		cancel()
+		if err != nil && code == 0 {
+			code = 1
+		}
+		os.Exit(code)
+	}
+
+	promMetrics := metrics.NewPrometheus()
+	svcs := app.newService(services.WithMetrics(promMetrics))
+	llmService := svcs.llm
+
+	// Background job workers; every replica takes from the shared queue.
+	// Jobs cut short by a shutdown run again once their lease expires.
+	app.add(workerComponent("job workers", func(ctx context.Context) {
+		svcs.jobs.RunJobWorkers(ctx, cfg.Jobs.Workers)
+	}))
+	slog.Info("Job workers started", "workers", cfg.Jobs.Workers)
+
+	// Uploaded batches, resumed if the server stopped while they ran
+	batches := batch.NewManager(cfg.Batch.Dir, llmService, myHttp.DecodeBatchLine, services.BatchOptions{
+		Concurrency: cfg.Batch.Concurrency,
+		RateLimits:  cfg.Batch.RateLimits,
+	})
+	batchCtx, stopBatches := context.WithCancel(context.Background())
+	if err := batches.Start(batchCtx); err != nil {
+		fatal("Failed to prepare batch directory", "err", err)
+	}
+	app.add(component{name: "batches", stop: func(ctx context.Context) error {
+		stopBatches()
+		return batches.Wait(ctx)
+	}})
+
+	// 4. gRPC Server
+	grpcLis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.Server.GRPCPort))
+	if err != nil {
+		fatal("Failed to listen for gRPC", "err", err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(myGrpc.UnaryAuthInterceptor(llmService)),
+		grpc.StreamInterceptor(myGrpc.StreamAuthInterceptor(llmService)),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	)
+	nexusv1.RegisterNexusServiceServer(grpcServer, myGrpc.NewServer(llmService))
+	app.add(grpcComponent("gRPC server", grpcServer, grpcLis))
+
+	// 5. HTTP Server
+	limits := myHttp.Limits{
+		MaxBodyBytes:        cfg.Limits.MaxRequestBytes,
+		RejectUnknownFields: cfg.Limits.RejectUnknownFields,
+	}
+	httpHandler := myHttp.NewHandler(llmService, limits)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/generate", httpHandler.Generate)
+	healthHandler := myHttp.NewHealthHandler(svcs.health)
+	mux.HandleFunc("GET /api/health", healthHandler.Health)
+	mux.HandleFunc("GET /api/health/live", healthHandler.Liveness)
+	mux.HandleFunc("GET /api/health/ready", healthHandler.Readiness)
+	mux.HandleFunc("POST /api/users", httpHandler.RegisterUser)
+	mux.HandleFunc("GET /api/models", httpHandler.ListModels)
+	mux.HandleFunc("POST /api/embeddings", httpHandler.Embeddings)
+	mux.HandleFunc("POST /api/tokenize", httpHandler.Tokenize)
+	collectionHandler := myHttp.NewCollectionHandler(svcs.knowledge, limits)
+	mux.HandleFunc("POST /api/collections", collectionHandler.CreateCollection)
+	mux.HandleFunc("POST /api/collections/{id}/documents", collectionHandler.AddDocument)
+	templateHandler := myHttp.NewTemplateHandler(svcs.templates, limits)
+	mux.HandleFunc("GET /api/templates", templateHandler.ListTemplates)
+	mux.HandleFunc("POST /api/templates", templateHandler.CreateTemplate)
+	mux.HandleFunc("GET /api/templates/{name}", templateHandler.GetTemplate)
+	mux.HandleFunc("DELETE /api/templates/{name}", templateHandler.DeleteTemplate)
+	mux.HandleFunc("GET /api/templates/{name}/versions", templateHandler.TemplateVersions)
+	jobHandler := myHttp.NewJobHandler(svcs.jobs, limits)
+	mux.HandleFunc("POST /api/jobs", jobHandler.CreateJob)
+	mux.HandleFunc("GET /api/jobs/{id}", jobHandler.GetJob)
+	batchHandler := myHttp.NewBatchHandler(batches, cfg.Batch.MaxUploadBytes)
+	mux.HandleFunc("POST /api/batches", batchHandler.CreateBatch)
+	mux.HandleFunc("GET /api/batches/{id}", batchHandler.Batch)
+	mux.HandleFunc("GET /api/batches/{id}/results", batchHandler.BatchResults)
+	mux.Handle("GET /metrics", promMetrics.Handler())
+
+	// OpenAI-compatible gateway, authenticated with keys from /api/keys
+	openAIHandler := openaicompat.NewHandler(llmService, cfg.Limits.MaxRequestBytes)
+	mux.HandleFunc("POST /v1/chat/completions", openAIHandler.ChatCompletions)
+	mux.HandleFunc("GET /v1/models", openAIHandler.Models)
+
+	// Admin endpoints, key issuance included, are only exposed when an admin
+	// key is configured.
+	if cfg.Server.AdminAPIKey != "" {
+		adminHandler := myHttp.NewAdminHandler(llmService, cfg.Server.AdminAPIKey)
+		mux.HandleFunc("POST /api/keys", adminHandler.CreateAPIKey)
+		mux.HandleFunc("GET /api/admin/cache/stats", adminHandler.CacheStats)
+		mux.HandleFunc("POST /api/admin/cache/purge", adminHandler.PurgeCache)
+	}
+
+	httpLis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.Server.Port))
+	if err != nil {
+		fatal("Failed to listen for HTTP", "err", err)
+	}
+	httpServer := &http.Server{
+		Handler: myHttp.Chain(myHttp.Routes(mux),
+			tracing.Middleware,
+			myHttp.RequestID,
+			promMetrics.InstrumentHTTP,
+			myHttp.SecurityHeaders(cfg.Server.HSTSMaxAge),
+			myHttp.CORS(myHttp.CORSPolicy{
+				AllowedOrigins:   cfg.CORS.AllowedOrigins,
+				AllowedMethods:   cfg.CORS.AllowedMethods,
+				AllowedHeaders:   cfg.CORS.AllowedHeaders,
+				AllowCredentials: cfg.CORS.AllowCredentials,
+				MaxAge:           cfg.CORS.MaxAge,
+			}),
+		),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	app.add(httpComponent("HTTP server", httpServer, httpLis))
+
+	// 6. Serve until SIGINT or SIGTERM, then drain and clean up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Run(ctx); err != nil {
+		fatal("Server stopped with errors", "err", err)
 	}
+	slog.Info("Server stopped")
+}
+
+// appServices are the services the servers and the batch command run on.
+type appServices struct {
+	llm       *services.LLMService
+	jobs      *services.JobService
+	templates *services.TemplateService
+	knowledge *services.KnowledgeService
+	health    *services.HealthService
+}
 
+// newService connects the infrastructure named in the config and builds the
+// services on top of it, applying opts to the LLMService after those derived
+// from the config. Connections and the service's pending writes are added to
+// the app, so they are flushed and closed on shutdown.
+func (a *App) newService(extra ...services.Option) appServices {
+	cfg := a.cfg
 	// 2. Initialize Infrastructure
 	// Database (optional)
 	var repo ports.Repository
+	var dbRepo *repository.PostgresRepository
+	var err error
 	if cfg.Database.Host != "" {
 		dbConnStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
 			cfg.Database.User, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
-		dbRepo, err := repository.NewPostgresRepository(dbConnStr)
+		dbRepo, err = repository.NewPostgresRepository(dbConnStr)
 		if err != nil {
-			log.Fatalf("Failed to connect to database (required for user registration): %v", err)
+			fatal("Failed to connect to database (required for user registration)", "err", err)
 		}
 		repo = dbRepo
+		a.add(component{name: "postgres", stop: func(context.Context) error {
+			dbRepo.Close()
+			return nil
+		}})
 	} else {
-		log.Fatalf("Database configuration is required to store users")
+		fatal("Database configuration is required to store users")
 	}
 
 	// Redis (optional)
 	var cache ports.Cache
 	if cfg.Redis.Addr != "" {
-		cache = repository.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password)
+		redisCache := repository.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password)
+		cache = redisCache
+		a.add(component{name: "redis", stop: func(context.Context) error {
+			return redisCache.Close()
+		}})
 	}
 
+	// Model catalog (optional)
+	var opts []services.Option
+	if cfg.LLM.ModelCatalogPath != "" {
+		models, err := catalog.Load(cfg.LLM.ModelCatalogPath, cfg.LLM)
+		if err != nil {
+			fatal("Failed to load model catalog", "err", err)
+		}
+		opts = append(opts, services.WithCatalog(models))
+	}
+
+	// Knowledge collections (optional)
+	var index ports.VectorIndex
+	switch cfg.Knowledge.VectorStore {
+	case "":
+	case "memory":
+		index = repository.NewMemoryVectorIndex()
+	case "postgres":
+		pgIndex, err := dbRepo.VectorIndex(context.Background())
+		if err != nil {
+			fatal("Failed to prepare vector index", "err", err)
+		}
+		index = pgIndex
+	default:
+		fatal("Unknown VECTOR_STORE: expected postgres or memory", "vector_store", cfg.Knowledge.VectorStore)
+	}
+
+	// Server-side tools for agent runs
+	toolRegistry := tools.NewRegistry(tools.Calculator{}, tools.Clock{})
+	if len(cfg.Agent.HTTPAllowedHosts) > 0 {
+		toolRegistry.Register(tools.NewHTTPFetch(cfg.Agent.HTTPAllowedHosts))
+	}
+	opts = append(opts, services.WithTools(toolRegistry))
+
+	// Stored request logs keep prompts and answers as REQUEST_LOG_REDACTION says
+	redactor, err := logging.NewRedactor(cfg.Log.StoredRedaction, cfg.Log.RedactLength)
+	if err != nil {
+		fatal("Invalid REQUEST_LOG_REDACTION", "err", err)
+	}
+	opts = append(opts, services.WithRequestLogRedaction(redactor))
+
 	// 3. Initialize Services
-	llmService := services.NewLLMService(cfg, repo, cache)
+	llmService := services.NewLLMService(cfg, repo, cache, append(opts, extra...)...)
+	a.add(component{name: "request logs and cache writes", stop: llmService.Flush})
 
-	// 4. HTTP Server only
-	httpHandler := myHttp.NewHandler(llmService)
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/generate", httpHandler.Generate)
-	mux.HandleFunc("/api/health", httpHandler.Health)
-	mux.HandleFunc("/api/users", httpHandler.RegisterUser)
+	// Feature services run on the LLMService. Prompt templates and the job
+	// queue live next to the users they serve.
+	return appServices{
+		llm:       llmService,
+		jobs:      services.NewJobService(cfg.Jobs, llmService, dbRepo),
+		templates: services.NewTemplateService(llmService, dbRepo),
+		knowledge: services.NewKnowledgeService(cfg.Knowledge, llmService, index),
+		health:    services.NewHealthService(cfg.Health, llmService),
+	}
+}
 
-	httpAddr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("HTTP Server listening on %s", httpAddr)
-	if err := http.ListenAndServe(httpAddr, mux); err != nil {
-		log.Fatalf("failed to serve http: %v", err)
+// checkCORS refuses to let any origin make credentialed requests, which
+// would let every website act with its visitors' cookies.
+func checkCORS(cfg config.CORSConfig) error {
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		return errors.New("CORS_ALLOW_CREDENTIALS cannot be combined with \"*\" in CORS_ALLOWED_ORIGINS; list the origins instead")
 	}
+	return nil
+}
+
+// fatal logs an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+func TestCheckCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.CORSConfig
+		wantErr bool
+	}{
+		{name: "none", cfg: config.CORSConfig{}},
+		{name: "any origin", cfg: config.CORSConfig{AllowedOrigins: []string{"*"}}},
+		{name: "listed origins with credentials", cfg: config.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}},
+		{name: "any origin with credentials", cfg: config.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCORS(tt.cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
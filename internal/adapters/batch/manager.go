@@ -0,0 +1,350 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+const (
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+	// StateInterrupted batches were stopped by a shutdown and resume when
+	// the server starts again.
+	StateInterrupted = "interrupted"
+)
+
+var (
+	// ErrNotFound is returned for unknown batches and for batches uploaded
+	// by another user.
+	ErrNotFound = ports.NewError(ports.ErrNotFound, "batch not found")
+	// ErrInvalid is returned for uploads that cannot be accepted.
+	ErrInvalid = ports.NewError(ports.ErrInvalidArgument, "invalid batch")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Status describes an uploaded batch.
+type Status struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	State  string `json:"state"`
+	// Requests is the number of request lines; Done how many have a result.
+	Requests  int       `json:"requests"`
+	Done      int       `json:"done"`
+	Summary   *Summary  `json:"summary,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type meta struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Requests  int       `json:"requests"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager runs uploaded batches in the background. Each batch is a
+// directory under dir holding its input, output and, once finished, its
+// summary, so batches survive restarts and resume on Start. Batches run on
+// the server that accepted them.
+type Manager struct {
+	dir     string
+	service *services.LLMService
+	decode  Decoder
+	opts    services.BatchOptions
+
+	ctx     context.Context
+	mu      sync.Mutex
+	running map[string]bool
+	failed  map[string]string
+	wg      sync.WaitGroup
+}
+
+func NewManager(dir string, service *services.LLMService, decode Decoder, opts services.BatchOptions) *Manager {
+	return &Manager{
+		dir:     dir,
+		service: service,
+		decode:  decode,
+		opts:    opts,
+		ctx:     context.Background(),
+		running: make(map[string]bool),
+		failed:  make(map[string]string),
+	}
+}
+
+// Start resumes every unfinished batch. Batches run until ctx is cancelled.
+func (m *Manager) Start(ctx context.Context) error {
+	m.ctx = ctx
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !idPattern.MatchString(e.Name()) {
+			continue
+		}
+		// Uploads interrupted before their metadata was written never started.
+		if _, err := os.Stat(m.path(e.Name(), "meta.json")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(m.path(e.Name(), "summary.json")); errors.Is(err, os.ErrNotExist) {
+			slog.Info("Resuming batch", "batch_id", e.Name())
+			m.launch(e.Name())
+		}
+	}
+	return nil
+}
+
+// Wait blocks until every batch has stopped after the context given to
+// Start was cancelled, or until ctx ends. Stopped batches resume on the
+// next Start.
+func (m *Manager) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("batches still running: %w", ctx.Err())
+	}
+}
+
+// Submit stores an upload for userID and starts it. Lines may omit user_id;
+// lines naming another user are refused.
+func (m *Manager) Submit(userID string, input io.Reader) (*Status, error) {
+	id := newBatchID()
+	if err := os.MkdirAll(m.path(id, ""), 0o755); err != nil {
+		return nil, err
+	}
+	requests, err := m.storeInput(id, userID, input)
+	if err == nil && requests == 0 {
+		err = fmt.Errorf("%w: no requests in upload", ErrInvalid)
+	}
+	if err != nil {
+		os.RemoveAll(m.path(id, ""))
+		return nil, err
+	}
+
+	md := meta{ID: id, UserID: userID, Requests: requests, CreatedAt: time.Now()}
+	if err := writeJSON(m.path(id, "meta.json"), md); err != nil {
+		return nil, err
+	}
+	m.launch(id)
+	return &Status{ID: id, UserID: userID, State: StateRunning, Requests: requests, CreatedAt: md.CreatedAt}, nil
+}
+
+// storeInput copies the upload to disk, counting its request lines and
+// checking they belong to userID.
+func (m *Manager) storeInput(id, userID string, input io.Reader) (int, error) {
+	f, err := os.Create(m.path(id, "input.jsonl"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, input); err != nil {
+		return 0, err
+	}
+	requests := 0
+	err = readLines(f.Name(), func(n int, line []byte) error {
+		requests++
+		var owner struct {
+			UserID string `json:"user_id"`
+		}
+		if json.Unmarshal(line, &owner) == nil && owner.UserID != "" && owner.UserID != userID {
+			return fmt.Errorf("%w: line %d belongs to another user", ErrInvalid, n)
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// Status reports on a batch of userID.
+func (m *Manager) Status(userID, id string) (*Status, error) {
+	md, err := m.meta(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	st := &Status{ID: id, UserID: md.UserID, Requests: md.Requests, CreatedAt: md.CreatedAt}
+	var summary Summary
+	if err := readJSON(m.path(id, "summary.json"), &summary); err == nil {
+		st.State = StateCompleted
+		st.Summary = &summary
+		st.Done = summary.Succeeded + summary.Failed
+		return st, nil
+	}
+
+	m.mu.Lock()
+	running, failure := m.running[id], m.failed[id]
+	m.mu.Unlock()
+	switch {
+	case running:
+		st.State = StateRunning
+	case failure != "":
+		st.State = StateFailed
+		st.Error = failure
+	default:
+		st.State = StateInterrupted
+	}
+	st.Done, err = countLines(m.path(id, "output.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Results opens the output of a batch of userID. It grows while the batch
+// runs.
+func (m *Manager) Results(userID, id string) (io.ReadCloser, error) {
+	if _, err := m.meta(userID, id); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(m.path(id, "output.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	return f, err
+}
+
+func (m *Manager) launch(id string) {
+	m.mu.Lock()
+	if m.running[id] {
+		m.mu.Unlock()
+		return
+	}
+	m.running[id] = true
+	delete(m.failed, id)
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		err := m.run(id)
+		m.mu.Lock()
+		delete(m.running, id)
+		if err != nil && m.ctx.Err() == nil {
+			m.failed[id] = err.Error()
+		}
+		m.mu.Unlock()
+		if err != nil {
+			slog.Error("Batch stopped", "batch_id", id, "err", err)
+		}
+	}()
+}
+
+func (m *Manager) run(id string) error {
+	var md meta
+	if err := readJSON(m.path(id, "meta.json"), &md); err != nil {
+		return err
+	}
+	// Lines run as the uploader.
+	decode := func(line []byte) (services.BatchItem, error) {
+		item, err := m.decode(line)
+		if err == nil {
+			item.Request.UserID = md.UserID
+		}
+		return item, err
+	}
+	summary, err := Run(m.ctx, m.service, decode, m.path(id, "input.jsonl"), m.path(id, "output.jsonl"), m.opts)
+	if err != nil {
+		return err
+	}
+	slog.Info("Batch completed", "batch_id", id, "succeeded", summary.Succeeded, "failed", summary.Failed, "cost_usd", summary.CostUSD)
+	return writeJSON(m.path(id, "summary.json"), summary)
+}
+
+// meta loads a batch's metadata, hiding other users' batches behind the
+// same error as missing ones.
+func (m *Manager) meta(userID, id string) (*meta, error) {
+	if !idPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var md meta
+	err := readJSON(m.path(id, "meta.json"), &md)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && md.UserID != userID) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+func (m *Manager) path(id, name string) string {
+	return filepath.Join(m.dir, id, name)
+}
+
+func newBatchID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writeJSON replaces path atomically, so readers never see half a file.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// countLines counts the complete lines of a file that may not exist yet.
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n := 0
+	r := bufio.NewReader(f)
+	for {
+		_, err := r.ReadSlice('\n')
+		switch {
+		case err == nil:
+			n++
+		case errors.Is(err, bufio.ErrBufferFull):
+		case err == io.EOF:
+			return n, nil
+		default:
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package batch runs JSONL files of generation requests through the
+// service and records one result line per request. Runs are resumable:
+// lines already in the output are not sent again, unless they failed with a
+// transient error.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Decoder turns one input line into a request.
+type Decoder func(line []byte) (services.BatchItem, error)
+
+// Result is one line of the output file.
+type Result struct {
+	// Line is the 1-based line number of the request in the input file.
+	Line         int             `json:"line"`
+	CustomID     string          `json:"custom_id,omitempty"`
+	Status       string          `json:"status"`
+	Provider     string          `json:"provider,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	JSON         json.RawMessage `json:"json,omitempty"`
+	FinishReason string          `json:"finish_reason,omitempty"`
+	Usage        *Usage          `json:"usage,omitempty"`
+	CacheHit     bool            `json:"cache_hit,omitempty"`
+	DurationMs   int64           `json:"duration_ms"`
+	// Error and ErrorCode describe a failure as the HTTP API would; the
+	// full error is only logged.
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int32   `json:"prompt_tokens"`
+	CompletionTokens int32   `json:"completion_tokens"`
+	TotalTokens      int32   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Summary totals the results of a whole file, including those of earlier,
+// interrupted runs.
+type Summary struct {
+	// Requests is the number of non-blank input lines.
+	Requests         int     `json:"requests"`
+	Succeeded        int     `json:"succeeded"`
+	Failed           int     `json:"failed"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	// Resumed counts results found in the output from an earlier run.
+	Resumed   int                         `json:"resumed"`
+	Providers map[string]*ProviderSummary `json:"providers"`
+	// DurationMs is the wall time of the last run only.
+	DurationMs int64 `json:"duration_ms"`
+}
+
+type ProviderSummary struct {
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+func (s *Summary) add(r Result) {
+	if r.Status == StatusSucceeded {
+		s.Succeeded++
+	} else {
+		s.Failed++
+	}
+	if r.Usage != nil {
+		s.PromptTokens += int64(r.Usage.PromptTokens)
+		s.CompletionTokens += int64(r.Usage.CompletionTokens)
+		s.TotalTokens += int64(r.Usage.TotalTokens)
+		s.CostUSD += r.Usage.CostUSD
+	}
+	if r.Provider == "" {
+		return
+	}
+	p := s.Providers[r.Provider]
+	if p == nil {
+		p = &ProviderSummary{}
+		s.Providers[r.Provider] = p
+	}
+	if r.Status == StatusSucceeded {
+		p.Succeeded++
+	} else {
+		p.Failed++
+	}
+	if r.Usage != nil {
+		p.TotalTokens += int64(r.Usage.TotalTokens)
+		p.CostUSD += r.Usage.CostUSD
+	}
+}
+
+// Run processes every line of input that has no result in output yet and
+// appends the new results to output. Lines that do not decode are recorded
+// as failed without being sent. Cancelling ctx stops the run; calling Run
+// again with the same files picks up where it stopped.
+func Run(ctx context.Context, svc *services.LLMService, decode Decoder, input, output string, opts services.BatchOptions) (*Summary, error) {
+	start := time.Now()
+	summary := &Summary{Providers: make(map[string]*ProviderSummary)}
+	done, err := loadResults(output, summary)
+	if err != nil {
+		return nil, err
+	}
+	summary.Resumed = len(done)
+
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	// Each result is written with a single call, so a crash can at worst
+	// leave one torn line, which loadResults discards on resume.
+	write := func(r Result) error {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write result of line %d: %w", r.Line, err)
+		}
+		summary.add(r)
+		return nil
+	}
+
+	var items []services.BatchItem
+	var pending []Result
+	err = readLines(input, func(n int, line []byte) error {
+		summary.Requests++
+		if done[n] {
+			return nil
+		}
+		var meta struct {
+			CustomID string `json:"custom_id"`
+		}
+		_ = json.Unmarshal(line, &meta)
+		item, err := decode(line)
+		if err != nil {
+			return write(Result{Line: n, CustomID: meta.CustomID, Status: StatusFailed, Error: err.Error(), ErrorCode: "invalid_argument"})
+		}
+		items = append(items, item)
+		pending = append(pending, Result{Line: n, CustomID: meta.CustomID})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.RunBatch(ctx, items, opts, func(br services.BatchResult) error {
+		return write(result(pending[br.Index], br))
+	})
+	summary.DurationMs = time.Since(start).Milliseconds()
+	return summary, err
+}
+
+func result(r Result, br services.BatchResult) Result {
+	r.Provider = br.Provider
+	r.DurationMs = br.Duration.Milliseconds()
+	if br.Err != nil {
+		slog.Warn("Batch request failed", "line", r.Line, "provider", br.Provider, "err", br.Err)
+		r.Status = StatusFailed
+		r.Error, r.ErrorCode = ports.PublicMessage(br.Err), ports.ErrorCode(br.Err)
+		return r
+	}
+	resp := br.Response
+	r.Status = StatusSucceeded
+	r.Model = resp.Model
+	r.Content = resp.Content
+	r.JSON = resp.JSON
+	r.FinishReason = resp.FinishReason
+	r.CacheHit = br.Provider == services.CachedProvider
+	if u := resp.Usage; u != nil {
+		r.Usage = &Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens, CostUSD: u.CostUSD}
+	}
+	return r
+}
+
+// loadResults reads an existing output file into summary and returns the
+// input lines it covers. A line's last result counts; one that failed with
+// a transient error is left out so the line is sent again. A torn final
+// line left by a crash is cut off.
+func loadResults(path string, summary *Summary) (map[int]bool, error) {
+	done := make(map[int]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	if complete < len(data) {
+		if err := os.Truncate(path, int64(complete)); err != nil {
+			return nil, fmt.Errorf("failed to discard torn result: %w", err)
+		}
+	}
+	latest := make(map[int]Result)
+	for n, line := range bytes.Split(data[:complete], []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r Result
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("%s line %d is not a batch result: %w", path, n+1, err)
+		}
+		latest[r.Line] = r
+	}
+	for line, r := range latest {
+		if r.Status == StatusFailed && ports.RetryableCode(r.ErrorCode) {
+			continue
+		}
+		done[line] = true
+		summary.add(r)
+	}
+	return done, nil
+}
+
+// readLines calls fn with the 1-based number and content of every non-blank
+// line. Lines may be arbitrarily long, as inline attachments make them.
+func readLines(path string, fn func(n int, line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if ferr := fn(n, trimmed); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+type echoProvider struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *echoProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.mu.Unlock()
+	return &ports.LLMResponse{Content: "echo: " + req.Prompt, Model: "echo-1", FinishReason: "stop",
+		Usage: &ports.UsageInfo{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5, CostUSD: 0.01}}, nil
+}
+
+func (p *echoProvider) Name() string { return "echo" }
+
+// brokenProvider fails with an upstream error that clients must not see.
+type brokenProvider struct{}
+
+func (brokenProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	return nil, &ports.ProviderError{Provider: "broken", Class: ports.ErrRateLimited, Err: errors.New("quota of org-secret exceeded")}
+}
+
+func (brokenProvider) Name() string { return "broken" }
+
+type userRepo struct{}
+
+func (userRepo) LogRequest(ctx context.Context, log ports.RequestLog) error { return nil }
+func (userRepo) CreateUser(ctx context.Context, name string) (*ports.User, error) {
+	return nil, errors.New("not supported")
+}
+func (userRepo) GetUser(ctx context.Context, id string) (*ports.User, error) {
+	return &ports.User{ID: id, Name: id, CreatedAt: time.Now()}, nil
+}
+func (userRepo) CreateAPIKey(ctx context.Context, key ports.APIKey, keyHash string) (*ports.APIKey, error) {
+	return nil, errors.New("not supported")
+}
+func (userRepo) GetUserByAPIKey(ctx context.Context, keyHash string) (*ports.User, error) {
+	return nil, errors.New("not supported")
+}
+
+func decodeLine(line []byte) (services.BatchItem, error) {
+	var req struct {
+		UserID   string `json:"user_id"`
+		Prompt   string `json:"prompt"`
+		Provider string `json:"provider"`
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		return services.BatchItem{}, err
+	}
+	return services.BatchItem{Request: ports.LLMRequest{UserID: req.UserID, Prompt: req.Prompt}, Provider: req.Provider}, nil
+}
+
+func TestRunResumes(t *testing.T) {
+	provider := &echoProvider{}
+	svc := services.NewLLMService(&config.Config{}, userRepo{}, nil, services.WithProvider("echo", provider))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.jsonl")
+	output := filepath.Join(dir, "output.jsonl")
+	lines := []string{
+		`{"custom_id":"a","user_id":"u1","prompt":"one","provider":"echo"}`,
+		``,
+		`{"custom_id":"b","user_id":"u1","prompt":"two","provider":"echo"}`,
+		`{"custom_id":"c","user_id":"u1","prompt":`,
+		`{"custom_id":"d","user_id":"u1","prompt":"four","provider":"echo"}`,
+	}
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier run finished line 1 and crashed halfway through line 3.
+	earlier := `{"line":1,"custom_id":"a","status":"succeeded","provider":"echo","content":"echo: one","usage":{"prompt_tokens":2,"completion_tokens":3,"total_tokens":5,"cost_usd":0.01},"duration_ms":4}` + "\n" + `{"line":3,"custom_id":"b","sta`
+	if err := os.WriteFile(output, []byte(earlier), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := Run(context.Background(), svc, decodeLine, input, output, services.BatchOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Requests != 4 || summary.Resumed != 1 || summary.Succeeded != 3 || summary.Failed != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if summary.TotalTokens != 15 || summary.Providers["echo"] == nil || summary.Providers["echo"].Succeeded != 3 {
+		t.Fatalf("unexpected totals: %+v", summary)
+	}
+	for _, p := range provider.prompts {
+		if p == "one" {
+			t.Fatal("line 1 was sent again")
+		}
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[int]Result)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var r Result
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatalf("output line %q is not a result: %v", line, err)
+		}
+		results[r.Line] = r
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if r := results[3]; r.Status != StatusSucceeded || r.CustomID != "b" || r.Content != "echo: two" {
+		t.Fatalf("unexpected result for line 3: %+v", r)
+	}
+	if r := results[4]; r.Status != StatusFailed || r.CustomID != "" || r.Error == "" {
+		t.Fatalf("unexpected result for line 4: %+v", r)
+	}
+
+	// Running again has nothing left to do.
+	provider.prompts = nil
+	summary, err = Run(context.Background(), svc, decodeLine, input, output, services.BatchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(provider.prompts) != 0 || summary.Resumed != 4 || summary.Succeeded != 3 {
+		t.Fatalf("expected a finished file to be left alone, sent %v, summary %+v", provider.prompts, summary)
+	}
+}
+
+func TestRunRetriesTransientFailures(t *testing.T) {
+	provider := &echoProvider{}
+	svc := services.NewLLMService(&config.Config{}, userRepo{}, nil, services.WithProvider("echo", provider))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.jsonl")
+	output := filepath.Join(dir, "output.jsonl")
+	lines := []string{
+		`{"user_id":"u1","prompt":"one","provider":"echo"}`,
+		`{"user_id":"u1","prompt":"two","provider":"echo"}`,
+	}
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier run was rate limited on line 1 and filtered on line 2.
+	earlier := `{"line":1,"status":"failed","provider":"echo","error":"rate limited","error_code":"rate_limited","duration_ms":1}` + "\n" +
+		`{"line":2,"status":"failed","provider":"echo","error":"filtered","error_code":"content_filtered","duration_ms":1}` + "\n"
+	if err := os.WriteFile(output, []byte(earlier), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := Run(context.Background(), svc, decodeLine, input, output, services.BatchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(provider.prompts) != 1 || provider.prompts[0] != "one" {
+		t.Fatalf("expected only the rate limited line to be sent again, sent %v", provider.prompts)
+	}
+	if summary.Resumed != 1 || summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	// The retry's result replaces the failure.
+	provider.prompts = nil
+	summary, err = Run(context.Background(), svc, decodeLine, input, output, services.BatchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(provider.prompts) != 0 || summary.Resumed != 2 || summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Fatalf("expected a finished file to be left alone, sent %v, summary %+v", provider.prompts, summary)
+	}
+}
+
+func TestRunHidesUpstreamErrors(t *testing.T) {
+	svc := services.NewLLMService(&config.Config{}, userRepo{}, nil, services.WithProvider("broken", brokenProvider{}))
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.jsonl")
+	output := filepath.Join(dir, "output.jsonl")
+	if err := os.WriteFile(input, []byte(`{"user_id":"u1","prompt":"one","provider":"broken"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(context.Background(), svc, decodeLine, input, output, services.BatchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Result
+	if err := json.Unmarshal(bytes.TrimSpace(data), &r); err != nil {
+		t.Fatalf("output %q is not a result: %v", data, err)
+	}
+	if r.Status != StatusFailed || r.ErrorCode != "rate_limited" || r.Error != "broken: rate limited by provider" {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
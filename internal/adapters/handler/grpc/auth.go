@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	nexusv1 "github.com/willexm1/go-llm-nexus/proto/nexus/v1"
+)
+
+// publicMethods can be called without an API key. RegisterUser stays open
+// like POST /api/users so a new user can obtain a key in the first place.
+var publicMethods = map[string]bool{
+	nexusv1.NexusService_Health_FullMethodName:       true,
+	nexusv1.NexusService_RegisterUser_FullMethodName: true,
+}
+
+type userKey struct{}
+
+func userFromContext(ctx context.Context) (*ports.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*ports.User)
+	return user, ok
+}
+
+// UnaryAuthInterceptor authenticates unary calls with the gateway API key in
+// the "authorization: Bearer <key>" metadata.
+func UnaryAuthInterceptor(service *services.LLMService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is UnaryAuthInterceptor for streaming calls.
+func StreamAuthInterceptor(service *services.LLMService) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), service)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, service *services.LLMService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var key string
+	if values := md.Get("authorization"); len(values) > 0 {
+		key = strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	}
+	if key == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing api key")
+	}
+	user, err := service.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		slog.ErrorContext(ctx, "Failed to authenticate api key", "err", err)
+		return nil, status.Error(codes.Internal, "failed to authenticate api key")
+	}
+	return context.WithValue(ctx, userKey{}, user), nil
+}
+
+// authenticatedStream carries the authenticated user through to the handler.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
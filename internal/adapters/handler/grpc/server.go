@@ -0,0 +1,205 @@
+// Package grpc serves the nexus.v1 gRPC API, a second adapter over the same
+// LLMService that backs the HTTP handlers.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	nexusv1 "github.com/willexm1/go-llm-nexus/proto/nexus/v1"
+)
+
+type Server struct {
+	nexusv1.UnimplementedNexusServiceServer
+	service *services.LLMService
+}
+
+func NewServer(service *services.LLMService) *Server {
+	return &Server{service: service}
+}
+
+func (s *Server) Generate(ctx context.Context, req *nexusv1.GenerateRequest) (*nexusv1.GenerateResponse, error) {
+	coreReq, err := s.coreRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Received gRPC request", "provider", req.GetProvider(), "user_id", coreReq.UserID)
+
+	start := time.Now()
+	resp, providerUsed, err := s.service.ProcessRequest(ctx, coreReq, req.GetProvider())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error processing gRPC request", "err", err)
+		return nil, serviceError(err)
+	}
+	return response(resp, providerUsed, time.Since(start)), nil
+}
+
+func (s *Server) GenerateStream(req *nexusv1.GenerateRequest, stream nexusv1.NexusService_GenerateStreamServer) error {
+	ctx := stream.Context()
+	coreReq, err := s.coreRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Received gRPC stream request", "provider", req.GetProvider(), "user_id", coreReq.UserID)
+
+	start := time.Now()
+	resp, providerUsed, err := s.service.ProcessStream(ctx, coreReq, req.GetProvider(), func(delta string) error {
+		return stream.Send(&nexusv1.GenerateStreamResponse{
+			Event: &nexusv1.GenerateStreamResponse_Delta{Delta: delta},
+		})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error processing gRPC stream", "err", err)
+		return serviceError(err)
+	}
+	return stream.Send(&nexusv1.GenerateStreamResponse{
+		Event: &nexusv1.GenerateStreamResponse_Done{Done: response(resp, providerUsed, time.Since(start))},
+	})
+}
+
+func (s *Server) RegisterUser(ctx context.Context, req *nexusv1.RegisterUserRequest) (*nexusv1.RegisterUserResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	user, err := s.service.RegisterUser(ctx, req.GetName())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to register user", "err", err)
+		return nil, serviceError(err)
+	}
+	return &nexusv1.RegisterUserResponse{
+		Id:        user.ID,
+		Name:      user.Name,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}, nil
+}
+
+func (s *Server) Health(ctx context.Context, req *nexusv1.HealthRequest) (*nexusv1.HealthResponse, error) {
+	return &nexusv1.HealthResponse{
+		Status:  "healthy",
+		Service: "go-llm-nexus",
+	}, nil
+}
+
+// coreRequest converts req for the service, billing it to the user the
+// auth interceptor resolved from the API key.
+func (s *Server) coreRequest(ctx context.Context, req *nexusv1.GenerateRequest) (ports.LLMRequest, error) {
+	user, ok := userFromContext(ctx)
+	if !ok {
+		return ports.LLMRequest{}, status.Error(codes.Unauthenticated, "missing api key")
+	}
+
+	coreReq := ports.LLMRequest{
+		UserID:      user.ID,
+		Model:       req.GetModel(),
+		Prompt:      req.GetPrompt(),
+		Temperature: req.GetTemperature(),
+		MaxTokens:   req.GetMaxTokens(),
+		CacheMode:   ports.CacheMode(req.GetCache()),
+		CacheTTL:    time.Duration(req.GetCacheTtlSeconds()) * time.Second,
+		Routing:     req.GetRouting(),
+	}
+	for _, m := range req.GetMessages() {
+		switch m.GetRole() {
+		case ports.RoleSystem, ports.RoleUser, ports.RoleAssistant:
+		default:
+			return ports.LLMRequest{}, status.Errorf(codes.InvalidArgument, "unsupported message role %q", m.GetRole())
+		}
+		coreReq.Messages = append(coreReq.Messages, ports.Message{Role: m.GetRole(), Content: m.GetContent()})
+	}
+	if h := req.GetHedge(); h != nil {
+		coreReq.Hedge = &ports.HedgePolicy{
+			Mode:      ports.HedgeMode(h.GetMode()),
+			Delay:     time.Duration(h.GetDelayMs()) * time.Millisecond,
+			Secondary: h.GetSecondary(),
+		}
+	}
+	return coreReq, nil
+}
+
+func response(resp *ports.LLMResponse, providerUsed string, duration time.Duration) *nexusv1.GenerateResponse {
+	out := &nexusv1.GenerateResponse{
+		Content:          resp.Content,
+		ProviderUsed:     providerUsed,
+		ModelUsed:        resp.Model,
+		ProcessingTimeMs: duration.Milliseconds(),
+		CacheHit:         providerUsed == services.CachedProvider,
+		Fingerprint:      resp.Fingerprint,
+		FinishReason:     resp.FinishReason,
+	}
+	if u := resp.Usage; u != nil {
+		out.Usage = &nexusv1.Usage{
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			TotalTokens:      u.TotalTokens,
+			CostUsd:          u.CostUSD,
+		}
+	}
+	return out
+}
+
+// errorCodes maps error classes onto gRPC status codes, in the order they
+// are matched.
+var errorCodes = []struct {
+	class error
+	code  codes.Code
+}{
+	{ports.ErrInvalidArgument, codes.InvalidArgument},
+	{ports.ErrUnauthenticated, codes.Unauthenticated},
+	{ports.ErrBudgetExceeded, codes.ResourceExhausted},
+	{ports.ErrNotFound, codes.NotFound},
+	{ports.ErrTooLarge, codes.ResourceExhausted},
+	{ports.ErrContentFiltered, codes.FailedPrecondition},
+	{ports.ErrRateLimited, codes.ResourceExhausted},
+	{ports.ErrInvalidOutput, codes.Unavailable},
+	{ports.ErrProviderUnavailable, codes.Unavailable},
+	{ports.ErrNotConfigured, codes.Unavailable},
+	{ports.ErrTimeout, codes.DeadlineExceeded},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+	{context.Canceled, codes.Canceled},
+}
+
+// serviceError maps service errors onto gRPC statuses. Provider failures are
+// described without the upstream response, and unclassified errors, which
+// may carry internal detail, only as "internal error".
+func serviceError(err error) error {
+	for _, c := range errorCodes {
+		if !errors.Is(err, c.class) {
+			continue
+		}
+		var provErr *ports.ProviderError
+		if errors.As(err, &provErr) {
+			return status.Error(c.code, provErr.Message())
+		}
+		var invalid *ports.ValidationError
+		if errors.As(err, &invalid) {
+			return validationStatus(invalid)
+		}
+		return status.Error(c.code, err.Error())
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+// validationStatus reports the invalid fields of a request as BadRequest
+// details.
+func validationStatus(invalid *ports.ValidationError) error {
+	st := status.New(codes.InvalidArgument, invalid.Error())
+	details := &errdetails.BadRequest{}
+	for _, f := range invalid.Fields {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+	}
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
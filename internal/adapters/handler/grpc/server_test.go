@@ -0,0 +1,277 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	nexusv1 "github.com/willexm1/go-llm-nexus/proto/nexus/v1"
+)
+
+type streamingProvider struct{}
+
+func (p *streamingProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	return &ports.LLMResponse{Content: "hello world", FinishReason: "stop", Usage: &ports.UsageInfo{TotalTokens: 3}}, nil
+}
+
+func (p *streamingProvider) GenerateStream(ctx context.Context, req ports.LLMRequest, onChunk func(string) error) (*ports.LLMResponse, error) {
+	for _, delta := range []string{"hello", " world"} {
+		if err := onChunk(delta); err != nil {
+			return nil, err
+		}
+	}
+	return p.Generate(ctx, req)
+}
+
+func (p *streamingProvider) Name() string { return "Stream" }
+
+type memoryRepo struct {
+	mu    sync.Mutex
+	users map[string]*ports.User
+	keys  map[string]string
+}
+
+func (r *memoryRepo) LogRequest(ctx context.Context, log ports.RequestLog) error { return nil }
+
+func (r *memoryRepo) CreateUser(ctx context.Context, name string) (*ports.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := &ports.User{ID: fmt.Sprintf("user-%d", len(r.users)+1), Name: name, CreatedAt: time.Now()}
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *memoryRepo) GetUser(ctx context.Context, id string) (*ports.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("user %s: %w", id, ports.ErrNotFound)
+}
+
+func (r *memoryRepo) CreateAPIKey(ctx context.Context, key ports.APIKey, keyHash string) (*ports.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[keyHash] = key.UserID
+	key.ID = keyHash[:8]
+	return &key, nil
+}
+
+func (r *memoryRepo) GetUserByAPIKey(ctx context.Context, keyHash string) (*ports.User, error) {
+	r.mu.Lock()
+	userID, ok := r.keys[keyHash]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("api key: %w", ports.ErrNotFound)
+	}
+	return r.GetUser(ctx, userID)
+}
+
+// brokenRepo fails to store users with a driver error clients must not see.
+type brokenRepo struct {
+	memoryRepo
+}
+
+func (r *brokenRepo) CreateUser(ctx context.Context, name string) (*ports.User, error) {
+	return nil, errors.New(`pq: password authentication failed for user "nexus"`)
+}
+
+// newTestClient serves the API over an in-memory listener.
+func newTestClient(t *testing.T) (nexusv1.NexusServiceClient, *services.LLMService) {
+	t.Helper()
+	repo := &memoryRepo{users: make(map[string]*ports.User), keys: make(map[string]string)}
+	svc := services.NewLLMService(&config.Config{}, repo, nil, services.WithProvider("stream", &streamingProvider{}))
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryAuthInterceptor(svc)),
+		grpc.StreamInterceptor(StreamAuthInterceptor(svc)),
+	)
+	nexusv1.RegisterNexusServiceServer(srv, NewServer(svc))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return nexusv1.NewNexusServiceClient(conn), svc
+}
+
+// withKey registers a user, issues them a key and returns a context carrying it.
+func withKey(t *testing.T, client nexusv1.NexusServiceClient, svc *services.LLMService) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	user, err := client.RegisterUser(ctx, &nexusv1.RegisterUserRequest{Name: "Ada"})
+	if err != nil {
+		t.Fatalf("register user: %v", err)
+	}
+	key, _, err := svc.CreateAPIKey(ctx, user.GetId(), "test")
+	if err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+}
+
+func TestServer_Auth(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	health, err := client.Health(ctx, &nexusv1.HealthRequest{})
+	if err != nil {
+		t.Fatalf("health should not require a key: %v", err)
+	}
+	if health.GetStatus() != "healthy" {
+		t.Fatalf("expected healthy, got %s", health.GetStatus())
+	}
+
+	req := &nexusv1.GenerateRequest{Prompt: "hi"}
+	if _, err := client.Generate(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a key, got %v", err)
+	}
+	badCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer nx-nope")
+	if _, err := client.Generate(badCtx, req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated with a bad key, got %v", err)
+	}
+	stream, err := client.GenerateStream(badCtx, req)
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated stream with a bad key, got %v", err)
+	}
+}
+
+func TestServer_Generate(t *testing.T) {
+	client, svc := newTestClient(t)
+	ctx := withKey(t, client, svc)
+
+	resp, err := client.Generate(ctx, &nexusv1.GenerateRequest{Prompt: "hi", Provider: "stream"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetContent() != "hello world" || resp.GetProviderUsed() != "Stream" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if resp.GetFingerprint() == "" || resp.GetUsage().GetTotalTokens() != 3 {
+		t.Fatalf("expected fingerprint and usage, got %v", resp)
+	}
+
+	_, err = client.Generate(ctx, &nexusv1.GenerateRequest{Prompt: "hi", Cache: "sometimes"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a bad cache mode, got %v", err)
+	}
+	_, err = client.Generate(ctx, &nexusv1.GenerateRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an empty request, got %v", err)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 {
+		t.Fatalf("expected field violations, got %v", details)
+	}
+	if bad, ok := details[0].(*errdetails.BadRequest); !ok || bad.GetFieldViolations()[0].GetField() != "prompt" {
+		t.Fatalf("expected a violation for prompt, got %v", details[0])
+	}
+}
+
+func TestServer_GenerateStream(t *testing.T) {
+	client, svc := newTestClient(t)
+	ctx := withKey(t, client, svc)
+
+	stream, err := client.GenerateStream(ctx, &nexusv1.GenerateRequest{
+		Messages: []*nexusv1.Message{{Role: "user", Content: "hi"}},
+		Provider: "stream",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var deltas []string
+	var done *nexusv1.GenerateResponse
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("stream failed: %v", err)
+		}
+		if d, ok := msg.GetEvent().(*nexusv1.GenerateStreamResponse_Delta); ok {
+			deltas = append(deltas, d.Delta)
+		}
+		if msg.GetDone() != nil {
+			done = msg.GetDone()
+		}
+	}
+	if strings.Join(deltas, "|") != "hello| world" {
+		t.Fatalf("unexpected deltas: %q", deltas)
+	}
+	if done == nil || done.GetContent() != "hello world" || done.GetFinishReason() != "stop" {
+		t.Fatalf("expected a final response, got %v", done)
+	}
+}
+
+func TestServer_RegisterUserErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		svc      *services.LLMService
+		req      *nexusv1.RegisterUserRequest
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{
+			name:     "no name",
+			svc:      services.NewLLMService(&config.Config{}, &memoryRepo{users: make(map[string]*ports.User)}, nil),
+			req:      &nexusv1.RegisterUserRequest{},
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "name is required",
+		},
+		{
+			name:     "no user storage",
+			svc:      services.NewLLMService(&config.Config{}, nil, nil),
+			req:      &nexusv1.RegisterUserRequest{Name: "Ada"},
+			wantCode: codes.Unavailable,
+			wantMsg:  "user storage not configured",
+		},
+		{
+			name:     "storage failure",
+			svc:      services.NewLLMService(&config.Config{}, &brokenRepo{}, nil),
+			req:      &nexusv1.RegisterUserRequest{Name: "Ada"},
+			wantCode: codes.Internal,
+			wantMsg:  "internal error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServer(tt.svc).RegisterUser(ctx, tt.req)
+			st, _ := status.FromError(err)
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMsg {
+				t.Fatalf("expected %s %q, got %s %q", tt.wantCode, tt.wantMsg, st.Code(), st.Message())
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// AdminHandler serves operational endpoints. Every request must present the
+// configured admin key as a bearer token.
+type AdminHandler struct {
+	service *services.LLMService
+	apiKey  string
+}
+
+var errUnauthorized = ports.NewError(ports.ErrUnauthenticated, "admin key required")
+
+func NewAdminHandler(service *services.LLMService, apiKey string) *AdminHandler {
+	return &AdminHandler{service: service, apiKey: apiKey}
+}
+
+type cacheStatsResponse struct {
+	Enabled           bool             `json:"enabled"`
+	Hits              int64            `json:"hits"`
+	Misses            int64            `json:"misses"`
+	HitRate           float64          `json:"hit_rate"`
+	Entries           int64            `json:"entries"`
+	EntriesByProvider map[string]int64 `json:"entries_by_provider"`
+}
+
+type cachePurgeRequest struct {
+	UserID      string `json:"user_id"`
+	Provider    string `json:"provider"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type cachePurgeResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+func (h *AdminHandler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	stats, err := h.service.CacheStats(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to read cache stats", "err", err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cacheStatsResponse{
+		Enabled:           stats.Enabled,
+		Hits:              stats.Hits,
+		Misses:            stats.Misses,
+		HitRate:           stats.HitRate,
+		Entries:           stats.Entries,
+		EntriesByProvider: stats.EntriesByProvider,
+	})
+}
+
+func (h *AdminHandler) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	var req cachePurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+
+	deleted, err := h.service.PurgeCache(r.Context(), services.CachePurge{
+		UserID:      req.UserID,
+		Provider:    req.Provider,
+		Fingerprint: req.Fingerprint,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to purge cache", "err", err)
+		writeError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Purged cache entries", "deleted", deleted, "user_id", req.UserID, "provider", req.Provider, "fingerprint", req.Fingerprint)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cachePurgeResponse{Deleted: deleted})
+}
+
+type createAPIKeyRequest struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type createAPIKeyResponse struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAPIKey issues a gateway key for the OpenAI-compatible /v1 endpoints.
+// The key is only ever returned in this response.
+func (h *AdminHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+	if req.UserID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+
+	plaintext, key, err := h.service.CreateAPIKey(r.Context(), req.UserID, req.Name)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create API key", "err", err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(createAPIKeyResponse{
+		ID:        key.ID,
+		Key:       plaintext,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.apiKey)) != 1 {
+		writeError(w, r, errUnauthorized)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+func TestAdminHandler_CreateAPIKey(t *testing.T) {
+	// Without user storage an authorized request gets as far as the service.
+	h := NewAdminHandler(services.NewLLMService(&config.Config{}, nil, nil), "admin-secret")
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "no key", wantStatus: http.StatusUnauthorized, wantCode: "unauthenticated"},
+		{name: "gateway key", auth: "Bearer nx-0123", wantStatus: http.StatusUnauthorized, wantCode: "unauthenticated"},
+		{name: "admin key", auth: "Bearer admin-secret", wantStatus: http.StatusServiceUnavailable, wantCode: "not_configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/keys", strings.NewReader(`{"user_id": "user-123", "name": "laptop"}`))
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			h.CreateAPIKey(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Fatalf("expected code %q, got %q", tt.wantCode, resp.Error.Code)
+			}
+		})
+	}
+}
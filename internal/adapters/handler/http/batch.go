@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/willexm1/go-llm-nexus/internal/adapters/batch"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// BatchHandler serves JSONL batch uploads, their status and their results.
+type BatchHandler struct {
+	manager        *batch.Manager
+	maxUploadBytes int64
+}
+
+func NewBatchHandler(manager *batch.Manager, maxUploadBytes int64) *BatchHandler {
+	return &BatchHandler{manager: manager, maxUploadBytes: maxUploadBytes}
+}
+
+// DecodeBatchLine reads one line of a batch file: a /generate request body,
+// optionally with a "custom_id" that is copied to the result.
+func DecodeBatchLine(line []byte) (services.BatchItem, error) {
+	var req GenerateRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return services.BatchItem{}, fmt.Errorf("invalid request: %v", err)
+	}
+	if req.Agent != nil {
+		return services.BatchItem{}, fmt.Errorf("agent mode is not supported in batches")
+	}
+	coreReq, err := req.coreRequest()
+	if err != nil {
+		return services.BatchItem{}, err
+	}
+	return services.BatchItem{Request: coreReq, Provider: req.Provider}, nil
+}
+
+// CreateBatch serves POST /api/batches?user_id=..., whose body is the JSONL
+// input. It answers 202 once the upload is stored.
+func (h *BatchHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+
+	status, err := h.manager.Submit(userID, http.MaxBytesReader(w, r.Body, h.maxUploadBytes))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to accept batch", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Batch accepted", "batch_id", status.ID, "user_id", userID, "requests", status.Requests)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/batches/"+status.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// Batch serves GET /api/batches/{id}?user_id=..., the batch's progress.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+	status, err := h.manager.Status(userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// BatchResults serves GET /api/batches/{id}/results?user_id=... as JSONL.
+func (h *BatchHandler) BatchResults(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+	results, err := h.manager.Results(userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer results.Close()
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	io.Copy(w, results)
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// CollectionHandler serves knowledge collections and their documents.
+type CollectionHandler struct {
+	service *services.KnowledgeService
+	limits  Limits
+}
+
+func NewCollectionHandler(service *services.KnowledgeService, limits Limits) *CollectionHandler {
+	return &CollectionHandler{service: service, limits: limits.withDefaults()}
+}
+
+type createCollectionRequest struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// EmbeddingProvider and EmbeddingModel default to the /embeddings defaults.
+	EmbeddingProvider string `json:"embedding_provider"`
+	EmbeddingModel    string `json:"embedding_model"`
+}
+
+type collectionResponse struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	EmbeddingProvider string    `json:"embedding_provider"`
+	EmbeddingModel    string    `json:"embedding_model"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type addDocumentRequest struct {
+	UserID string `json:"user_id"`
+	Title  string `json:"title"`
+	// ContentType is "text", "markdown" or "html" (MIME types work too).
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+type documentResponse struct {
+	ID           string    `json:"id"`
+	CollectionID string    `json:"collection_id"`
+	Title        string    `json:"title"`
+	ContentType  string    `json:"content_type"`
+	Chunks       int       `json:"chunks"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CitationPayload struct {
+	Index         int     `json:"index"`
+	DocumentID    string  `json:"document_id"`
+	DocumentTitle string  `json:"document_title"`
+	ChunkID       string  `json:"chunk_id"`
+	Score         float64 `json:"score"`
+	Text          string  `json:"text"`
+}
+
+// CreateCollection serves POST /api/collections.
+func (h *CollectionHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req createCollectionRequest
+	if err := h.limits.decode(w, r, &req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+	if req.UserID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+	if req.Name == "" {
+		badRequest(w, r, "name is required")
+		return
+	}
+
+	c, err := h.service.CreateCollection(r.Context(), ports.Collection{
+		UserID:            req.UserID,
+		Name:              req.Name,
+		EmbeddingProvider: req.EmbeddingProvider,
+		EmbeddingModel:    req.EmbeddingModel,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create collection", "err", err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collectionResponse{
+		ID:                c.ID,
+		Name:              c.Name,
+		EmbeddingProvider: c.EmbeddingProvider,
+		EmbeddingModel:    c.EmbeddingModel,
+		CreatedAt:         c.CreatedAt,
+	})
+}
+
+// AddDocument serves POST /api/collections/{id}/documents.
+func (h *CollectionHandler) AddDocument(w http.ResponseWriter, r *http.Request) {
+	collectionID := r.PathValue("id")
+	var req addDocumentRequest
+	if err := h.limits.decode(w, r, &req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+	if req.UserID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+
+	start := time.Now()
+	doc, err := h.service.AddDocument(r.Context(), req.UserID, collectionID, services.DocumentUpload{
+		Title:       req.Title,
+		ContentType: req.ContentType,
+		Content:     req.Content,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to add document", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Document indexed", "collection_id", collectionID, "chunks", doc.Chunks, "duration_ms", time.Since(start).Milliseconds())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(documentResponse{
+		ID:           doc.ID,
+		CollectionID: doc.CollectionID,
+		Title:        doc.Title,
+		ContentType:  doc.ContentType,
+		Chunks:       doc.Chunks,
+		CreatedAt:    doc.CreatedAt,
+	})
+}
+
+func convertCitations(citations []ports.Citation) []CitationPayload {
+	var out []CitationPayload
+	for _, c := range citations {
+		out = append(out, CitationPayload{
+			Index:         c.Index,
+			DocumentID:    c.DocumentID,
+			DocumentTitle: c.DocumentTitle,
+			ChunkID:       c.ChunkID,
+			Score:         c.Score,
+			Text:          c.Text,
+		})
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+type EmbeddingsRequest struct {
+	UserID string `json:"user_id"`
+	// Input is a single string or a list of strings.
+	Input      embeddingInput `json:"input"`
+	Provider   string         `json:"provider"`
+	Model      string         `json:"model"`
+	Dimensions int32          `json:"dimensions"`
+}
+
+// embeddingInput accepts both a single string and a list of strings.
+type embeddingInput []string
+
+func (in *embeddingInput) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*in = embeddingInput{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("input must be a string or an array of strings")
+	}
+	*in = many
+	return nil
+}
+
+type EmbeddingsResponse struct {
+	Data             []EmbeddingPayload `json:"data"`
+	Model            string             `json:"model"`
+	ProviderUsed     string             `json:"provider_used"`
+	ProcessingTimeMs int64              `json:"processing_time_ms"`
+	Usage            *UsagePayload      `json:"usage,omitempty"`
+}
+
+type EmbeddingPayload struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+func (h *Handler) Embeddings(w http.ResponseWriter, r *http.Request) {
+	var req EmbeddingsRequest
+	if err := h.limits.decode(w, r, &req); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode embeddings request", "err", err)
+		invalidBody(w, r, err)
+		return
+	}
+	if req.UserID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+
+	start := time.Now()
+	resp, providerUsed, err := h.service.Embed(r.Context(), ports.EmbeddingRequest{
+		UserID:     req.UserID,
+		Model:      req.Model,
+		Inputs:     req.Input,
+		Dimensions: req.Dimensions,
+	}, req.Provider)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error embedding inputs", "err", err)
+		writeError(w, r, err)
+		return
+	}
+
+	duration := time.Since(start)
+	slog.InfoContext(r.Context(), "Embeddings completed", "provider", providerUsed, "inputs", len(req.Input), "duration_ms", duration.Milliseconds())
+
+	data := make([]EmbeddingPayload, len(resp.Vectors))
+	for i, v := range resp.Vectors {
+		data[i] = EmbeddingPayload{Index: i, Embedding: v}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EmbeddingsResponse{
+		Data:             data,
+		Model:            resp.Model,
+		ProviderUsed:     providerUsed,
+		ProcessingTimeMs: duration.Milliseconds(),
+		Usage:            convertUsage(resp.Usage),
+	})
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
+)
+
+// ErrorResponse is the body of every failed request.
+type ErrorResponse struct {
+	Error ErrorPayload `json:"error"`
+}
+
+type ErrorPayload struct {
+	// Code names the error class, e.g. "rate_limited"; see ports.ErrorCode.
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	// Provider is set when an upstream provider refused or failed the call.
+	Provider string `json:"provider,omitempty"`
+	// Fields lists the invalid fields of a request that failed validation.
+	Fields []FieldErrorPayload `json:"fields,omitempty"`
+}
+
+type FieldErrorPayload struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorStatus maps error codes, see ports.ErrorCode, onto status codes.
+var errorStatus = map[string]int{
+	"invalid_argument":     http.StatusBadRequest,
+	"unauthenticated":      http.StatusUnauthorized,
+	"budget_exceeded":      http.StatusPaymentRequired,
+	"not_found":            http.StatusNotFound,
+	"too_large":            http.StatusRequestEntityTooLarge,
+	"content_filtered":     http.StatusUnprocessableEntity,
+	"rate_limited":         http.StatusTooManyRequests,
+	"invalid_output":       http.StatusBadGateway,
+	"provider_unavailable": http.StatusServiceUnavailable,
+	"not_configured":       http.StatusServiceUnavailable,
+	"timeout":              http.StatusGatewayTimeout,
+}
+
+// writeError reports err as an ErrorResponse. Provider failures are
+// described without the upstream response, and unclassified errors, which
+// may carry internal detail, only as "internal error" and are logged.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = ports.Errorf(ports.ErrTooLarge, "request body exceeds %d bytes", tooLarge.Limit)
+	}
+	payload := ErrorPayload{Code: ports.ErrorCode(err), Message: ports.PublicMessage(err)}
+	status, ok := errorStatus[payload.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+		slog.ErrorContext(r.Context(), "Internal error", "err", err)
+	}
+	var provErr *ports.ProviderError
+	if errors.As(err, &provErr) {
+		payload.Provider = provErr.Provider
+	}
+	var invalid *ports.ValidationError
+	if errors.As(err, &invalid) {
+		for _, f := range invalid.Fields {
+			payload.Fields = append(payload.Fields, FieldErrorPayload{Field: f.Field, Message: f.Message})
+		}
+	}
+	payload.RequestID = logging.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: payload})
+}
+
+// invalidBody reports a request body that could not be decoded, naming the
+// field at fault where the decoder does.
+func invalidBody(w http.ResponseWriter, r *http.Request, err error) {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		err = &ports.ValidationError{Fields: []ports.FieldError{{Field: typeErr.Field, Message: "cannot be a " + typeErr.Value}}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		err = &ports.ValidationError{Fields: []ports.FieldError{{Field: field, Message: "is not a known field"}}}
+	default:
+		err = ports.Errorf(ports.ErrInvalidArgument, "invalid request body: %w", err)
+	}
+	writeError(w, r, err)
+}
+
+// badRequest reports a request missing or misusing a field.
+func badRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeError(w, r, ports.NewError(ports.ErrInvalidArgument, message))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorPayload{
+		Code:      "method_not_allowed",
+		Message:   "method not allowed",
+		RequestID: logging.RequestID(r.Context()),
+	}})
+}
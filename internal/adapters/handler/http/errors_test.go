@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
+)
+
+func TestWriteError(t *testing.T) {
+	upstream := errors.New(`openai api error (status 429): {"error":{"message":"secret org-123"}}`)
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		want       ErrorPayload
+	}{
+		{
+			name:       "provider error",
+			err:        fmt.Errorf("all providers failed: %w", &ports.ProviderError{Provider: "openai", Class: ports.ErrRateLimited, Err: upstream}),
+			wantStatus: http.StatusTooManyRequests,
+			want:       ErrorPayload{Code: "rate_limited", Message: "openai: rate limited by provider", Provider: "openai"},
+		},
+		{
+			name:       "service error",
+			err:        fmt.Errorf("%w: gpt-9", services.ErrUnknownModel),
+			wantStatus: http.StatusBadRequest,
+			want:       ErrorPayload{Code: "invalid_argument", Message: "unknown model: gpt-9"},
+		},
+		{
+			name: "validation error",
+			err: &ports.ValidationError{Fields: []ports.FieldError{
+				{Field: "temperature", Message: "must be between 0 and 2 for gpt-4o"},
+				{Field: "max_tokens", Message: "must not be negative"},
+			}},
+			wantStatus: http.StatusBadRequest,
+			want: ErrorPayload{
+				Code:    "invalid_argument",
+				Message: "invalid request: temperature must be between 0 and 2 for gpt-4o; max_tokens must not be negative",
+				Fields: []FieldErrorPayload{
+					{Field: "temperature", Message: "must be between 0 and 2 for gpt-4o"},
+					{Field: "max_tokens", Message: "must not be negative"},
+				},
+			},
+		},
+		{
+			name:       "body too large",
+			err:        &http.MaxBytesError{Limit: 1024},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			want:       ErrorPayload{Code: "too_large", Message: "request body exceeds 1024 bytes"},
+		},
+		{
+			name:       "unclassified",
+			err:        errors.New("pq: password authentication failed"),
+			wantStatus: http.StatusInternalServerError,
+			want:       ErrorPayload{Code: "internal", Message: "internal error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/generate", nil)
+			r = r.WithContext(logging.WithRequestID(r.Context(), "req-1"))
+			w := httptest.NewRecorder()
+			writeError(w, r, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Fatalf("response leaks upstream detail: %s", w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+			}
+			tt.want.RequestID = "req-1"
+			if !reflect.DeepEqual(resp.Error, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, resp.Error)
+			}
+		})
+	}
+}
+
+func TestHandler_DecodeLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     Limits
+		body       string
+		wantStatus int
+		wantFields []FieldErrorPayload
+	}{
+		{
+			name:       "body too large",
+			limits:     Limits{MaxBodyBytes: 64},
+			body:       `{"user_id": "u1", "prompt": "` + strings.Repeat("x", 100) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "unknown field",
+			limits:     Limits{RejectUnknownFields: true},
+			body:       `{"user_id": "u1", "prompt": "hi", "temprature": 0.5}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []FieldErrorPayload{{Field: "temprature", Message: "is not a known field"}},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"user_id": "u1", "prompt": "hi", "max_tokens": "many"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []FieldErrorPayload{{Field: "max_tokens", Message: "cannot be a string"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(nil, tt.limits)
+			w := httptest.NewRecorder()
+			h.Generate(w, httptest.NewRequest("POST", "/api/generate", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+			}
+			if !reflect.DeepEqual(resp.Error.Fields, tt.wantFields) {
+				t.Fatalf("expected fields %+v, got %+v", tt.wantFields, resp.Error.Fields)
+			}
+		})
+	}
+}
@@ -2,35 +2,180 @@ package http
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
 	"github.com/willexm1/go-llm-nexus/internal/core/services"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
 )
 
+var tracer = otel.Tracer("github.com/willexm1/go-llm-nexus/internal/adapters/handler/http")
+
+const defaultMaxBodyBytes = 32 << 20
+
+// Limits bound the JSON request bodies a Handler accepts.
+type Limits struct {
+	// MaxBodyBytes caps a request body; larger ones are refused with 413.
+	MaxBodyBytes int64
+	// RejectUnknownFields refuses bodies with fields the endpoint does not
+	// know instead of ignoring them.
+	RejectUnknownFields bool
+}
+
 type Handler struct {
 	service *services.LLMService
+	limits  Limits
+}
+
+func NewHandler(service *services.LLMService, limits Limits) *Handler {
+	return &Handler{service: service, limits: limits.withDefaults()}
+}
+
+// withDefaults fills in the limits left unset.
+func (l Limits) withDefaults() Limits {
+	if l.MaxBodyBytes <= 0 {
+		l.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	return l
 }
 
-func NewHandler(service *services.LLMService) *Handler {
-	return &Handler{service: service}
+// decode reads a JSON request body into v within the limits.
+func (l Limits) decode(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, l.MaxBodyBytes))
+	if l.RejectUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
 }
 
 type GenerateRequest struct {
-	UserID      string  `json:"user_id"`
-	Prompt      string  `json:"prompt"`
-	Provider    string  `json:"provider"`
+	UserID   string `json:"user_id"`
+	Prompt   string `json:"prompt"`
+	Provider string `json:"provider"`
+	// Model is a catalog alias such as "fast" or an explicit model ID.
+	Model       string  `json:"model"`
 	Temperature float32 `json:"temperature"`
 	MaxTokens   int32   `json:"max_tokens"`
+	// Cache is one of "bypass", "refresh" or "only"; empty uses the cache normally.
+	Cache           string `json:"cache"`
+	CacheTTLSeconds int    `json:"cache_ttl_seconds"`
+	// Routing selects a strategy ("cheapest", "latency", ...) when Provider is empty.
+	Routing string `json:"routing"`
+	// Hedge overrides the server's hedging policy for this request.
+	Hedge *HedgePayload `json:"hedge,omitempty"`
+	// Messages replaces Prompt with a full conversation, including tool calls
+	// and tool results from earlier turns.
+	Messages []MessagePayload `json:"messages,omitempty"`
+	Tools    []ToolPayload    `json:"tools,omitempty"`
+	// ToolChoice is "auto", "none", "required" or the name of a tool to force.
+	ToolChoice string `json:"tool_choice,omitempty"`
+	// Agent switches to agent mode: the nexus runs its own tools until the
+	// model answers. Tools in the request are not allowed in this mode.
+	Agent *AgentPayload `json:"agent,omitempty"`
+	// ResponseFormat asks for JSON output, validated before it is returned.
+	ResponseFormat *ResponseFormatPayload `json:"response_format,omitempty"`
+	// Attachments are images or files sent alongside Prompt in one user message.
+	Attachments []ContentPartPayload `json:"attachments,omitempty"`
+	// CollectionID grounds the answer on a knowledge collection; TopK
+	// overrides how many chunks are retrieved.
+	CollectionID string `json:"collection_id,omitempty"`
+	TopK         int    `json:"top_k,omitempty"`
+	// Template renders the prompt from a stored template, "name" or
+	// "name@version", with Variables; Prompt and Messages must be empty.
+	Template  string         `json:"template,omitempty"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type ContentPartPayload struct {
+	// Type is "text", "image" or "file".
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// URL points at an image; Data carries inline bytes as base64.
+	URL      string `json:"url,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+type ResponseFormatPayload struct {
+	// Type is "text", "json_object" or "json_schema".
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// Repair retries once with the validation errors when the answer is invalid.
+	Repair bool `json:"repair"`
+}
+
+type AgentPayload struct {
+	MaxSteps   int      `json:"max_steps"`
+	MaxCostUSD float64  `json:"max_cost_usd"`
+	Tools      []string `json:"tools"`
+}
+
+type AgentStepPayload struct {
+	Step        int               `json:"step"`
+	Provider    string            `json:"provider"`
+	Content     string            `json:"content,omitempty"`
+	ToolCalls   []ToolCallPayload `json:"tool_calls,omitempty"`
+	ToolResults []MessagePayload  `json:"tool_results,omitempty"`
+	Usage       *UsagePayload     `json:"usage,omitempty"`
+}
+
+type MessagePayload struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Parts replaces Content for messages carrying images or files.
+	Parts      []ContentPartPayload `json:"parts,omitempty"`
+	ToolCalls  []ToolCallPayload    `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
+	Name       string               `json:"name,omitempty"`
+}
+
+type ToolPayload struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is a JSON Schema object describing the tool's arguments.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+type ToolCallPayload struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type HedgePayload struct {
+	Mode      string `json:"mode"`
+	DelayMs   int64  `json:"delay_ms"`
+	Secondary string `json:"secondary"`
 }
 
 type GenerateResponse struct {
 	Content          string        `json:"content"`
 	ProviderUsed     string        `json:"provider_used"`
+	ModelUsed        string        `json:"model_used,omitempty"`
 	ProcessingTimeMs int64         `json:"processing_time_ms"`
+	CacheHit         bool          `json:"cache_hit"`
+	Fingerprint      string        `json:"fingerprint"`
 	Usage            *UsagePayload `json:"usage,omitempty"`
+	// ToolCalls are set when the model wants tools run; send their results
+	// back as "tool" messages to continue.
+	ToolCalls    []ToolCallPayload `json:"tool_calls,omitempty"`
+	FinishReason string            `json:"finish_reason,omitempty"`
+	// JSON is the parsed answer when a JSON response format was requested.
+	JSON json.RawMessage `json:"json,omitempty"`
+	// Citations are the collection excerpts the answer was grounded on.
+	Citations []CitationPayload `json:"citations,omitempty"`
+	// TraceID, StopReason and Steps describe an agent run.
+	TraceID    string             `json:"trace_id,omitempty"`
+	StopReason string             `json:"stop_reason,omitempty"`
+	Steps      []AgentStepPayload `json:"steps,omitempty"`
 }
 
 type UsagePayload struct {
@@ -41,82 +186,246 @@ type UsagePayload struct {
 }
 
 func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	if r.Method != "POST" {
-		log.Printf("[HTTP] Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	ctx, span := tracer.Start(r.Context(), "Handler.Generate")
+	defer span.End()
+	r = r.WithContext(ctx)
 
 	var req GenerateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[HTTP] Failed to decode request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := h.limits.decode(w, r, &req); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode request body", "err", err)
+		invalidBody(w, r, err)
 		return
 	}
 
 	if req.UserID == "" {
-		log.Printf("[HTTP] Missing user identifier")
-		http.Error(w, "user_id is required", http.StatusBadRequest)
+		slog.WarnContext(r.Context(), "Missing user identifier")
+		badRequest(w, r, "user_id is required")
 		return
 	}
 
-	log.Printf("[HTTP] Received request - Provider: %s, User: %s, Prompt: %.50s...", req.Provider, req.UserID, req.Prompt)
+	slog.InfoContext(r.Context(), "Received generate request", "provider", req.Provider, "user_id", req.UserID, logging.Content("prompt", req.Prompt))
 
 	start := time.Now()
-	coreReq := ports.LLMRequest{
-		UserID:      req.UserID,
-		Prompt:      req.Prompt,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
+	coreReq, err := req.coreRequest()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if req.Agent != nil {
+		h.generateAgent(w, r, req, coreReq, start)
+		return
 	}
 
 	resp, providerUsed, err := h.service.ProcessRequest(r.Context(), coreReq, req.Provider)
 	if err != nil {
-		log.Printf("[HTTP] Error processing request: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error processing request", "err", err)
+		span.SetStatus(codes.Error, err.Error())
+		writeError(w, r, err)
 		return
 	}
+	span.SetAttributes(attribute.String("nexus.provider", providerUsed))
 
 	duration := time.Since(start)
-	log.Printf("[HTTP] Request completed - Provider: %s, Duration: %v", providerUsed, duration)
+	slog.InfoContext(r.Context(), "Request completed", "provider", providerUsed, "duration_ms", duration.Milliseconds(), logging.Content("response", resp.Content))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(GenerateResponse{
 		Content:          resp.Content,
 		ProviderUsed:     providerUsed,
+		ModelUsed:        resp.Model,
 		ProcessingTimeMs: duration.Milliseconds(),
+		CacheHit:         providerUsed == services.CachedProvider,
+		Fingerprint:      resp.Fingerprint,
 		Usage:            convertUsage(resp.Usage),
+		ToolCalls:        convertToolCalls(resp.ToolCalls),
+		FinishReason:     resp.FinishReason,
+		JSON:             resp.JSON,
+		Citations:        convertCitations(resp.Citations),
 	})
 }
 
-func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+// generateAgent serves a /generate request in agent mode, where the nexus
+// runs its own tools until the model produces an answer.
+func (h *Handler) generateAgent(w http.ResponseWriter, r *http.Request, req GenerateRequest, coreReq ports.LLMRequest, start time.Time) {
+	result, err := h.service.RunAgent(r.Context(), coreReq, req.Provider, services.AgentOptions{
+		MaxSteps:   req.Agent.MaxSteps,
+		MaxCostUSD: req.Agent.MaxCostUSD,
+		Tools:      req.Agent.Tools,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error running agent", "err", err)
+		writeError(w, r, err)
 		return
 	}
 
+	duration := time.Since(start)
+	slog.InfoContext(r.Context(), "Agent run completed", "trace_id", result.TraceID, "steps", len(result.Steps), "stop_reason", result.StopReason, "duration_ms", duration.Milliseconds())
+
+	steps := make([]AgentStepPayload, 0, len(result.Steps))
+	for _, st := range result.Steps {
+		payload := AgentStepPayload{
+			Step:      st.Step,
+			Provider:  st.Provider,
+			Content:   st.Content,
+			ToolCalls: convertToolCalls(st.ToolCalls),
+			Usage:     convertUsage(st.Usage),
+		}
+		for _, m := range st.ToolResults {
+			payload.ToolResults = append(payload.ToolResults, MessagePayload{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID, Name: m.Name})
+		}
+		steps = append(steps, payload)
+	}
+	usage := result.Usage
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "go-llm-nexus",
+	json.NewEncoder(w).Encode(GenerateResponse{
+		Content:          result.Response.Content,
+		ProviderUsed:     result.Provider,
+		ModelUsed:        result.Response.Model,
+		ProcessingTimeMs: duration.Milliseconds(),
+		Fingerprint:      result.Response.Fingerprint,
+		Usage:            convertUsage(&usage),
+		ToolCalls:        convertToolCalls(result.Response.ToolCalls),
+		FinishReason:     result.Response.FinishReason,
+		JSON:             result.Response.JSON,
+		Citations:        convertCitations(result.Response.Citations),
+		TraceID:          result.TraceID,
+		StopReason:       result.StopReason,
+		Steps:            steps,
 	})
 }
 
+// coreRequest converts the payload into the service's request type.
+func (req GenerateRequest) coreRequest() (ports.LLMRequest, error) {
+	coreReq := ports.LLMRequest{
+		UserID:      req.UserID,
+		Model:       req.Model,
+		Prompt:      req.Prompt,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		CacheMode:   ports.CacheMode(req.Cache),
+		CacheTTL:    time.Duration(req.CacheTTLSeconds) * time.Second,
+		Routing:     req.Routing,
+		ToolChoice:  req.ToolChoice,
+
+		CollectionID:  req.CollectionID,
+		RetrievalTopK: req.TopK,
+	}
+	if f := req.ResponseFormat; f != nil {
+		coreReq.ResponseFormat = &ports.ResponseFormat{
+			Type:   ports.ResponseFormatType(f.Type),
+			Name:   f.Name,
+			Schema: f.Schema,
+			Repair: f.Repair,
+		}
+	}
+	for _, m := range req.Messages {
+		msg := ports.Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID, Name: m.Name, Parts: convertParts(m.Parts)}
+		for _, call := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, ports.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+		}
+		coreReq.Messages = append(coreReq.Messages, msg)
+	}
+	if len(req.Attachments) > 0 {
+		var parts []ports.ContentPart
+		if req.Prompt != "" {
+			parts = append(parts, ports.ContentPart{Type: ports.PartText, Text: req.Prompt})
+		}
+		coreReq.Messages = append(coreReq.Messages, ports.Message{Role: ports.RoleUser, Parts: append(parts, convertParts(req.Attachments)...)})
+	}
+	for _, tool := range req.Tools {
+		coreReq.Tools = append(coreReq.Tools, ports.Tool{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+	}
+	if req.Template != "" {
+		name, version, err := services.ParseTemplateRef(req.Template)
+		if err != nil {
+			return coreReq, err
+		}
+		coreReq.Template = &ports.TemplateRef{Name: name, Version: version, Variables: req.Variables}
+	}
+	if req.Hedge != nil {
+		coreReq.Hedge = &ports.HedgePolicy{
+			Mode:      ports.HedgeMode(req.Hedge.Mode),
+			Delay:     time.Duration(req.Hedge.DelayMs) * time.Millisecond,
+			Secondary: req.Hedge.Secondary,
+		}
+	}
+	return coreReq, nil
+}
+
+func convertParts(parts []ContentPartPayload) []ports.ContentPart {
+	var out []ports.ContentPart
+	for _, p := range parts {
+		out = append(out, ports.ContentPart{
+			Type:     ports.PartType(p.Type),
+			Text:     p.Text,
+			URL:      p.URL,
+			Data:     p.Data,
+			MIMEType: p.MIMEType,
+			Filename: p.Filename,
+		})
+	}
+	return out
+}
+
+type modelPayload struct {
+	ID                   string   `json:"id"`
+	Provider             string   `json:"provider"`
+	UpstreamID           string   `json:"upstream_id"`
+	ContextWindow        int32    `json:"context_window"`
+	MaxOutputTokens      int32    `json:"max_output_tokens"`
+	InputCostPer1K       float64  `json:"input_cost_per_1k"`
+	OutputCostPer1K      float64  `json:"output_cost_per_1k"`
+	CachedInputCostPer1K float64  `json:"cached_input_cost_per_1k"`
+	Capabilities         []string `json:"capabilities"`
+	Default              bool     `json:"default"`
+	Available            bool     `json:"available"`
+}
+
+type modelsResponse struct {
+	Models  []modelPayload    `json:"models"`
+	Aliases map[string]string `json:"aliases"`
+}
+
+func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
+	catalog := h.service.Catalog()
+	models := catalog.Models()
+	resp := modelsResponse{
+		Models:  make([]modelPayload, 0, len(models)),
+		Aliases: catalog.Aliases(),
+	}
+	for _, m := range models {
+		def, _ := catalog.Default(m.Provider)
+		capabilities := []string{}
+		if m.Capabilities.Vision {
+			capabilities = append(capabilities, "vision")
+		}
+		if m.Capabilities.Tools {
+			capabilities = append(capabilities, "tools")
+		}
+		if m.Capabilities.JSON {
+			capabilities = append(capabilities, "json")
+		}
+		resp.Models = append(resp.Models, modelPayload{
+			ID:                   m.ID,
+			Provider:             m.Provider,
+			UpstreamID:           m.UpstreamID,
+			ContextWindow:        m.ContextWindow,
+			MaxOutputTokens:      m.MaxOutputTokens,
+			InputCostPer1K:       m.InputCostPer1K,
+			OutputCostPer1K:      m.OutputCostPer1K,
+			CachedInputCostPer1K: m.CachedInputCostPer1K,
+			Capabilities:         capabilities,
+			Default:              def.ID == m.ID,
+			Available:            h.service.HasProvider(m.Provider),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 type registerUserRequest struct {
 	Name string `json:"name"`
 }
@@ -128,36 +437,21 @@ type registerUserResponse struct {
 }
 
 func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	if r.Method != "POST" {
-		log.Printf("[HTTP] Method not allowed for register user: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var req registerUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[HTTP] Failed to decode register user request: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := h.limits.decode(w, r, &req); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode register user request", "err", err)
+		invalidBody(w, r, err)
 		return
 	}
 	if req.Name == "" {
-		http.Error(w, "name is required", http.StatusBadRequest)
+		badRequest(w, r, "name is required")
 		return
 	}
 
 	user, err := h.service.RegisterUser(r.Context(), req.Name)
 	if err != nil {
-		log.Printf("[HTTP] Failed to register user: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to register user", "err", err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -180,3 +474,11 @@ func convertUsage(u *ports.UsageInfo) *UsagePayload {
 		CostUSD:          u.CostUSD,
 	}
 }
+
+func convertToolCalls(calls []ports.ToolCall) []ToolCallPayload {
+	var out []ToolCallPayload
+	for _, c := range calls {
+		out = append(out, ToolCallPayload{ID: c.ID, Name: c.Name, Arguments: c.Arguments})
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// HealthHandler serves the health, liveness and readiness probes.
+type HealthHandler struct {
+	service *services.HealthService
+}
+
+func NewHealthHandler(service *services.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "healthy",
+		"service": "go-llm-nexus",
+	})
+}
+
+// Liveness serves GET /api/health/live. It only shows the process is
+// serving HTTP, so a failing dependency never gets the server restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+type componentHealthPayload struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+type readinessResponse struct {
+	Status     string                   `json:"status"`
+	CheckedAt  time.Time                `json:"checked_at"`
+	Components []componentHealthPayload `json:"components"`
+}
+
+// Readiness serves GET /api/health/ready: 200 while requests can be served,
+// degraded included, and 503 once they would fail.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	readiness := h.service.Readiness(r.Context())
+	resp := readinessResponse{
+		Status:     readiness.Status,
+		CheckedAt:  readiness.CheckedAt,
+		Components: make([]componentHealthPayload, 0, len(readiness.Components)),
+	}
+	for _, c := range readiness.Components {
+		resp.Components = append(resp.Components, componentHealthPayload{
+			Name:      c.Name,
+			Kind:      c.Kind,
+			Status:    c.Status,
+			LatencyMs: c.Latency.Milliseconds(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if readiness.Status == services.ReadinessUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// JobHandler serves asynchronous generation jobs.
+type JobHandler struct {
+	service *services.JobService
+	limits  Limits
+}
+
+func NewJobHandler(service *services.JobService, limits Limits) *JobHandler {
+	return &JobHandler{service: service, limits: limits.withDefaults()}
+}
+
+type createJobRequest struct {
+	GenerateRequest
+	// WebhookURL receives the finished job as a signed POST.
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+type jobResponse struct {
+	ID       string          `json:"id"`
+	Status   ports.JobStatus `json:"status"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error,omitempty"`
+	// ErrorCode classifies Error like the code of an ErrorResponse.
+	ErrorCode string `json:"error_code,omitempty"`
+	// Result is set once the job has succeeded.
+	Result        *GenerateResponse   `json:"result,omitempty"`
+	WebhookStatus ports.WebhookStatus `json:"webhook_status,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	StartedAt     *time.Time          `json:"started_at,omitempty"`
+	FinishedAt    *time.Time          `json:"finished_at,omitempty"`
+}
+
+// CreateJob serves POST /api/jobs. It accepts the /generate request body and
+// answers 202 with the job's ID as soon as the job is queued.
+func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var req createJobRequest
+	if err := h.limits.decode(w, r, &req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+	if req.UserID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+	if req.Agent != nil {
+		badRequest(w, r, "agent mode is not supported for jobs")
+		return
+	}
+	coreReq, err := req.coreRequest()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	job, err := h.service.SubmitJob(r.Context(), coreReq, req.Provider, req.WebhookURL)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to queue job", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Job queued", "job_id", job.ID, "user_id", job.UserID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(convertJob(job))
+}
+
+// GetJob serves GET /api/jobs/{id}?user_id=...
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		badRequest(w, r, "user_id is required")
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertJob(job))
+}
+
+func convertJob(job *ports.Job) jobResponse {
+	out := jobResponse{
+		ID:            job.ID,
+		Status:        job.Status,
+		Attempts:      job.Attempts,
+		Error:         job.Error,
+		ErrorCode:     job.ErrorCode,
+		WebhookStatus: job.WebhookStatus,
+		CreatedAt:     job.CreatedAt,
+		StartedAt:     job.StartedAt,
+		FinishedAt:    job.FinishedAt,
+	}
+	if resp := job.Result; resp != nil {
+		result := &GenerateResponse{
+			Content:      resp.Content,
+			ProviderUsed: job.ProviderUsed,
+			ModelUsed:    resp.Model,
+			CacheHit:     job.ProviderUsed == services.CachedProvider,
+			Fingerprint:  resp.Fingerprint,
+			Usage:        convertUsage(resp.Usage),
+			ToolCalls:    convertToolCalls(resp.ToolCalls),
+			FinishReason: resp.FinishReason,
+			JSON:         resp.JSON,
+			Citations:    convertCitations(resp.Citations),
+		}
+		if job.StartedAt != nil && job.FinishedAt != nil {
+			result.ProcessingTimeMs = job.FinishedAt.Sub(*job.StartedAt).Milliseconds()
+		}
+		out.Result = result
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	policy := CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         10 * time.Minute,
+	}
+	tests := []struct {
+		name       string
+		policy     CORSPolicy
+		method     string
+		origin     string
+		preflight  bool
+		wantStatus int
+		want       map[string]string
+	}{
+		{
+			name: "same origin", policy: policy, method: "POST",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "allowed origin", policy: policy, method: "POST", origin: "https://app.example.com",
+			wantStatus: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Expose-Headers": RequestIDHeader,
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name: "other origin", policy: policy, method: "POST", origin: "https://evil.example.com",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "preflight", policy: policy, method: "OPTIONS", origin: "https://app.example.com", preflight: true,
+			wantStatus: http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name: "preflight from other origin", policy: policy, method: "OPTIONS", origin: "https://evil.example.com", preflight: true,
+			wantStatus: http.StatusNoContent,
+			want:       map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			name: "any origin", policy: CORSPolicy{AllowedOrigins: []string{"*"}}, method: "GET", origin: "https://app.example.com",
+			wantStatus: http.StatusOK,
+			want:       map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			name: "any origin with credentials", policy: CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, method: "GET", origin: "https://app.example.com",
+			wantStatus: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/generate", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			rec := httptest.NewRecorder()
+			CORS(tt.policy)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			for header, want := range tt.want {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("expected %s %q, got %q", header, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rec := httptest.NewRecorder()
+	SecurityHeaders(0)(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/api/health", nil))
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" || rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("expected security headers, got %v", rec.Header())
+	}
+	if hsts := rec.Header().Get("Strict-Transport-Security"); hsts != "" {
+		t.Fatalf("expected no HSTS by default, got %q", hsts)
+	}
+
+	rec = httptest.NewRecorder()
+	SecurityHeaders(365*24*time.Hour)(ok).ServeHTTP(rec, httptest.NewRequest("GET", "/api/health", nil))
+	if hsts := rec.Header().Get("Strict-Transport-Security"); hsts != "max-age=31536000" {
+		t.Fatalf("expected HSTS for a year, got %q", hsts)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/generate", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /api/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("id")))
+	})
+	tests := []struct {
+		method, path string
+		wantStatus   int
+		wantCode     string
+	}{
+		{method: "POST", path: "/api/generate", wantStatus: http.StatusOK},
+		{method: "GET", path: "/api/jobs/42", wantStatus: http.StatusOK},
+		{method: "GET", path: "/api/generate", wantStatus: http.StatusMethodNotAllowed, wantCode: "method_not_allowed"},
+		{method: "GET", path: "/api/unknown", wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{method: "GET", path: "/api/jobs/42/extra", wantStatus: http.StatusNotFound, wantCode: "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Routes(mux).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected a JSON error, got %q", rec.Body.String())
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Fatalf("expected code %q, got %q", tt.wantCode, body.Error.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// TemplateHandler serves the prompt template registry.
+type TemplateHandler struct {
+	service *services.TemplateService
+	limits  Limits
+}
+
+func NewTemplateHandler(service *services.TemplateService, limits Limits) *TemplateHandler {
+	return &TemplateHandler{service: service, limits: limits.withDefaults()}
+}
+
+type TemplatePayload struct {
+	Name        string                   `json:"name"`
+	Version     int                      `json:"version,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	System      string                   `json:"system,omitempty"`
+	Body        string                   `json:"body"`
+	Variables   []ports.TemplateVariable `json:"variables,omitempty"`
+	CreatedAt   *time.Time               `json:"created_at,omitempty"`
+}
+
+// ListTemplates serves GET /api/templates, the latest version of every
+// template.
+func (h *TemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.ListTemplates(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list templates", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	out := make([]TemplatePayload, 0, len(templates))
+	for _, t := range templates {
+		out = append(out, templatePayload(t))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]TemplatePayload{"templates": out})
+}
+
+// CreateTemplate serves POST /api/templates, which saves a new version.
+func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req TemplatePayload
+	if err := h.limits.decode(w, r, &req); err != nil {
+		invalidBody(w, r, err)
+		return
+	}
+	t, err := h.service.CreateTemplate(r.Context(), ports.PromptTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		System:      req.System,
+		Body:        req.Body,
+		Variables:   req.Variables,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create template", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Template saved", "template", t.Name, "version", t.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(templatePayload(*t))
+}
+
+// GetTemplate serves GET /api/templates/{name}, the latest version or
+// ?version=N.
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if raw := r.URL.Query().Get("version"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			badRequest(w, r, "version must be a positive number")
+			return
+		}
+		version = v
+	}
+	t, err := h.service.GetTemplate(r.Context(), r.PathValue("name"), version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templatePayload(*t))
+}
+
+// TemplateVersions serves GET /api/templates/{name}/versions.
+func (h *TemplateHandler) TemplateVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.service.TemplateVersions(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	out := make([]TemplatePayload, 0, len(versions))
+	for _, t := range versions {
+		out = append(out, templatePayload(t))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]TemplatePayload{"versions": out})
+}
+
+// DeleteTemplate serves DELETE /api/templates/{name}, which removes every
+// version.
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteTemplate(r.Context(), r.PathValue("name")); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete template", "err", err)
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func templatePayload(t ports.PromptTemplate) TemplatePayload {
+	created := t.CreatedAt
+	return TemplatePayload{
+		Name:        t.Name,
+		Version:     t.Version,
+		Description: t.Description,
+		System:      t.System,
+		Body:        t.Body,
+		Variables:   t.Variables,
+		CreatedAt:   &created,
+	}
+}
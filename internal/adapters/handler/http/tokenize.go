@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// TokenizeRequest carries the parts of a GenerateRequest that make up its
+// prompt.
+type TokenizeRequest struct {
+	Provider  string           `json:"provider"`
+	Model     string           `json:"model"`
+	Prompt    string           `json:"prompt"`
+	Messages  []MessagePayload `json:"messages,omitempty"`
+	Tools     []ToolPayload    `json:"tools,omitempty"`
+	MaxTokens int32            `json:"max_tokens"`
+}
+
+type TokenizeResponse struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Encoding is the BPE encoding counted with, or "estimate" for models
+	// whose tokenizer is not public; Exact is set when it is known to be
+	// the model's own.
+	Encoding      string `json:"encoding"`
+	Exact         bool   `json:"exact"`
+	Tokens        int    `json:"tokens"`
+	ContextWindow int32  `json:"context_window,omitempty"`
+	// EstimatedCostUSD prices the prompt and max_tokens of output.
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+}
+
+// Tokenize counts the tokens of a prompt as the model that would serve it
+// sees them, without calling the provider.
+func (h *Handler) Tokenize(w http.ResponseWriter, r *http.Request) {
+	var req TokenizeRequest
+	if err := h.limits.decode(w, r, &req); err != nil {
+		slog.WarnContext(r.Context(), "Failed to decode tokenize request", "err", err)
+		invalidBody(w, r, err)
+		return
+	}
+	coreReq, err := GenerateRequest{
+		Model:     req.Model,
+		Prompt:    req.Prompt,
+		Messages:  req.Messages,
+		Tools:     req.Tools,
+		MaxTokens: req.MaxTokens,
+	}.coreRequest()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	est, err := h.service.Tokenize(coreReq, req.Provider)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TokenizeResponse{
+		Provider:         est.Provider,
+		Model:            est.Model,
+		Encoding:         est.Encoding,
+		Exact:            est.Exact,
+		Tokens:           est.Tokens,
+		ContextWindow:    est.ContextWindow,
+		EstimatedCostUSD: est.CostUSD,
+	})
+}
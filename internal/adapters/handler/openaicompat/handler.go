@@ -0,0 +1,656 @@
+// Package openaicompat exposes the gateway over the OpenAI chat completions
+// wire format so existing OpenAI SDKs and tools can use it as a drop-in
+// endpoint, whichever provider actually serves the request.
+package openaicompat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+const defaultMaxBodyBytes = 32 << 20
+
+type Handler struct {
+	service      *services.LLMService
+	maxBodyBytes int64
+}
+
+// NewHandler serves the gateway, refusing request bodies over maxBodyBytes.
+func NewHandler(service *services.LLMService, maxBodyBytes int64) *Handler {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	return &Handler{service: service, maxBodyBytes: maxBodyBytes}
+}
+
+type chatCompletionRequest struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	Temperature         *float32        `json:"temperature"`
+	MaxTokens           int32           `json:"max_tokens"`
+	MaxCompletionTokens int32           `json:"max_completion_tokens"`
+	Stream              bool            `json:"stream"`
+	StreamOptions       *streamOptions  `json:"stream_options"`
+	N                   int             `json:"n"`
+	Tools               []toolDef       `json:"tools"`
+	ToolChoice          toolChoice      `json:"tool_choice"`
+	ResponseFormat      *responseFormat `json:"response_format"`
+
+	// Fields the gateway cannot honour; requests setting them are refused
+	// rather than answered as if they were not there.
+	Functions    json.RawMessage `json:"functions"`
+	FunctionCall json.RawMessage `json:"function_call"`
+	Logprobs     bool            `json:"logprobs"`
+	Audio        json.RawMessage `json:"audio"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role       string     `json:"role"`
+	Content    content    `json:"content"`
+	Name       string     `json:"name"`
+	ToolCalls  []toolCall `json:"tool_calls"`
+	ToolCallID string     `json:"tool_call_id"`
+}
+
+type toolDef struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type toolCall struct {
+	// Index orders tool calls in stream deltas.
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+		// Arguments is the JSON object encoded as a string.
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// toolChoice accepts "auto", "none" and "required" as well as the object
+// form naming one function to force.
+type toolChoice string
+
+func (c *toolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*c = toolChoice(mode)
+		return nil
+	}
+	var forced struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &forced); err != nil || forced.Type != "function" || forced.Function.Name == "" {
+		return fmt.Errorf("tool_choice must be a string or a function to call")
+	}
+	*c = toolChoice(forced.Function.Name)
+	return nil
+}
+
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+// content accepts the plain string form, the array-of-parts form and null.
+// Text-only parts are concatenated into text; once an image or file is
+// present every part is kept in order.
+type content struct {
+	text  string
+	parts []ports.ContentPart
+}
+
+func (c *content) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = content{}
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = content{text: text}
+		return nil
+	}
+	var parts []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL *struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+		File *struct {
+			FileData string `json:"file_data"`
+			FileID   string `json:"file_id"`
+			Filename string `json:"filename"`
+		} `json:"file"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of parts")
+	}
+	var b strings.Builder
+	var out []ports.ContentPart
+	media := false
+	for _, part := range parts {
+		switch {
+		case part.Type == "text":
+			b.WriteString(part.Text)
+			out = append(out, ports.ContentPart{Type: ports.PartText, Text: part.Text})
+		case part.Type == "image_url" && part.ImageURL != nil:
+			image := ports.ContentPart{Type: ports.PartImage, URL: part.ImageURL.URL}
+			if strings.HasPrefix(image.URL, "data:") {
+				mimeType, data, err := decodeDataURL(image.URL)
+				if err != nil {
+					return err
+				}
+				image = ports.ContentPart{Type: ports.PartImage, Data: data, MIMEType: mimeType}
+			}
+			out = append(out, image)
+			media = true
+		case part.Type == "file" && part.File != nil:
+			if part.File.FileID != "" {
+				return fmt.Errorf("file_id is not supported, send the file as file_data")
+			}
+			mimeType, data, err := decodeDataURL(part.File.FileData)
+			if err != nil {
+				return err
+			}
+			out = append(out, ports.ContentPart{Type: ports.PartFile, Data: data, MIMEType: mimeType, Filename: part.File.Filename})
+			media = true
+		default:
+			return fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+	}
+	*c = content{text: b.String()}
+	if media {
+		c.parts = out
+	}
+	return nil
+}
+
+// decodeDataURL splits a base64 data URL into its MIME type and bytes.
+func decodeDataURL(s string) (string, []byte, error) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(s, "data:"), ",")
+	mimeType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !strings.HasPrefix(s, "data:") || !ok || !isBase64 || mimeType == "" {
+		return "", nil, fmt.Errorf("inline media must be a base64 data URL")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("inline media is not valid base64")
+	}
+	return mimeType, data, nil
+}
+
+type chatCompletionResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *usage       `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int              `json:"index"`
+	Message      *responseMessage `json:"message,omitempty"`
+	Delta        *responseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type responseMessage struct {
+	Role      string     `json:"role,omitempty"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+type usage struct {
+	PromptTokens     int32 `json:"prompt_tokens"`
+	CompletionTokens int32 `json:"completion_tokens"`
+	TotalTokens      int32 `json:"total_tokens"`
+}
+
+type modelList struct {
+	Object string        `json:"object"`
+	Data   []modelObject `json:"data"`
+}
+
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// ChatCompletions serves POST /v1/chat/completions.
+func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "Invalid request body: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+		return
+	}
+
+	providerName, model := h.splitModel(req.Model)
+	coreReq, err := req.coreRequest(user.ID, model)
+	if err != nil {
+		var unsupported *paramError
+		if errors.As(err, &unsupported) {
+			writeParamError(w, unsupported.param, unsupported.message)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	slog.InfoContext(r.Context(), "OpenAI-compatible request", "model", req.Model, "user_id", user.ID, "stream", req.Stream)
+
+	id := completionID()
+	created := time.Now().Unix()
+	if req.Stream {
+		h.stream(w, r, coreReq, providerName, req, id, created)
+		return
+	}
+
+	resp, _, err := h.service.ProcessRequest(r.Context(), coreReq, providerName)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error processing OpenAI-compatible request", "err", err)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   responseModel(resp, req.Model),
+		Choices: []chatChoice{{
+			Message:      &responseMessage{Role: ports.RoleAssistant, Content: responseContent(resp), ToolCalls: convertToolCalls(resp.ToolCalls)},
+			FinishReason: finishReason(resp),
+		}},
+		Usage: convertUsage(resp.Usage),
+	})
+}
+
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, coreReq ports.LLMRequest, providerName string, req chatCompletionRequest, id string, created int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "api_error", "streaming not supported")
+		return
+	}
+
+	started := false
+	send := func(chunk chatCompletionResponse) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		payload, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	chunk := func(delta *responseMessage, finish *string) chatCompletionResponse {
+		return chatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: []chatChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	first := true
+	resp, _, err := h.service.ProcessStream(r.Context(), coreReq, providerName, func(delta string) error {
+		msg := &responseMessage{Content: delta}
+		if first {
+			msg.Role = ports.RoleAssistant
+			first = false
+		}
+		return send(chunk(msg, nil))
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error streaming OpenAI-compatible request", "err", err)
+		if !started {
+			writeServiceError(w, err)
+			return
+		}
+		// Headers are already sent, so the error travels as a final event.
+		_, errType, message := classify(err)
+		payload, _ := json.Marshal(errorResponse{Error: errorBody{Message: message, Type: errType}})
+		fmt.Fprintf(w, "data: %s\n\n", payload)
+		flusher.Flush()
+		return
+	}
+
+	if len(resp.ToolCalls) > 0 {
+		// Tool calls arrive whole with the final response, so they are sent
+		// as one delta before the finish.
+		calls := convertToolCalls(resp.ToolCalls)
+		for i := range calls {
+			calls[i].Index = &i
+		}
+		if err := send(chunk(&responseMessage{ToolCalls: calls}, nil)); err != nil {
+			return
+		}
+	}
+	final := chunk(&responseMessage{}, finishReason(resp))
+	final.Model = responseModel(resp, req.Model)
+	if err := send(final); err != nil {
+		return
+	}
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		usageChunk := chunk(nil, nil)
+		usageChunk.Choices = []chatChoice{}
+		usageChunk.Usage = convertUsage(resp.Usage)
+		if err := send(usageChunk); err != nil {
+			return
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// Models serves GET /v1/models with every catalog model and alias whose provider is configured.
+func (h *Handler) Models(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authenticate(w, r); !ok {
+		return
+	}
+
+	catalog := h.service.Catalog()
+	list := modelList{Object: "list", Data: []modelObject{}}
+	for _, m := range catalog.Models() {
+		if h.service.HasProvider(m.Provider) {
+			list.Data = append(list.Data, modelObject{ID: m.ID, Object: "model", OwnedBy: m.Provider})
+		}
+	}
+	for alias, target := range catalog.Aliases() {
+		if m, ok := catalog.Resolve(target); ok && h.service.HasProvider(m.Provider) {
+			list.Data = append(list.Data, modelObject{ID: alias, Object: "model", OwnedBy: m.Provider})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*ports.User, bool) {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || key == "" {
+		writeError(w, http.StatusUnauthorized, "authentication_error", "Missing bearer API key")
+		return nil, false
+	}
+	user, err := h.service.AuthenticateAPIKey(r.Context(), key)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Rejected API key", "err", err)
+		writeServiceError(w, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// splitModel turns the OpenAI model field into a provider and a model.
+// "gemini/gemini-2.0-flash" pins the provider, a bare provider name uses its
+// default model, "auto" lets the router choose, and anything else is resolved
+// through the catalog.
+func (h *Handler) splitModel(model string) (string, string) {
+	if model == "" || model == "auto" {
+		return "", ""
+	}
+	if h.service.HasProvider(model) {
+		return model, ""
+	}
+	if provider, rest, ok := strings.Cut(model, "/"); ok && h.service.HasProvider(provider) {
+		return provider, rest
+	}
+	return "", model
+}
+
+// paramError names a request field the gateway cannot honour.
+type paramError struct {
+	param   string
+	message string
+}
+
+func (e *paramError) Error() string { return e.message }
+
+// coreRequest maps the OpenAI request onto the service's request.
+func (req chatCompletionRequest) coreRequest(userID, model string) (ports.LLMRequest, error) {
+	switch {
+	case req.N > 1:
+		return ports.LLMRequest{}, &paramError{param: "n", message: "n > 1 is not supported"}
+	case len(req.Functions) > 0 && string(req.Functions) != "null":
+		return ports.LLMRequest{}, &paramError{param: "functions", message: "functions is not supported, use tools"}
+	case len(req.FunctionCall) > 0 && string(req.FunctionCall) != "null":
+		return ports.LLMRequest{}, &paramError{param: "function_call", message: "function_call is not supported, use tool_choice"}
+	case req.Logprobs:
+		return ports.LLMRequest{}, &paramError{param: "logprobs", message: "logprobs is not supported"}
+	case len(req.Audio) > 0 && string(req.Audio) != "null":
+		return ports.LLMRequest{}, &paramError{param: "audio", message: "audio output is not supported"}
+	}
+
+	coreReq := ports.LLMRequest{
+		UserID:    userID,
+		Model:     model,
+		MaxTokens: req.MaxTokens,
+		// Adapters always send a temperature, so an omitted one gets
+		// OpenAI's default rather than 0.
+		Temperature: 1,
+		ToolChoice:  string(req.ToolChoice),
+	}
+	if req.MaxCompletionTokens > 0 {
+		coreReq.MaxTokens = req.MaxCompletionTokens
+	}
+	if req.Temperature != nil {
+		coreReq.Temperature = *req.Temperature
+	}
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			return coreReq, &paramError{param: "tools", message: fmt.Sprintf("tool type %q is not supported", tool.Type)}
+		}
+		coreReq.Tools = append(coreReq.Tools, ports.Tool{Name: tool.Function.Name, Description: tool.Function.Description, Parameters: tool.Function.Parameters})
+	}
+	if f := req.ResponseFormat; f != nil {
+		coreReq.ResponseFormat = &ports.ResponseFormat{Type: ports.ResponseFormatType(f.Type)}
+		if f.JSONSchema != nil {
+			coreReq.ResponseFormat.Name = f.JSONSchema.Name
+			coreReq.ResponseFormat.Schema = f.JSONSchema.Schema
+		}
+	}
+	for _, m := range req.Messages {
+		msg := ports.Message{Role: m.Role, Content: m.Content.text, Parts: m.Content.parts, Name: m.Name, ToolCallID: m.ToolCallID}
+		if msg.Role == "developer" {
+			msg.Role = ports.RoleSystem
+		}
+		for _, call := range m.ToolCalls {
+			args := json.RawMessage(call.Function.Arguments)
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			if !json.Valid(args) {
+				return coreReq, &paramError{param: "messages", message: fmt.Sprintf("arguments of tool call %q are not valid JSON", call.ID)}
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ports.ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: args})
+		}
+		coreReq.Messages = append(coreReq.Messages, msg)
+	}
+	return coreReq, nil
+}
+
+// responseContent is the answer text: the validated JSON for JSON response
+// formats, with any fencing removed, or the content as generated.
+func responseContent(resp *ports.LLMResponse) string {
+	if len(resp.JSON) > 0 {
+		return string(resp.JSON)
+	}
+	return resp.Content
+}
+
+func convertToolCalls(calls []ports.ToolCall) []toolCall {
+	var out []toolCall
+	for _, c := range calls {
+		call := toolCall{ID: c.ID, Type: "function"}
+		call.Function.Name = c.Name
+		call.Function.Arguments = string(c.Arguments)
+		if call.Function.Arguments == "" {
+			call.Function.Arguments = "{}"
+		}
+		out = append(out, call)
+	}
+	return out
+}
+
+func responseModel(resp *ports.LLMResponse, requested string) string {
+	if resp.Model != "" {
+		return resp.Model
+	}
+	return requested
+}
+
+func finishReason(resp *ports.LLMResponse) *string {
+	reason := resp.FinishReason
+	if reason == "" {
+		reason = "stop"
+	}
+	return &reason
+}
+
+func convertUsage(u *ports.UsageInfo) *usage {
+	if u == nil {
+		return nil
+	}
+	return &usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+func completionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// errorClasses maps error classes onto an HTTP status and OpenAI error
+// type, in the order they are matched.
+var errorClasses = []struct {
+	class   error
+	status  int
+	errType string
+}{
+	{ports.ErrInvalidArgument, http.StatusBadRequest, "invalid_request_error"},
+	{ports.ErrUnauthenticated, http.StatusUnauthorized, "authentication_error"},
+	{ports.ErrBudgetExceeded, http.StatusTooManyRequests, "insufficient_quota"},
+	{ports.ErrNotFound, http.StatusNotFound, "not_found_error"},
+	{ports.ErrTooLarge, http.StatusRequestEntityTooLarge, "invalid_request_error"},
+	{ports.ErrContentFiltered, http.StatusBadRequest, "invalid_request_error"},
+	{ports.ErrRateLimited, http.StatusTooManyRequests, "rate_limit_error"},
+	{ports.ErrInvalidOutput, http.StatusBadGateway, "api_error"},
+	{ports.ErrProviderUnavailable, http.StatusServiceUnavailable, "api_error"},
+	{ports.ErrNotConfigured, http.StatusServiceUnavailable, "api_error"},
+	{ports.ErrTimeout, http.StatusGatewayTimeout, "api_error"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "api_error"},
+}
+
+// classify maps service errors onto an HTTP status, an OpenAI error type
+// and a message safe to show to clients: provider failures are described
+// without the upstream response, and unclassified errors not at all.
+func classify(err error) (int, string, string) {
+	for _, c := range errorClasses {
+		if !errors.Is(err, c.class) {
+			continue
+		}
+		var provErr *ports.ProviderError
+		if errors.As(err, &provErr) {
+			return c.status, c.errType, provErr.Message()
+		}
+		return c.status, c.errType, err.Error()
+	}
+	return http.StatusInternalServerError, "api_error", "internal error"
+}
+
+// writeServiceError reports err, naming the first invalid field as param
+// for requests that failed validation.
+func writeServiceError(w http.ResponseWriter, err error) {
+	status, errType, message := classify(err)
+	body := errorBody{Message: message, Type: errType}
+	var invalid *ports.ValidationError
+	if errors.As(err, &invalid) && len(invalid.Fields) > 0 {
+		body.Param = &invalid.Fields[0].Field
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: body})
+}
+
+// writeParamError refuses a request over a field the gateway cannot honour.
+func writeParamError(w http.ResponseWriter, param, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(errorResponse{Error: errorBody{Message: message, Type: "invalid_request_error", Param: &param}})
+}
+
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: errorBody{Message: message, Type: errType}})
+}
@@ -0,0 +1,379 @@
+package openaicompat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/services"
+)
+
+// fakeProvider answers with fixed content, streaming it in two deltas, or
+// with toolCalls when set, or fails with err.
+type fakeProvider struct {
+	name      string
+	err       error
+	toolCalls []ports.ToolCall
+
+	mu   sync.Mutex
+	last ports.LLMRequest
+}
+
+func (p *fakeProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	p.mu.Lock()
+	p.last = req
+	p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(p.toolCalls) > 0 {
+		return &ports.LLMResponse{ToolCalls: p.toolCalls, Model: req.Model, FinishReason: "tool_calls"}, nil
+	}
+	return &ports.LLMResponse{Content: "hello", Model: req.Model, FinishReason: "stop",
+		Usage: &ports.UsageInfo{PromptTokens: 4, CompletionTokens: 2, TotalTokens: 6}}, nil
+}
+
+func (p *fakeProvider) GenerateStream(ctx context.Context, req ports.LLMRequest, onChunk func(delta string) error) (*ports.LLMResponse, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, delta := range []string{"hel", "lo"} {
+		if err := onChunk(delta); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+// keyRepo keeps users and gateway keys in memory.
+type keyRepo struct {
+	mu   sync.Mutex
+	keys map[string]*ports.User
+}
+
+func (r *keyRepo) LogRequest(ctx context.Context, log ports.RequestLog) error { return nil }
+func (r *keyRepo) CreateUser(ctx context.Context, name string) (*ports.User, error) {
+	return nil, errors.New("not supported")
+}
+func (r *keyRepo) GetUser(ctx context.Context, id string) (*ports.User, error) {
+	return &ports.User{ID: id, Name: id, CreatedAt: time.Now()}, nil
+}
+func (r *keyRepo) CreateAPIKey(ctx context.Context, key ports.APIKey, keyHash string) (*ports.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[keyHash] = &ports.User{ID: key.UserID, Name: key.UserID, CreatedAt: time.Now()}
+	return &key, nil
+}
+func (r *keyRepo) GetUserByAPIKey(ctx context.Context, keyHash string) (*ports.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.keys[keyHash]; ok {
+		return user, nil
+	}
+	return nil, ports.ErrNotFound
+}
+
+// newTestHandler serves gemini and openai through a catalog, and returns a
+// gateway key for user-1.
+func newTestHandler(t *testing.T, gemini, openai *fakeProvider) (*Handler, string) {
+	t.Helper()
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "gemini-2.0-flash", Provider: "gemini", ContextWindow: 1000, Capabilities: ports.ModelCapabilities{Vision: true}},
+		{ID: "gpt-4o", Provider: "openai", ContextWindow: 1000},
+	}, map[string]string{"fast": "gemini-2.0-flash"}, map[string]string{"gemini": "gemini-2.0-flash", "openai": "gpt-4o"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	svc := services.NewLLMService(&config.Config{}, &keyRepo{keys: make(map[string]*ports.User)}, nil,
+		services.WithCatalog(models), services.WithProvider("gemini", gemini), services.WithProvider("openai", openai))
+	key, _, err := svc.CreateAPIKey(context.Background(), "user-1", "test")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	return NewHandler(svc, 0), key
+}
+
+func chatRequest(key, body string) *http.Request {
+	r := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set("Authorization", "Bearer "+key)
+	}
+	return r
+}
+
+func TestChatCompletions(t *testing.T) {
+	gemini := &fakeProvider{name: "gemini"}
+	h, key := newTestHandler(t, gemini, &fakeProvider{name: "openai"})
+
+	w := httptest.NewRecorder()
+	h.ChatCompletions(w, chatRequest(key, `{
+		"model": "gemini/gemini-2.0-flash",
+		"messages": [
+			{"role": "developer", "content": "be brief"},
+			{"role": "user", "content": [{"type": "text", "text": "hi "}, {"type": "text", "text": "there"}]}
+		],
+		"max_completion_tokens": 50
+	}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp chatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+	}
+	if resp.Object != "chat.completion" || !strings.HasPrefix(resp.ID, "chatcmpl-") || resp.Model != "gemini-2.0-flash" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "hello" || *resp.Choices[0].FinishReason != "stop" {
+		t.Fatalf("unexpected choices: %+v", resp.Choices)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 6 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+
+	got := gemini.last
+	if got.UserID != "user-1" || got.MaxTokens != 50 || got.Temperature != 1 || len(got.Messages) != 2 {
+		t.Fatalf("unexpected request to the provider: %+v", got)
+	}
+	if got.Messages[0].Role != ports.RoleSystem || got.Messages[1].Content != "hi there" {
+		t.Fatalf("unexpected messages: %+v", got.Messages)
+	}
+}
+
+func TestChatCompletions_Stream(t *testing.T) {
+	h, key := newTestHandler(t, &fakeProvider{name: "gemini"}, &fakeProvider{name: "openai"})
+
+	w := httptest.NewRecorder()
+	h.ChatCompletions(w, chatRequest(key, `{
+		"model": "gemini",
+		"messages": [{"role": "user", "content": "hi"}],
+		"stream": true,
+		"stream_options": {"include_usage": true}
+	}`))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var events []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	if len(events) != 5 || events[4] != "[DONE]" {
+		t.Fatalf("expected two deltas, a final chunk, usage and [DONE], got %q", events)
+	}
+
+	chunks := make([]chatCompletionResponse, 4)
+	for i := range chunks {
+		if err := json.Unmarshal([]byte(events[i]), &chunks[i]); err != nil {
+			t.Fatalf("event %q is not a chunk: %v", events[i], err)
+		}
+		if chunks[i].Object != "chat.completion.chunk" || chunks[i].ID != chunks[0].ID {
+			t.Fatalf("unexpected chunk: %+v", chunks[i])
+		}
+	}
+	if d := chunks[0].Choices[0].Delta; d.Role != ports.RoleAssistant || d.Content != "hel" {
+		t.Fatalf("expected the first delta to carry the role, got %+v", d)
+	}
+	if d := chunks[1].Choices[0].Delta; d.Role != "" || d.Content != "lo" {
+		t.Fatalf("unexpected second delta: %+v", d)
+	}
+	if c := chunks[2].Choices[0]; c.FinishReason == nil || *c.FinishReason != "stop" {
+		t.Fatalf("expected the final chunk to finish, got %+v", c)
+	}
+	if len(chunks[3].Choices) != 0 || chunks[3].Usage == nil || chunks[3].Usage.TotalTokens != 6 {
+		t.Fatalf("unexpected usage chunk: %+v", chunks[3])
+	}
+}
+
+func TestChatCompletions_Tools(t *testing.T) {
+	gemini := &fakeProvider{name: "gemini", toolCalls: []ports.ToolCall{{ID: "call_2", Name: "weather", Arguments: json.RawMessage(`{"city":"Oslo"}`)}}}
+	h, key := newTestHandler(t, gemini, &fakeProvider{name: "openai"})
+
+	w := httptest.NewRecorder()
+	h.ChatCompletions(w, chatRequest(key, `{
+		"model": "gemini",
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "Weather here? "}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw=="}}]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "locate", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "Oslo"}
+		],
+		"tools": [
+			{"type": "function", "function": {"name": "locate", "parameters": {"type": "object"}}},
+			{"type": "function", "function": {"name": "weather", "description": "Current weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}}
+		],
+		"tool_choice": {"type": "function", "function": {"name": "weather"}}
+	}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp chatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+	}
+	choice := resp.Choices[0]
+	if *choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("expected a tool call, got %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "call_2" || call.Type != "function" || call.Function.Name != "weather" || call.Function.Arguments != `{"city":"Oslo"}` {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+
+	got := gemini.last
+	if len(got.Tools) != 2 || got.Tools[1].Name != "weather" || got.ToolChoice != "weather" {
+		t.Fatalf("expected the tools and forced choice passed on, got %+v %q", got.Tools, got.ToolChoice)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("expected 3 messages, got %+v", got.Messages)
+	}
+	if parts := got.Messages[0].Parts; len(parts) != 2 || parts[1].Type != ports.PartImage || parts[1].MIMEType != "image/png" || len(parts[1].Data) == 0 {
+		t.Fatalf("expected the inline image decoded, got %+v", parts)
+	}
+	if calls := got.Messages[1].ToolCalls; len(calls) != 1 || calls[0].Name != "locate" {
+		t.Fatalf("expected the assistant's tool call, got %+v", got.Messages[1])
+	}
+	if m := got.Messages[2]; m.Role != ports.RoleTool || m.ToolCallID != "call_1" || m.Content != "Oslo" {
+		t.Fatalf("expected the tool result, got %+v", m)
+	}
+}
+
+func TestCoreRequest_ResponseFormat(t *testing.T) {
+	var req chatCompletionRequest
+	if err := json.Unmarshal([]byte(`{
+		"messages": [{"role": "user", "content": "hi"}],
+		"response_format": {"type": "json_schema", "json_schema": {"name": "answer", "schema": {"type": "object"}}}
+	}`), &req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	coreReq, err := req.coreRequest("user-1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coreReq.Temperature != 1 {
+		t.Fatalf("expected OpenAI's default temperature, got %v", coreReq.Temperature)
+	}
+	f := coreReq.ResponseFormat
+	if f == nil || f.Type != ports.ResponseFormatJSONSchema || f.Name != "answer" || string(f.Schema) != `{"type": "object"}` {
+		t.Fatalf("unexpected response format: %+v", f)
+	}
+}
+
+func TestSplitModel(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeProvider{name: "gemini"}, &fakeProvider{name: "openai"})
+	tests := []struct {
+		model, wantProvider, wantModel string
+	}{
+		{model: "", wantProvider: "", wantModel: ""},
+		{model: "auto", wantProvider: "", wantModel: ""},
+		{model: "gemini", wantProvider: "gemini", wantModel: ""},
+		{model: "gemini/gemini-2.0-flash", wantProvider: "gemini", wantModel: "gemini-2.0-flash"},
+		{model: "openai/ft:gpt-4o/custom", wantProvider: "openai", wantModel: "ft:gpt-4o/custom"},
+		{model: "fast", wantProvider: "", wantModel: "fast"},
+		{model: "mistral/large", wantProvider: "", wantModel: "mistral/large"},
+	}
+	for _, tt := range tests {
+		provider, model := h.splitModel(tt.model)
+		if provider != tt.wantProvider || model != tt.wantModel {
+			t.Errorf("%q: expected (%q, %q), got (%q, %q)", tt.model, tt.wantProvider, tt.wantModel, provider, model)
+		}
+	}
+}
+
+func TestChatCompletions_Errors(t *testing.T) {
+	upstream := &ports.ProviderError{Provider: "openai", Class: ports.ErrRateLimited, Err: errors.New(`{"error":{"message":"secret org-123"}}`)}
+	h, key := newTestHandler(t, &fakeProvider{name: "gemini"}, &fakeProvider{name: "openai", err: upstream})
+	const hi = `"messages": [{"role": "user", "content": "hi"}]`
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		wantStatus int
+		wantType   string
+		wantParam  string
+	}{
+		{name: "missing key", body: `{` + hi + `}`, wantStatus: http.StatusUnauthorized, wantType: "authentication_error"},
+		{name: "unknown key", key: "nx-0000", body: `{` + hi + `}`, wantStatus: http.StatusUnauthorized, wantType: "authentication_error"},
+		{name: "invalid body", key: key, body: `{"messages": `, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{name: "no messages", key: key, body: `{"model": "gemini"}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{name: "several choices", key: key, body: `{"n": 2, ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error", wantParam: "n"},
+		{name: "legacy functions", key: key, body: `{"functions": [{"name": "f"}], ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error", wantParam: "functions"},
+		{name: "logprobs", key: key, body: `{"logprobs": true, ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error", wantParam: "logprobs"},
+		{name: "non-function tool", key: key, body: `{"tools": [{"type": "code_interpreter"}], ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error", wantParam: "tools"},
+		{name: "audio part", key: key, body: `{"messages": [{"role": "user", "content": [{"type": "input_audio", "input_audio": {"data": "AA=="}}]}]}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{name: "uploaded file", key: key, body: `{"messages": [{"role": "user", "content": [{"type": "file", "file": {"file_id": "file-1"}}]}]}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{name: "unknown model", key: key, body: `{"model": "gpt-9", ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{name: "invalid field", key: key, body: `{"model": "gemini", "temperature": 5, ` + hi + `}`, wantStatus: http.StatusBadRequest, wantType: "invalid_request_error", wantParam: "temperature"},
+		{name: "rate limited", key: key, body: `{"model": "openai", ` + hi + `}`, wantStatus: http.StatusTooManyRequests, wantType: "rate_limit_error"},
+		{name: "rate limited stream", key: key, body: `{"model": "openai", "stream": true, ` + hi + `}`, wantStatus: http.StatusTooManyRequests, wantType: "rate_limit_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ChatCompletions(w, chatRequest(tt.key, tt.body))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Fatalf("response leaks upstream detail: %s", w.Body.String())
+			}
+			var resp errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+			}
+			if resp.Error.Type != tt.wantType || resp.Error.Message == "" {
+				t.Fatalf("expected a %s, got %+v", tt.wantType, resp.Error)
+			}
+			var param string
+			if resp.Error.Param != nil {
+				param = *resp.Error.Param
+			}
+			if param != tt.wantParam {
+				t.Fatalf("expected param %q, got %q", tt.wantParam, param)
+			}
+		})
+	}
+}
+
+func TestModels(t *testing.T) {
+	h, key := newTestHandler(t, &fakeProvider{name: "gemini"}, &fakeProvider{name: "openai"})
+
+	w := httptest.NewRecorder()
+	h.Models(w, httptest.NewRequest("GET", "/v1/models", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without a key, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/v1/models", nil)
+	r.Header.Set("Authorization", "Bearer "+key)
+	h.Models(w, r)
+	var list modelList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body.String(), err)
+	}
+	owners := make(map[string]string)
+	for _, m := range list.Data {
+		owners[m.ID] = m.OwnedBy
+	}
+	if len(owners) != 3 || owners["gemini-2.0-flash"] != "gemini" || owners["gpt-4o"] != "openai" || owners["fast"] != "gemini" {
+		t.Fatalf("unexpected models: %+v", list.Data)
+	}
+}
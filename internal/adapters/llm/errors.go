@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// statusClass maps the HTTP status of a refused upstream call onto an error
+// class. Rejected credentials are the server's problem rather than the
+// caller's, so they count as the provider being unavailable.
+func statusClass(status int) error {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+		return ports.ErrInvalidArgument
+	case http.StatusRequestEntityTooLarge:
+		return ports.ErrTooLarge
+	case http.StatusTooManyRequests:
+		return ports.ErrRateLimited
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ports.ErrTimeout
+	}
+	return ports.ErrProviderUnavailable
+}
+
+// callError wraps a call to provider that got no usable answer. A call
+// cancelled by the caller is returned as is.
+func callError(provider string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	class := ports.ErrProviderUnavailable
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		class = ports.ErrTimeout
+	}
+	return &ports.ProviderError{Provider: provider, Class: class, Err: err}
+}
+
+// openAIError classifies an error response from the OpenAI API. The body is
+// kept for logs only.
+func openAIError(status int, body []byte, err error) error {
+	class := statusClass(status)
+	var payload struct {
+		Error struct {
+			Type string `json:"type"`
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		switch {
+		case payload.Error.Code == "insufficient_quota":
+			class = ports.ErrBudgetExceeded
+		case payload.Error.Code == "content_filter", payload.Error.Code == "content_policy_violation":
+			class = ports.ErrContentFiltered
+		}
+	}
+	return &ports.ProviderError{Provider: "openai", Class: class, Err: err}
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestOpenAIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"type":"requests","code":"rate_limit_exceeded"}}`, ports.ErrRateLimited},
+		{"quota", http.StatusTooManyRequests, `{"error":{"type":"insufficient_quota","code":"insufficient_quota"}}`, ports.ErrBudgetExceeded},
+		{"content policy", http.StatusBadRequest, `{"error":{"code":"content_policy_violation"}}`, ports.ErrContentFiltered},
+		{"bad request", http.StatusBadRequest, `{"error":{"code":"invalid_value"}}`, ports.ErrInvalidArgument},
+		{"server error", http.StatusInternalServerError, `not json`, ports.ErrProviderUnavailable},
+		{"bad credentials", http.StatusUnauthorized, `{}`, ports.ErrProviderUnavailable},
+		{"gateway timeout", http.StatusGatewayTimeout, ``, ports.ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := fmt.Errorf("openai api error (status %d): %s", tt.status, tt.body)
+			err := openAIError(tt.status, []byte(tt.body), upstream)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			var provErr *ports.ProviderError
+			if !errors.As(err, &provErr) || provErr.Provider != "openai" {
+				t.Fatalf("expected an openai provider error, got %#v", err)
+			}
+			if tt.body != "" && strings.Contains(provErr.Message(), tt.body) {
+				t.Fatalf("message leaks the upstream body: %q", provErr.Message())
+			}
+		})
+	}
+}
+
+func TestCallError(t *testing.T) {
+	if err := callError("openai", context.Canceled); err != context.Canceled {
+		t.Fatalf("expected cancellation to pass through, got %v", err)
+	}
+	if err := callError("openai", fmt.Errorf("post: %w", context.DeadlineExceeded)); !errors.Is(err, ports.ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if err := callError("gemini", errors.New("connection refused")); !errors.Is(err, ports.ErrProviderUnavailable) {
+		t.Fatalf("expected the provider to be unavailable, got %v", err)
+	}
+}
@@ -2,20 +2,35 @@ package llm
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
 	"sync"
 
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/genai"
 )
 
+// geminiMaxEmbeddingInputs is the most contents one batch embedding call accepts.
+const geminiMaxEmbeddingInputs = 100
+
 type GeminiProvider struct {
-	apiKey          string
-	model           string
-	inputCostPer1K  float64
-	outputCostPer1K float64
-	mu              sync.Mutex
-	client          *genai.Client
+	apiKey             string
+	model              string
+	inputCostPer1K     float64
+	outputCostPer1K    float64
+	embeddingModel     string
+	embeddingCostPer1K float64
+	catalog            ports.ModelCatalog
+	mu                 sync.Mutex
+	client             *genai.Client
 }
 
 type GeminiConfig struct {
@@ -23,6 +38,11 @@ type GeminiConfig struct {
 	Model           string
 	InputCostPer1K  float64
 	OutputCostPer1K float64
+	// EmbeddingModel is used when an embedding request names no model.
+	EmbeddingModel     string
+	EmbeddingCostPer1K float64
+	// Catalog prices models it knows; the flat costs above cover the rest.
+	Catalog ports.ModelCatalog
 }
 
 func NewGeminiProvider(cfg GeminiConfig) *GeminiProvider {
@@ -30,11 +50,18 @@ func NewGeminiProvider(cfg GeminiConfig) *GeminiProvider {
 	if model == "" {
 		model = "gemini-2.0-flash-exp"
 	}
+	embeddingModel := cfg.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = "text-embedding-004"
+	}
 	return &GeminiProvider{
-		apiKey:          cfg.APIKey,
-		model:           model,
-		inputCostPer1K:  cfg.InputCostPer1K,
-		outputCostPer1K: cfg.OutputCostPer1K,
+		apiKey:             cfg.APIKey,
+		model:              model,
+		inputCostPer1K:     cfg.InputCostPer1K,
+		outputCostPer1K:    cfg.OutputCostPer1K,
+		embeddingModel:     embeddingModel,
+		embeddingCostPer1K: cfg.EmbeddingCostPer1K,
+		catalog:            cfg.Catalog,
 	}
 }
 
@@ -42,40 +69,351 @@ func (p *GeminiProvider) Name() string {
 	return "Gemini"
 }
 
+// Ping retrieves the default model, which checks the key without spending
+// tokens.
+func (p *GeminiProvider) Ping(ctx context.Context) error {
+	client, err := p.clientForRequests()
+	if err != nil {
+		return err
+	}
+	if _, err := client.Models.Get(ctx, p.model, nil); err != nil {
+		return geminiError(err)
+	}
+	return nil
+}
+
 func (p *GeminiProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
 	client, err := p.clientForRequests()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gemini client: %w", err)
 	}
 
-	maxTokens := req.MaxTokens
-	result, err := client.Models.GenerateContent(
-		ctx,
-		p.model,
-		genai.Text(req.Prompt),
-		&genai.GenerateContentConfig{
-			Temperature:     &req.Temperature,
-			MaxOutputTokens: maxTokens,
-		},
-	)
+	model := p.modelFor(req)
+	contents, config := p.buildRequest(req)
+	result, err := client.Models.GenerateContent(ctx, model, contents, config)
 	if err != nil {
-		return nil, fmt.Errorf("gemini generation failed: %w", err)
+		return nil, geminiError(err)
 	}
-
-	usage := &ports.UsageInfo{}
-	if result.UsageMetadata != nil {
-		usage.PromptTokens = result.UsageMetadata.PromptTokenCount
-		usage.CompletionTokens = result.UsageMetadata.CandidatesTokenCount
-		usage.TotalTokens = result.UsageMetadata.TotalTokenCount
+	if err := blockedPrompt(result); err != nil {
+		return nil, err
 	}
-	usage.CostUSD = p.calculateCost(usage.PromptTokens, usage.CompletionTokens)
 
+	text, calls := candidateParts(result)
+	reason := finishReason(result)
+	if len(calls) > 0 {
+		reason = "tool_calls"
+	}
 	return &ports.LLMResponse{
-		Content: result.Text(),
-		Usage:   usage,
+		Content:      text,
+		ToolCalls:    calls,
+		FinishReason: reason,
+		Usage:        p.usage(model, result.UsageMetadata),
+		Model:        firstNonEmpty(result.ModelVersion, model),
 	}, nil
 }
 
+// geminiError classifies a failed Gemini call by the status of the API
+// error, if it got that far.
+func geminiError(err error) error {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return &ports.ProviderError{Provider: "gemini", Class: statusClass(apiErr.Code), Err: err}
+	}
+	return callError("gemini", err)
+}
+
+// blockedPrompt reports a prompt Gemini refused to answer: it then returns
+// no candidates, only the reason in its prompt feedback.
+func blockedPrompt(result *genai.GenerateContentResponse) error {
+	if len(result.Candidates) > 0 || result.PromptFeedback == nil || result.PromptFeedback.BlockReason == "" {
+		return nil
+	}
+	return &ports.ProviderError{Provider: "gemini", Class: ports.ErrContentFiltered, Err: fmt.Errorf("prompt blocked: %s", result.PromptFeedback.BlockReason)}
+}
+
+// GenerateStream forwards each streamed candidate's text to onChunk. Gemini
+// reports cumulative usage on the stream's chunks, so the last one wins.
+func (p *GeminiProvider) GenerateStream(ctx context.Context, req ports.LLMRequest, onChunk func(delta string) error) (*ports.LLMResponse, error) {
+	client, err := p.clientForRequests()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gemini client: %w", err)
+	}
+
+	model := p.modelFor(req)
+	contents, config := p.buildRequest(req)
+	result := &ports.LLMResponse{Model: model, Usage: p.usage(model, nil)}
+	var content strings.Builder
+	for chunk, err := range client.Models.GenerateContentStream(ctx, model, contents, config) {
+		if err != nil {
+			return nil, geminiError(err)
+		}
+		if err := blockedPrompt(chunk); err != nil {
+			return nil, err
+		}
+		if chunk.ModelVersion != "" {
+			result.Model = chunk.ModelVersion
+		}
+		if chunk.UsageMetadata != nil {
+			result.Usage = p.usage(model, chunk.UsageMetadata)
+		}
+		if reason := finishReason(chunk); reason != "" {
+			result.FinishReason = reason
+		}
+		text, calls := candidateParts(chunk)
+		result.ToolCalls = append(result.ToolCalls, calls...)
+		if text != "" {
+			content.WriteString(text)
+			if err := onChunk(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Content = content.String()
+	if len(result.ToolCalls) > 0 {
+		result.FinishReason = "tool_calls"
+	}
+	return result, nil
+}
+
+func (p *GeminiProvider) modelFor(req ports.LLMRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.model
+}
+
+// buildRequest maps the conversation onto Gemini contents. System messages
+// become the system instruction and assistant turns use the "model" role.
+// Tool calls become FunctionCall parts and tool results FunctionResponse
+// parts, with consecutive results grouped into one turn as Gemini expects.
+func (p *GeminiProvider) buildRequest(req ports.LLMRequest) ([]*genai.Content, *genai.GenerateContentConfig) {
+	config := &genai.GenerateContentConfig{
+		Temperature:     &req.Temperature,
+		MaxOutputTokens: req.MaxTokens,
+	}
+	var contents []*genai.Content
+	var system []*genai.Part
+	// Gemini needs the function name on a result; OpenAI-style clients only
+	// send the call ID, so remember which call had which name.
+	callNames := make(map[string]string)
+	lastWasTool := false
+	for _, m := range req.Conversation() {
+		isTool := m.Role == ports.RoleTool
+		switch m.Role {
+		case ports.RoleSystem:
+			system = append(system, genai.NewPartFromText(m.Content))
+		case ports.RoleAssistant:
+			var parts []*genai.Part
+			if m.Content != "" {
+				parts = append(parts, genai.NewPartFromText(m.Content))
+			}
+			for _, call := range m.ToolCalls {
+				callNames[call.ID] = call.Name
+				var args map[string]any
+				_ = json.Unmarshal(call.Arguments, &args)
+				parts = append(parts, &genai.Part{FunctionCall: &genai.FunctionCall{ID: call.ID, Name: call.Name, Args: args}})
+			}
+			contents = append(contents, genai.NewContentFromParts(parts, genai.RoleModel))
+		case ports.RoleTool:
+			name := firstNonEmpty(m.Name, callNames[m.ToolCallID])
+			part := &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: m.ToolCallID, Name: name, Response: toolResult(m.Content)}}
+			if lastWasTool {
+				last := contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+			} else {
+				contents = append(contents, genai.NewContentFromParts([]*genai.Part{part}, genai.RoleUser))
+			}
+		default:
+			if len(m.Parts) > 0 {
+				contents = append(contents, genai.NewContentFromParts(geminiParts(m.Parts), genai.RoleUser))
+				break
+			}
+			contents = append(contents, genai.NewContentFromText(m.Content, genai.RoleUser))
+		}
+		lastWasTool = isTool
+	}
+	if len(system) > 0 {
+		config.SystemInstruction = genai.NewContentFromParts(system, "")
+	}
+
+	if len(req.Tools) > 0 {
+		decls := make([]*genai.FunctionDeclaration, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			decl := &genai.FunctionDeclaration{Name: tool.Name, Description: tool.Description}
+			if len(tool.Parameters) > 0 {
+				decl.ParametersJsonSchema = tool.Parameters
+			}
+			decls = append(decls, decl)
+		}
+		config.Tools = []*genai.Tool{{FunctionDeclarations: decls}}
+	}
+	switch req.ToolChoice {
+	case "":
+	case ports.ToolChoiceAuto:
+		config.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAuto}}
+	case ports.ToolChoiceNone:
+		config.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeNone}}
+	case ports.ToolChoiceRequired:
+		config.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAny}}
+	default:
+		config.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{
+			Mode:                 genai.FunctionCallingConfigModeAny,
+			AllowedFunctionNames: []string{req.ToolChoice},
+		}}
+	}
+	if f := req.ResponseFormat; f != nil {
+		switch f.Type {
+		case ports.ResponseFormatJSONObject:
+			config.ResponseMIMEType = "application/json"
+		case ports.ResponseFormatJSONSchema:
+			config.ResponseMIMEType = "application/json"
+			// ResponseSchema only takes Gemini's OpenAPI subset; the raw JSON
+			// Schema goes through ResponseJsonSchema unchanged.
+			config.ResponseJsonSchema = f.Schema
+		}
+	}
+	return contents, config
+}
+
+// geminiParts maps multimodal parts: inline media becomes InlineData and
+// image URLs become FileData references.
+func geminiParts(parts []ports.ContentPart) []*genai.Part {
+	out := make([]*genai.Part, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.Type == ports.PartText:
+			out = append(out, genai.NewPartFromText(p.Text))
+		case len(p.Data) > 0:
+			out = append(out, genai.NewPartFromBytes(p.Data, p.MIMEType))
+		default:
+			mimeType := p.MIMEType
+			if mimeType == "" {
+				mimeType = mime.TypeByExtension(path.Ext(p.URL))
+			}
+			out = append(out, genai.NewPartFromURI(p.URL, mimeType))
+		}
+	}
+	return out
+}
+
+// toolResult wraps a tool's output for a FunctionResponse. JSON objects are
+// passed through; anything else goes under "output", the key Gemini expects.
+func toolResult(content string) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"output": content}
+}
+
+// candidateParts splits the first candidate into its text and tool calls.
+// Gemini does not always assign call IDs, so missing ones are generated.
+func candidateParts(result *genai.GenerateContentResponse) (string, []ports.ToolCall) {
+	if len(result.Candidates) == 0 || result.Candidates[0].Content == nil {
+		return "", nil
+	}
+	var text strings.Builder
+	var calls []ports.ToolCall
+	for _, part := range result.Candidates[0].Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			calls = append(calls, ports.ToolCall{
+				ID:        firstNonEmpty(part.FunctionCall.ID, newCallID()),
+				Name:      part.FunctionCall.Name,
+				Arguments: args,
+			})
+		case part.Text != "" && !part.Thought:
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), calls
+}
+
+func newCallID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+func (p *GeminiProvider) usage(model string, meta *genai.GenerateContentResponseUsageMetadata) *ports.UsageInfo {
+	usage := &ports.UsageInfo{}
+	if meta != nil {
+		usage.PromptTokens = meta.PromptTokenCount
+		usage.CompletionTokens = meta.CandidatesTokenCount
+		usage.TotalTokens = meta.TotalTokenCount
+		usage.CachedTokens = meta.CachedContentTokenCount
+	}
+	usage.CostUSD = p.calculateCost(model, *usage)
+	return usage
+}
+
+// finishReason maps Gemini's finish reason onto the OpenAI vocabulary used by ports.LLMResponse.
+func finishReason(result *genai.GenerateContentResponse) string {
+	if len(result.Candidates) == 0 {
+		return ""
+	}
+	switch result.Candidates[0].FinishReason {
+	case "":
+		return ""
+	case genai.FinishReasonStop:
+		return "stop"
+	case genai.FinishReasonMaxTokens:
+		return "length"
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent, genai.FinishReasonSPII:
+		return "content_filter"
+	default:
+		return strings.ToLower(string(result.Candidates[0].FinishReason))
+	}
+}
+
+// Embed calls EmbedContent in batches. The Gemini API does not always report
+// token usage for embeddings, so inputs without statistics are estimated at
+// four characters per token.
+func (p *GeminiProvider) Embed(ctx context.Context, req ports.EmbeddingRequest) (*ports.EmbeddingResponse, error) {
+	client, err := p.clientForRequests()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gemini client: %w", err)
+	}
+
+	model := firstNonEmpty(req.Model, p.embeddingModel)
+	config := &genai.EmbedContentConfig{}
+	if req.Dimensions > 0 {
+		config.OutputDimensionality = &req.Dimensions
+	}
+	result := &ports.EmbeddingResponse{Model: model, Vectors: make([][]float32, 0, len(req.Inputs))}
+	var tokens int32
+	for start := 0; start < len(req.Inputs); start += geminiMaxEmbeddingInputs {
+		batch := req.Inputs[start:min(start+geminiMaxEmbeddingInputs, len(req.Inputs))]
+		contents := make([]*genai.Content, len(batch))
+		for i, input := range batch {
+			contents[i] = genai.NewContentFromText(input, genai.RoleUser)
+		}
+		resp, err := client.Models.EmbedContent(ctx, model, contents, config)
+		if err != nil {
+			return nil, geminiError(err)
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("gemini returned %d embeddings for %d inputs", len(resp.Embeddings), len(batch))
+		}
+		for i, e := range resp.Embeddings {
+			result.Vectors = append(result.Vectors, e.Values)
+			if e.Statistics != nil && e.Statistics.TokenCount > 0 {
+				tokens += int32(e.Statistics.TokenCount)
+			} else {
+				tokens += int32((len(batch[i]) + 3) / 4)
+			}
+		}
+	}
+	result.Usage = &ports.UsageInfo{PromptTokens: tokens, TotalTokens: tokens}
+	result.Usage.CostUSD = embeddingCost(p.catalog, "gemini", model, p.embeddingCostPer1K, tokens)
+	return result, nil
+}
+
 func (p *GeminiProvider) clientForRequests() (*genai.Client, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -83,7 +421,8 @@ func (p *GeminiProvider) clientForRequests() (*genai.Client, error) {
 		return p.client, nil
 	}
 	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
-		APIKey: p.apiKey,
+		APIKey:     p.apiKey,
+		HTTPClient: &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
 	})
 	if err != nil {
 		return nil, err
@@ -92,7 +431,12 @@ func (p *GeminiProvider) clientForRequests() (*genai.Client, error) {
 	return client, nil
 }
 
-func (p *GeminiProvider) calculateCost(promptTokens, completionTokens int32) float64 {
-	cost := (float64(promptTokens) / 1000.0 * p.inputCostPer1K) + (float64(completionTokens) / 1000.0 * p.outputCostPer1K)
+func (p *GeminiProvider) calculateCost(model string, usage ports.UsageInfo) float64 {
+	if p.catalog != nil {
+		if info, ok := p.catalog.Lookup("gemini", model); ok {
+			return info.Cost(usage)
+		}
+	}
+	cost := (float64(usage.PromptTokens) / 1000.0 * p.inputCostPer1K) + (float64(usage.CompletionTokens) / 1000.0 * p.outputCostPer1K)
 	return cost
 }
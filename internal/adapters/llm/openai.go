@@ -1,23 +1,41 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
 )
 
+const (
+	openAIChatURL       = "https://api.openai.com/v1/chat/completions"
+	openAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+	openAIModelsURL     = "https://api.openai.com/v1/models"
+	// openAIMaxEmbeddingInputs is the most inputs /v1/embeddings accepts per call.
+	openAIMaxEmbeddingInputs = 2048
+)
+
 type OpenAIProvider struct {
-	apiKey          string
-	model           string
-	inputCostPer1K  float64
-	outputCostPer1K float64
-	client          *http.Client
+	apiKey             string
+	model              string
+	inputCostPer1K     float64
+	outputCostPer1K    float64
+	embeddingModel     string
+	embeddingCostPer1K float64
+	catalog            ports.ModelCatalog
+	client             *http.Client
 }
 
 type OpenAIConfig struct {
@@ -25,6 +43,11 @@ type OpenAIConfig struct {
 	Model           string
 	InputCostPer1K  float64
 	OutputCostPer1K float64
+	// EmbeddingModel is used when an embedding request names no model.
+	EmbeddingModel     string
+	EmbeddingCostPer1K float64
+	// Catalog prices models it knows; the flat costs above cover the rest.
+	Catalog ports.ModelCatalog
 }
 
 func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
@@ -32,13 +55,21 @@ func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
 	if model == "" {
 		model = "gpt-3.5-turbo"
 	}
+	embeddingModel := cfg.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = "text-embedding-3-small"
+	}
 	return &OpenAIProvider{
-		apiKey:          cfg.APIKey,
-		model:           model,
-		inputCostPer1K:  cfg.InputCostPer1K,
-		outputCostPer1K: cfg.OutputCostPer1K,
+		apiKey:             cfg.APIKey,
+		model:              model,
+		inputCostPer1K:     cfg.InputCostPer1K,
+		outputCostPer1K:    cfg.OutputCostPer1K,
+		embeddingModel:     embeddingModel,
+		embeddingCostPer1K: cfg.EmbeddingCostPer1K,
+		catalog:            cfg.Catalog,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 }
@@ -47,49 +78,356 @@ func (p *OpenAIProvider) Name() string {
 	return "OpenAI"
 }
 
+// Ping retrieves the default model, which checks the key without spending
+// tokens.
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", openAIModelsURL+"/"+url.PathEscape(p.model), nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return callError("openai", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return openAIError(resp.StatusCode, body, fmt.Errorf("openai api error: %s", resp.Status))
+	}
+	return nil
+}
+
 type openAIRequest struct {
-	Model       string  `json:"model"`
-	Messages    []msg   `json:"messages"`
-	Temperature float32 `json:"temperature"`
-	MaxTokens   int32   `json:"max_tokens"`
+	Model          string                `json:"model"`
+	Messages       []msg                 `json:"messages"`
+	Temperature    float32               `json:"temperature"`
+	MaxTokens      int32                 `json:"max_tokens,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	ToolChoice     any                   `json:"tool_choice,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	// Index identifies the call a streamed fragment belongs to; it is never
+	// set on requests.
+	Index    int    `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type msg struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or a list of openAIContentPart for multimodal messages.
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+	File     *openAIFile     `json:"file,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
 }
 
 type openAIResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
 	Usage openAIUsage `json:"usage"`
 }
 
+// openAIChunk is one server-sent event of a streamed completion.
+type openAIChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
 type openAIUsage struct {
-	PromptTokens     int32 `json:"prompt_tokens"`
-	CompletionTokens int32 `json:"completion_tokens"`
-	TotalTokens      int32 `json:"total_tokens"`
+	PromptTokens        int32 `json:"prompt_tokens"`
+	CompletionTokens    int32 `json:"completion_tokens"`
+	TotalTokens         int32 `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int32 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
 }
 
 func (p *OpenAIProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
-	requestBody := openAIRequest{
-		Model:       p.model,
+	model := p.modelFor(req)
+	resp, err := p.send(ctx, p.client, openAIChatURL, p.buildRequest(model, req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var openAIResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
+		return nil, err
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, &ports.ProviderError{Provider: "openai", Class: ports.ErrInvalidOutput, Err: errors.New("no choices returned")}
+	}
+
+	return &ports.LLMResponse{
+		Content:      openAIResp.Choices[0].Message.Content,
+		ToolCalls:    toolCalls(openAIResp.Choices[0].Message.ToolCalls),
+		FinishReason: openAIResp.Choices[0].FinishReason,
+		Usage:        p.usage(model, openAIResp.Usage),
+		Model:        firstNonEmpty(openAIResp.Model, model),
+	}, nil
+}
+
+// GenerateStream requests a streamed completion and forwards each content
+// delta to onChunk as the server-sent events arrive.
+func (p *OpenAIProvider) GenerateStream(ctx context.Context, req ports.LLMRequest, onChunk func(delta string) error) (*ports.LLMResponse, error) {
+	model := p.modelFor(req)
+	body := p.buildRequest(model, req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	// The regular client's timeout would cut long streams short; the
+	// request context bounds the call instead.
+	resp, err := p.send(ctx, &http.Client{Transport: p.client.Transport}, openAIChatURL, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &ports.LLMResponse{Model: model, Usage: &ports.UsageInfo{}}
+	var content strings.Builder
+	// Tool calls arrive as fragments keyed by index: the first carries the
+	// ID and name, the rest append to the arguments.
+	var calls []openAIToolCall
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode openai stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = p.usage(model, *chunk.Usage)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+			for _, fragment := range choice.Delta.ToolCalls {
+				for len(calls) <= fragment.Index {
+					calls = append(calls, openAIToolCall{})
+				}
+				call := &calls[fragment.Index]
+				if fragment.ID != "" {
+					call.ID = fragment.ID
+				}
+				if fragment.Function.Name != "" {
+					call.Function.Name = fragment.Function.Name
+				}
+				call.Function.Arguments += fragment.Function.Arguments
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onChunk(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, callError("openai", fmt.Errorf("stream interrupted: %w", err))
+	}
+
+	result.Content = content.String()
+	result.ToolCalls = toolCalls(calls)
+	return result, nil
+}
+
+func (p *OpenAIProvider) modelFor(req ports.LLMRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.model
+}
+
+func (p *OpenAIProvider) buildRequest(model string, req ports.LLMRequest) openAIRequest {
+	conversation := req.Conversation()
+	messages := make([]msg, 0, len(conversation))
+	for _, m := range conversation {
+		out := msg{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if len(m.Parts) > 0 {
+			out.Content = contentParts(m.Parts)
+		}
+		for _, call := range m.ToolCalls {
+			c := openAIToolCall{ID: call.ID, Type: "function"}
+			c.Function.Name = call.Name
+			c.Function.Arguments = string(toolArguments(string(call.Arguments)))
+			out.ToolCalls = append(out.ToolCalls, c)
+		}
+		messages = append(messages, out)
+	}
+	body := openAIRequest{
+		Model:       model,
 		Temperature: req.Temperature,
 		MaxTokens:   req.MaxTokens,
-		Messages: []msg{
-			{Role: "user", Content: req.Prompt},
-		},
+		Messages:    messages,
+	}
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	switch req.ToolChoice {
+	case "":
+	case ports.ToolChoiceAuto, ports.ToolChoiceNone, ports.ToolChoiceRequired:
+		body.ToolChoice = req.ToolChoice
+	default:
+		body.ToolChoice = map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": req.ToolChoice},
+		}
+	}
+	if f := req.ResponseFormat; f != nil && f.Type != "" {
+		body.ResponseFormat = &openAIResponseFormat{Type: string(f.Type)}
+		if f.Type == ports.ResponseFormatJSONSchema {
+			body.ResponseFormat.JSONSchema = &openAIJSONSchema{
+				Name:   firstNonEmpty(f.Name, "response"),
+				Schema: f.Schema,
+			}
+		}
+	}
+	return body
+}
+
+// contentParts maps multimodal parts onto OpenAI's text, image_url and file
+// parts. Inline media is sent as a base64 data URL.
+func contentParts(parts []ports.ContentPart) []openAIContentPart {
+	out := make([]openAIContentPart, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case ports.PartImage:
+			url := p.URL
+			if len(p.Data) > 0 {
+				url = dataURL(p.MIMEType, p.Data)
+			}
+			out = append(out, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+		case ports.PartFile:
+			out = append(out, openAIContentPart{Type: "file", File: &openAIFile{Filename: p.Filename, FileData: dataURL(p.MIMEType, p.Data)}})
+		default:
+			out = append(out, openAIContentPart{Type: "text", Text: p.Text})
+		}
 	}
+	return out
+}
+
+func dataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
 
+func toolCalls(calls []openAIToolCall) []ports.ToolCall {
+	var out []ports.ToolCall
+	for _, c := range calls {
+		out = append(out, ports.ToolCall{
+			ID:        c.ID,
+			Name:      c.Function.Name,
+			Arguments: toolArguments(c.Function.Arguments),
+		})
+	}
+	return out
+}
+
+// toolArguments keeps the model's argument string as JSON when it is valid
+// and wraps it as a JSON string otherwise, so callers always get valid JSON.
+func toolArguments(args string) json.RawMessage {
+	if args == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	quoted, _ := json.Marshal(args)
+	return quoted
+}
+
+// send posts a request to an OpenAI endpoint and returns the response once
+// the status has been checked. The caller must close the body.
+func (p *OpenAIProvider) send(ctx context.Context, client *http.Client, url string, requestBody any) (*http.Response, error) {
 	body, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
@@ -97,40 +435,108 @@ func (p *OpenAIProvider) Generate(ctx context.Context, req ports.LLMRequest) (*p
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
 
-	resp, err := p.client.Do(httpReq)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, callError("openai", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("openai api error: %s - %s", resp.Status, string(bodyBytes))
+		return nil, openAIError(resp.StatusCode, bodyBytes, fmt.Errorf("openai api error: %s - %s", resp.Status, string(bodyBytes)))
 	}
+	return resp, nil
+}
 
-	var openAIResp openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return nil, err
+func (p *OpenAIProvider) usage(model string, u openAIUsage) *ports.UsageInfo {
+	usage := &ports.UsageInfo{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CachedTokens:     u.PromptTokensDetails.CachedTokens,
 	}
+	usage.CostUSD = p.calculateCost(model, *usage)
+	return usage
+}
 
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned from openai")
+func (p *OpenAIProvider) calculateCost(model string, usage ports.UsageInfo) float64 {
+	if p.catalog != nil {
+		if info, ok := p.catalog.Lookup("openai", model); ok {
+			return info.Cost(usage)
+		}
 	}
+	cost := (float64(usage.PromptTokens) / 1000.0 * p.inputCostPer1K) + (float64(usage.CompletionTokens) / 1000.0 * p.outputCostPer1K)
+	return cost
+}
 
-	usage := &ports.UsageInfo{
-		PromptTokens:     openAIResp.Usage.PromptTokens,
-		CompletionTokens: openAIResp.Usage.CompletionTokens,
-		TotalTokens:      openAIResp.Usage.TotalTokens,
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int32    `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// Embed calls /v1/embeddings, batching the inputs to stay within the API's
+// per-request limit. Usage is summed across batches.
+func (p *OpenAIProvider) Embed(ctx context.Context, req ports.EmbeddingRequest) (*ports.EmbeddingResponse, error) {
+	model := firstNonEmpty(req.Model, p.embeddingModel)
+	result := &ports.EmbeddingResponse{Model: model, Vectors: make([][]float32, 0, len(req.Inputs))}
+	var tokens int32
+	for start := 0; start < len(req.Inputs); start += openAIMaxEmbeddingInputs {
+		batch := req.Inputs[start:min(start+openAIMaxEmbeddingInputs, len(req.Inputs))]
+		resp, err := p.send(ctx, p.client, openAIEmbeddingsURL, openAIEmbeddingRequest{Model: model, Input: batch, Dimensions: req.Dimensions})
+		if err != nil {
+			return nil, err
+		}
+		var body openAIEmbeddingResponse
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(body.Data) != len(batch) {
+			return nil, fmt.Errorf("openai returned %d embeddings for %d inputs", len(body.Data), len(batch))
+		}
+		vectors := make([][]float32, len(batch))
+		for _, d := range body.Data {
+			if d.Index < 0 || d.Index >= len(batch) {
+				return nil, fmt.Errorf("openai returned embedding index %d out of range", d.Index)
+			}
+			vectors[d.Index] = d.Embedding
+		}
+		result.Vectors = append(result.Vectors, vectors...)
+		result.Model = firstNonEmpty(body.Model, model)
+		tokens += body.Usage.PromptTokens
 	}
-	usage.CostUSD = p.calculateCost(usage.PromptTokens, usage.CompletionTokens)
+	result.Usage = &ports.UsageInfo{PromptTokens: tokens, TotalTokens: tokens}
+	result.Usage.CostUSD = embeddingCost(p.catalog, "openai", model, p.embeddingCostPer1K, tokens)
+	return result, nil
+}
 
-	return &ports.LLMResponse{
-		Content: openAIResp.Choices[0].Message.Content,
-		Usage:   usage,
-	}, nil
+// embeddingCost prices embedding tokens at the catalog's input rate when the
+// model is listed there and at the flat embedding rate otherwise.
+func embeddingCost(catalog ports.ModelCatalog, provider, model string, costPer1K float64, tokens int32) float64 {
+	if catalog != nil {
+		if info, ok := catalog.Lookup(provider, model); ok {
+			return info.Cost(ports.UsageInfo{PromptTokens: tokens})
+		}
+	}
+	return float64(tokens) / 1000.0 * costPer1K
 }
 
-func (p *OpenAIProvider) calculateCost(promptTokens, completionTokens int32) float64 {
-	cost := (float64(promptTokens) / 1000.0 * p.inputCostPer1K) + (float64(completionTokens) / 1000.0 * p.outputCostPer1K)
-	return cost
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
@@ -0,0 +1,210 @@
+// Package metrics exposes the service's measurements to Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const namespace = "nexus"
+
+// requestLabels are shared by every per-request series.
+var requestLabels = []string{"provider", "model", "status", "cache_hit"}
+
+// latencyBuckets span cache hits through long generations, in seconds.
+var latencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+// Prometheus implements ports.Metrics and instruments HTTP handlers. It
+// owns its registry, so several can coexist in tests.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	latency          *prometheus.HistogramVec
+	promptTokens     *prometheus.CounterVec
+	completionTokens *prometheus.CounterVec
+	cost             *prometheus.CounterVec
+	inFlight         prometheus.Gauge
+	cacheHits        prometheus.Counter
+	cacheMisses      prometheus.Counter
+	rateLimited      *prometheus.CounterVec
+	pendingLogs      prometheus.Gauge
+
+	httpRequests *prometheus.CounterVec
+	httpLatency  *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+}
+
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "requests_total",
+			Help: "Generation requests by outcome.",
+		}, requestLabels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "request_duration_seconds",
+			Help:    "Time to answer a generation request, including cache lookups and failover.",
+			Buckets: latencyBuckets,
+		}, requestLabels),
+		promptTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "prompt_tokens_total",
+			Help: "Prompt tokens billed by providers.",
+		}, requestLabels),
+		completionTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "completion_tokens_total",
+			Help: "Completion tokens billed by providers.",
+		}, requestLabels),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cost_usd_total",
+			Help: "Estimated provider spend in US dollars.",
+		}, requestLabels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "requests_in_flight",
+			Help: "Generation requests in progress.",
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_hits_total",
+			Help: "Response cache lookups that found an answer.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_misses_total",
+			Help: "Response cache lookups that found nothing.",
+		}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rate_limited_total",
+			Help: "Provider calls refused because of a rate limit or quota.",
+		}, []string{"provider"}),
+		pendingLogs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "request_log_queue_depth",
+			Help: "Request logs waiting to be written to the database.",
+		}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "http_requests_total",
+			Help: "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_duration_seconds",
+			Help:    "Time to serve an HTTP request.",
+			Buckets: latencyBuckets,
+		}, []string{"route", "method"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "http_requests_in_flight",
+			Help: "HTTP requests being served.",
+		}),
+	}
+	p.registry.MustRegister(
+		p.requests, p.latency, p.promptTokens, p.completionTokens, p.cost, p.inFlight,
+		p.cacheHits, p.cacheMisses, p.rateLimited, p.pendingLogs,
+		p.httpRequests, p.httpLatency, p.httpInFlight,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return p
+}
+
+func (p *Prometheus) ObserveRequest(m ports.RequestMetric) {
+	status := "success"
+	if !m.Success {
+		status = "error"
+	}
+	labels := prometheus.Labels{
+		"provider":  m.Provider,
+		"model":     m.Model,
+		"status":    status,
+		"cache_hit": strconv.FormatBool(m.CacheHit),
+	}
+	p.requests.With(labels).Inc()
+	p.latency.With(labels).Observe(m.Duration.Seconds())
+	if m.Usage != nil {
+		p.promptTokens.With(labels).Add(float64(m.Usage.PromptTokens))
+		p.completionTokens.With(labels).Add(float64(m.Usage.CompletionTokens))
+		p.cost.With(labels).Add(m.Usage.CostUSD)
+	}
+}
+
+func (p *Prometheus) AddInFlight(delta int) {
+	p.inFlight.Add(float64(delta))
+}
+
+func (p *Prometheus) ObserveCacheLookup(hit bool) {
+	if hit {
+		p.cacheHits.Inc()
+	} else {
+		p.cacheMisses.Inc()
+	}
+}
+
+func (p *Prometheus) ObserveRateLimited(provider string) {
+	p.rateLimited.WithLabelValues(provider).Inc()
+}
+
+func (p *Prometheus) AddPendingLogs(delta int) {
+	p.pendingLogs.Add(float64(delta))
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+// InstrumentHTTP counts and times the requests next serves. next is
+// expected to be a ServeMux: requests are labeled by the pattern that
+// matched them, never by raw path, to keep the number of series bounded.
+func (p *Prometheus) InstrumentHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		p.httpInFlight.Inc()
+		defer p.httpInFlight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		p.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		p.httpLatency.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder remembers the status code written through it. It keeps
+// streaming handlers working by passing Flush on.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	r.wroteHeader = true
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const jobColumns = `id, user_id, COALESCE(provider, ''), request, status, attempts, result,
+	COALESCE(provider_used, ''), COALESCE(error, ''), COALESCE(error_code, ''), COALESCE(webhook_url, ''),
+	COALESCE(webhook_status, ''), webhook_attempts, COALESCE(webhook_error, ''),
+	created_at, started_at, finished_at`
+
+func (r *PostgresRepository) EnqueueJob(ctx context.Context, job ports.Job) (*ports.Job, error) {
+	request, err := json.Marshal(job.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO jobs (user_id, provider, request, status, webhook_url)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at
+	`, job.UserID, job.Provider, request, job.Status, job.WebhookURL)
+	if err := row.Scan(&job.ID, &job.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *PostgresRepository) GetJob(ctx context.Context, id string) (*ports.Job, error) {
+	job, err := scanJob(r.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if err != nil {
+		return nil, notFound(err, "job "+id)
+	}
+	return job, nil
+}
+
+// ClaimJob locks the oldest runnable row with SKIP LOCKED, so concurrent
+// workers in any replica each get a different job without waiting on one
+// another.
+func (r *PostgresRepository) ClaimJob(ctx context.Context, lease time.Duration) (*ports.Job, error) {
+	job, err := scanJob(r.pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, started_at = now(),
+			lease_expires_at = now() + $1 * interval '1 millisecond'
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE (status = 'queued' AND (run_after IS NULL OR run_after <= now()))
+				OR (status = 'running' AND lease_expires_at < now())
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns,
+		lease.Milliseconds()))
+	if err != nil {
+		return nil, notFound(err, "runnable job")
+	}
+	return job, nil
+}
+
+// FinishJob only updates the row while the caller's claim, identified by
+// the attempt count, is still the current one.
+func (r *PostgresRepository) FinishJob(ctx context.Context, job ports.Job) error {
+	var result []byte
+	if job.Result != nil {
+		var err error
+		if result, err = json.Marshal(job.Result); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = $3, result = $4, provider_used = NULLIF($5, ''), error = NULLIF($6, ''),
+			error_code = NULLIF($7, ''),
+			finished_at = now(), lease_expires_at = NULL,
+			webhook_status = CASE WHEN webhook_url IS NOT NULL THEN 'pending' END,
+			webhook_next_attempt_at = CASE WHEN webhook_url IS NOT NULL THEN now() END
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+	`, job.ID, job.Attempts, job.Status, result, job.ProviderUsed, job.Error, job.ErrorCode)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("job %s attempt %d: %w", job.ID, job.Attempts, ports.ErrNotFound)
+	}
+	return nil
+}
+
+// RetryJob, like FinishJob, only updates the row while the caller's claim is
+// the current one.
+func (r *PostgresRepository) RetryJob(ctx context.Context, job ports.Job, retryIn time.Duration) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = 'queued', provider_used = NULLIF($3, ''), error = NULLIF($4, ''),
+			error_code = NULLIF($5, ''), lease_expires_at = NULL,
+			run_after = now() + $6 * interval '1 millisecond'
+		WHERE id = $1 AND attempts = $2 AND status = 'running'
+	`, job.ID, job.Attempts, job.ProviderUsed, job.Error, job.ErrorCode, retryIn.Milliseconds())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("job %s attempt %d: %w", job.ID, job.Attempts, ports.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) PruneJobs(ctx context.Context, olderThan time.Duration) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM jobs
+		WHERE finished_at < now() - $1 * interval '1 millisecond'
+			AND webhook_status IS DISTINCT FROM 'pending'
+	`, olderThan.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhook pushes the next attempt time out by lease while delivering,
+// so a crashed sender's webhook becomes due again afterwards.
+func (r *PostgresRepository) ClaimWebhook(ctx context.Context, lease time.Duration) (*ports.Job, error) {
+	job, err := scanJob(r.pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET webhook_attempts = webhook_attempts + 1,
+			webhook_next_attempt_at = now() + $1 * interval '1 millisecond'
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE webhook_status = 'pending' AND webhook_next_attempt_at <= now()
+			ORDER BY webhook_next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns,
+		lease.Milliseconds()))
+	if err != nil {
+		return nil, notFound(err, "due webhook")
+	}
+	return job, nil
+}
+
+func (r *PostgresRepository) RecordWebhook(ctx context.Context, id string, status ports.WebhookStatus, errMsg string, retryIn time.Duration) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE jobs
+		SET webhook_status = $2, webhook_error = NULLIF($3, ''),
+			webhook_next_attempt_at = CASE WHEN $2 = 'pending' THEN now() + $4 * interval '1 millisecond' END
+		WHERE id = $1
+	`, id, status, errMsg, retryIn.Milliseconds())
+	return err
+}
+
+func scanJob(row pgx.Row) (*ports.Job, error) {
+	var job ports.Job
+	var request, result []byte
+	var webhookStatus string
+	err := row.Scan(&job.ID, &job.UserID, &job.Provider, &request, &job.Status, &job.Attempts, &result,
+		&job.ProviderUsed, &job.Error, &job.ErrorCode, &job.WebhookURL,
+		&webhookStatus, &job.WebhookAttempts, &job.WebhookError,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.WebhookStatus = ports.WebhookStatus(webhookStatus)
+	if err := json.Unmarshal(request, &job.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode request of job %s: %w", job.ID, err)
+	}
+	if len(result) > 0 {
+		job.Result = &ports.LLMResponse{}
+		if err := json.Unmarshal(result, job.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode result of job %s: %w", job.ID, err)
+		}
+	}
+	return &job, nil
+}
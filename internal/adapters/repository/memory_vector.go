@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// MemoryVectorIndex is a VectorIndex that keeps everything in process and
+// searches by brute force. It suits tests and small deployments; contents
+// are lost on restart.
+type MemoryVectorIndex struct {
+	mu          sync.RWMutex
+	collections map[string]ports.Collection
+	chunks      map[string][]ports.Chunk
+}
+
+func NewMemoryVectorIndex() *MemoryVectorIndex {
+	return &MemoryVectorIndex{
+		collections: make(map[string]ports.Collection),
+		chunks:      make(map[string][]ports.Chunk),
+	}
+}
+
+func (m *MemoryVectorIndex) CreateCollection(ctx context.Context, c ports.Collection) (*ports.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.ID = newID()
+	c.CreatedAt = time.Now()
+	m.collections[c.ID] = c
+	return &c, nil
+}
+
+func (m *MemoryVectorIndex) GetCollection(ctx context.Context, id string) (*ports.Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.collections[id]
+	if !ok {
+		return nil, fmt.Errorf("collection %s: %w", id, ports.ErrNotFound)
+	}
+	return &c, nil
+}
+
+func (m *MemoryVectorIndex) AddDocument(ctx context.Context, doc ports.Document, chunks []ports.Chunk) (*ports.Document, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.collections[doc.CollectionID]; !ok {
+		return nil, fmt.Errorf("collection %s: %w", doc.CollectionID, ports.ErrNotFound)
+	}
+	doc.ID = newID()
+	doc.Chunks = len(chunks)
+	doc.CreatedAt = time.Now()
+	for i, chunk := range chunks {
+		chunk.ID = newID()
+		chunk.CollectionID = doc.CollectionID
+		chunk.DocumentID = doc.ID
+		chunk.DocumentTitle = doc.Title
+		chunk.Index = i
+		m.chunks[doc.CollectionID] = append(m.chunks[doc.CollectionID], chunk)
+	}
+	return &doc, nil
+}
+
+func (m *MemoryVectorIndex) Search(ctx context.Context, collectionID string, query []float32, k int) ([]ports.ScoredChunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.collections[collectionID]; !ok {
+		return nil, fmt.Errorf("collection %s: %w", collectionID, ports.ErrNotFound)
+	}
+	var hits []ports.ScoredChunk
+	for _, chunk := range m.chunks[collectionID] {
+		hits = append(hits, ports.ScoredChunk{Chunk: chunk, Score: cosine(query, chunk.Embedding)})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// cosine is the cosine similarity of a and b, or 0 when their sizes differ
+// or either is all zeros.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
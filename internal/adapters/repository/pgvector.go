@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// PgVectorIndex stores collections in Postgres and searches chunk
+// embeddings with the pgvector extension's cosine distance.
+type PgVectorIndex struct {
+	pool *pgxpool.Pool
+}
+
+// VectorIndex creates the knowledge tables on the repository's database and
+// returns an index backed by them. The database must have the pgvector
+// extension available (the pgvector/pgvector images ship it).
+func (r *PostgresRepository) VectorIndex(ctx context.Context) (*PgVectorIndex, error) {
+	if _, err := r.pool.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		return nil, fmt.Errorf("failed to ensure pgvector extension: %v", err)
+	}
+
+	// Vectors are left without a fixed dimension so collections may use
+	// different embedding models; searches are exact scans per collection.
+	_, err := r.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS collections (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id),
+			name TEXT NOT NULL,
+			embedding_provider TEXT NOT NULL,
+			embedding_model TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS documents (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+			title TEXT,
+			content_type TEXT,
+			chunks INT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS chunks (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+			document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+			chunk_index INT NOT NULL,
+			content TEXT NOT NULL,
+			embedding vector NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS chunks_collection_id_idx ON chunks (collection_id);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create knowledge tables: %v", err)
+	}
+	return &PgVectorIndex{pool: r.pool}, nil
+}
+
+func (p *PgVectorIndex) CreateCollection(ctx context.Context, c ports.Collection) (*ports.Collection, error) {
+	row := p.pool.QueryRow(ctx, `
+		INSERT INTO collections (user_id, name, embedding_provider, embedding_model)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, c.UserID, c.Name, c.EmbeddingProvider, c.EmbeddingModel)
+	if err := row.Scan(&c.ID, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (p *PgVectorIndex) GetCollection(ctx context.Context, id string) (*ports.Collection, error) {
+	row := p.pool.QueryRow(ctx, `
+		SELECT id, user_id, name, embedding_provider, COALESCE(embedding_model, ''), created_at
+		FROM collections WHERE id = $1
+	`, id)
+	var c ports.Collection
+	if err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.EmbeddingProvider, &c.EmbeddingModel, &c.CreatedAt); err != nil {
+		return nil, notFound(err, "collection "+id)
+	}
+	return &c, nil
+}
+
+// AddDocument stores the document and its chunks in one transaction, so a
+// failed upload leaves nothing half-indexed.
+func (p *PgVectorIndex) AddDocument(ctx context.Context, doc ports.Document, chunks []ports.Chunk) (*ports.Document, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	doc.Chunks = len(chunks)
+	row := tx.QueryRow(ctx, `
+		INSERT INTO documents (collection_id, title, content_type, chunks)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, doc.CollectionID, doc.Title, doc.ContentType, doc.Chunks)
+	if err := row.Scan(&doc.ID, &doc.CreatedAt); err != nil {
+		return nil, notFound(err, "collection "+doc.CollectionID)
+	}
+
+	batch := &pgx.Batch{}
+	for i, chunk := range chunks {
+		batch.Queue(`
+			INSERT INTO chunks (collection_id, document_id, chunk_index, content, embedding)
+			VALUES ($1, $2, $3, $4, $5::vector)
+		`, doc.CollectionID, doc.ID, i, chunk.Text, vectorLiteral(chunk.Embedding))
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("failed to store chunks: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (p *PgVectorIndex) Search(ctx context.Context, collectionID string, query []float32, k int) ([]ports.ScoredChunk, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT c.id, c.document_id, COALESCE(d.title, ''), c.chunk_index, c.content,
+			1 - (c.embedding <=> $2::vector) AS score
+		FROM chunks c JOIN documents d ON d.id = c.document_id
+		WHERE c.collection_id = $1
+		ORDER BY c.embedding <=> $2::vector
+		LIMIT $3
+	`, collectionID, vectorLiteral(query), k)
+	if err != nil {
+		return nil, notFound(err, "collection "+collectionID)
+	}
+	defer rows.Close()
+
+	var hits []ports.ScoredChunk
+	for rows.Next() {
+		hit := ports.ScoredChunk{Chunk: ports.Chunk{CollectionID: collectionID}}
+		if err := rows.Scan(&hit.ID, &hit.DocumentID, &hit.DocumentTitle, &hit.Index, &hit.Text, &hit.Score); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// vectorLiteral formats v in pgvector's text form, e.g. [0.1,0.2].
+func vectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, x := range v {
+		parts[i] = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// notFound maps missing rows, malformed UUIDs and dangling references onto
+// ports.ErrNotFound.
+func notFound(err error, what string) error {
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) ||
+		(errors.As(err, &pgErr) && (pgErr.Code == "22P02" || pgErr.Code == "23503")) {
+		return fmt.Errorf("%s: %w", what, ports.ErrNotFound)
+	}
+	return err
+}
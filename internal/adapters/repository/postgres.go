@@ -3,31 +3,34 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
 )
 
 type PostgresRepository struct {
-	conn *pgx.Conn
+	pool *pgxpool.Pool
 }
 
 func NewPostgresRepository(connString string) (*PostgresRepository, error) {
-	conn, err := pgx.Connect(context.Background(), connString)
+	// A pool rather than a single connection: handlers and job workers
+	// query concurrently.
+	pool, err := pgxpool.New(context.Background(), connString)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %v", err)
 	}
 
 	ctx := context.Background()
 	// Ensure pgcrypto extension exists before using gen_random_uuid
-	if _, err = conn.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS "pgcrypto"`); err != nil {
+	if _, err = pool.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS "pgcrypto"`); err != nil {
 		return nil, fmt.Errorf("failed to ensure pgcrypto extension: %v", err)
 	}
 
 	// Create users table
-	_, err = conn.Exec(ctx, `
+	_, err = pool.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS users (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			name TEXT NOT NULL,
@@ -39,7 +42,7 @@ func NewPostgresRepository(connString string) (*PostgresRepository, error) {
 	}
 
 	// Create request_logs table with optional user reference
-	_, err = conn.Exec(ctx, `
+	_, err = pool.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS request_logs (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			user_id UUID NULL REFERENCES users(id),
@@ -58,7 +61,109 @@ func NewPostgresRepository(connString string) (*PostgresRepository, error) {
 		return nil, fmt.Errorf("failed to create table: %v", err)
 	}
 
-	return &PostgresRepository{conn: conn}, nil
+	// Gateway API keys; only the SHA-256 of each key is stored
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id),
+			name TEXT,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api_keys table: %v", err)
+	}
+
+	// Prompt templates; each (name, version) row is written once and never updated
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS prompt_templates (
+			name TEXT NOT NULL,
+			version INT NOT NULL,
+			description TEXT,
+			system TEXT,
+			body TEXT NOT NULL,
+			variables JSONB,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (name, version)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt_templates table: %v", err)
+	}
+
+	// Asynchronous generation jobs, shared by the workers of every replica
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS jobs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id),
+			provider TEXT,
+			request JSONB NOT NULL,
+			status TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			lease_expires_at TIMESTAMP,
+			result JSONB,
+			provider_used TEXT,
+			error TEXT,
+			webhook_url TEXT,
+			webhook_status TEXT,
+			webhook_attempts INT NOT NULL DEFAULT 0,
+			webhook_next_attempt_at TIMESTAMP,
+			webhook_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS jobs_runnable_idx ON jobs (created_at) WHERE status IN ('queued', 'running');
+		CREATE INDEX IF NOT EXISTS jobs_webhook_due_idx ON jobs (webhook_next_attempt_at) WHERE webhook_status = 'pending';
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jobs table: %v", err)
+	}
+
+	// Columns added after the initial schema
+	_, err = pool.Exec(ctx, `
+		ALTER TABLE request_logs
+			ADD COLUMN IF NOT EXISTS routing_strategy TEXT,
+			ADD COLUMN IF NOT EXISTS routing_reason TEXT,
+			ADD COLUMN IF NOT EXISTS hedge_role TEXT,
+			ADD COLUMN IF NOT EXISTS hedge_winner BOOLEAN,
+			ADD COLUMN IF NOT EXISTS error TEXT,
+			ADD COLUMN IF NOT EXISTS model TEXT,
+			ADD COLUMN IF NOT EXISTS trace_id TEXT,
+			ADD COLUMN IF NOT EXISTS trace_step INT,
+			ADD COLUMN IF NOT EXISTS attachments JSONB,
+			ADD COLUMN IF NOT EXISTS template_name TEXT,
+			ADD COLUMN IF NOT EXISTS template_version INT,
+			ADD COLUMN IF NOT EXISTS request_id TEXT;
+		CREATE INDEX IF NOT EXISTS request_logs_trace_id_idx ON request_logs (trace_id);
+		CREATE INDEX IF NOT EXISTS request_logs_request_id_idx ON request_logs (request_id);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate request_logs: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		ALTER TABLE jobs
+			ADD COLUMN IF NOT EXISTS error_code TEXT,
+			ADD COLUMN IF NOT EXISTS run_after TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS jobs_finished_idx ON jobs (finished_at);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate jobs: %v", err)
+	}
+
+	return &PostgresRepository{pool: pool}, nil
+}
+
+// Ping checks that a pooled connection can reach the database.
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// Close waits for queries in progress and closes the pool.
+func (r *PostgresRepository) Close() {
+	r.pool.Close()
 }
 
 func (r *PostgresRepository) LogRequest(ctx context.Context, log ports.RequestLog) error {
@@ -66,15 +171,23 @@ func (r *PostgresRepository) LogRequest(ctx context.Context, log ports.RequestLo
 	if log.UserID != "" {
 		userID = sql.NullString{String: log.UserID, Valid: true}
 	}
-	_, err := r.conn.Exec(ctx, `
-		INSERT INTO request_logs (user_id, prompt, provider, response, duration_ms, prompt_tokens, completion_tokens, total_tokens, cost_usd, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`, userID, log.Prompt, log.Provider, log.Response, log.DurationMs, log.PromptTokens, log.CompletionTokens, log.TotalTokens, log.CostUSD, log.CreatedAt)
+	// Only attachment metadata is stored, never the bytes.
+	var attachments []byte
+	if len(log.Attachments) > 0 {
+		var err error
+		if attachments, err = json.Marshal(log.Attachments); err != nil {
+			return fmt.Errorf("failed to encode attachments: %w", err)
+		}
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO request_logs (user_id, prompt, provider, model, response, duration_ms, prompt_tokens, completion_tokens, total_tokens, cost_usd, routing_strategy, routing_reason, hedge_role, hedge_winner, error, trace_id, trace_step, attachments, template_name, template_version, request_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16, ''), NULLIF($17, 0), $18, NULLIF($19, ''), NULLIF($20, 0), NULLIF($21, ''), $22)
+	`, userID, log.Prompt, log.Provider, log.Model, log.Response, log.DurationMs, log.PromptTokens, log.CompletionTokens, log.TotalTokens, log.CostUSD, log.RoutingStrategy, log.RoutingReason, log.HedgeRole, log.HedgeWinner, log.Error, log.TraceID, log.TraceStep, attachments, log.TemplateName, log.TemplateVersion, log.RequestID, log.CreatedAt)
 	return err
 }
 
 func (r *PostgresRepository) CreateUser(ctx context.Context, name string) (*ports.User, error) {
-	row := r.conn.QueryRow(ctx, `
+	row := r.pool.QueryRow(ctx, `
 		INSERT INTO users (name)
 		VALUES ($1)
 		RETURNING id, created_at
@@ -88,12 +201,37 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, name string) (*port
 }
 
 func (r *PostgresRepository) GetUser(ctx context.Context, id string) (*ports.User, error) {
-	row := r.conn.QueryRow(ctx, `
+	row := r.pool.QueryRow(ctx, `
 		SELECT id, name, created_at FROM users WHERE id = $1
 	`, id)
 	var user ports.User
 	if err := row.Scan(&user.ID, &user.Name, &user.CreatedAt); err != nil {
+		return nil, notFound(err, "user "+id)
+	}
+	return &user, nil
+}
+
+func (r *PostgresRepository) CreateAPIKey(ctx context.Context, key ports.APIKey, keyHash string) (*ports.APIKey, error) {
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, key.UserID, key.Name, key.Prefix, keyHash)
+	if err := row.Scan(&key.ID, &key.CreatedAt); err != nil {
 		return nil, err
 	}
+	return &key, nil
+}
+
+func (r *PostgresRepository) GetUserByAPIKey(ctx context.Context, keyHash string) (*ports.User, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT u.id, u.name, u.created_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1
+	`, keyHash)
+	var user ports.User
+	if err := row.Scan(&user.ID, &user.Name, &user.CreatedAt); err != nil {
+		return nil, notFound(err, "api key")
+	}
 	return &user, nil
 }
@@ -2,11 +2,18 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// tagKeyPrefix namespaces the sets that track which keys carry a tag.
+const tagKeyPrefix = "tag:"
+
+// scanBatch is the COUNT hint used when walking the keyspace.
+const scanBatch = 500
+
 type RedisCache struct {
 	client *redis.Client
 }
@@ -20,10 +27,98 @@ func NewRedisCache(addr, password string) *RedisCache {
 	return &RedisCache{client: rdb}
 }
 
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
 func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	return c.client.Get(ctx, key).Result()
 }
 
-func (c *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
-	return c.client.Set(ctx, key, value, ttl).Err()
+func (c *RedisCache) Set(ctx context.Context, key string, value string, ttl time.Duration, tags ...string) error {
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, key, value, ttl)
+	for _, tag := range tags {
+		tagKey := tagKeyPrefix + tag
+		pipe.SAdd(ctx, tagKey, key)
+		if ttl > 0 {
+			// Keep the tag set alive as long as its longest-lived member.
+			pipe.ExpireNX(ctx, tagKey, ttl)
+			pipe.ExpireGT(ctx, tagKey, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return c.client.Del(ctx, keys...).Result()
+}
+
+func (c *RedisCache) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var deleted int64
+	err := c.scan(ctx, prefix, func(keys []string) error {
+		n, err := c.client.Del(ctx, keys...).Result()
+		deleted += n
+		return err
+	})
+	return deleted, err
+}
+
+func (c *RedisCache) InvalidateTag(ctx context.Context, tag string) (int64, error) {
+	tagKey := tagKeyPrefix + tag
+	keys, err := c.client.SMembers(ctx, tagKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	if len(keys) > 0 {
+		if deleted, err = c.client.Del(ctx, keys...).Result(); err != nil {
+			return 0, err
+		}
+	}
+	return deleted, c.client.Del(ctx, tagKey).Err()
+}
+
+func (c *RedisCache) Count(ctx context.Context, prefix string) (int64, error) {
+	var count int64
+	err := c.scan(ctx, prefix, func(keys []string) error {
+		count += int64(len(keys))
+		return nil
+	})
+	return count, err
+}
+
+// scan walks every key starting with prefix, handing them to fn in batches.
+func (c *RedisCache) scan(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	match := escapeGlob(prefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, match, scanBatch).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
 }
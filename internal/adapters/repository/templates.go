@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// CreateTemplateVersion inserts t as one more than the highest stored
+// version of its name. Two concurrent saves of the same name race for the
+// same version; the loser fails on the primary key and can retry.
+func (r *PostgresRepository) CreateTemplateVersion(ctx context.Context, t ports.PromptTemplate) (*ports.PromptTemplate, error) {
+	variables, err := json.Marshal(t.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variables: %w", err)
+	}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO prompt_templates (name, version, description, system, body, variables)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM prompt_templates WHERE name = $1
+		RETURNING version, created_at
+	`, t.Name, t.Description, t.System, t.Body, variables)
+	if err := row.Scan(&t.Version, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PostgresRepository) GetTemplate(ctx context.Context, name string, version int) (*ports.PromptTemplate, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT name, version, COALESCE(description, ''), COALESCE(system, ''), body, variables, created_at
+		FROM prompt_templates
+		WHERE name = $1 AND ($2 = 0 OR version = $2)
+		ORDER BY version DESC
+		LIMIT 1
+	`, name, version)
+	t, err := scanTemplate(row)
+	if err != nil {
+		return nil, notFound(err, "template "+name)
+	}
+	return t, nil
+}
+
+func (r *PostgresRepository) ListTemplates(ctx context.Context) ([]ports.PromptTemplate, error) {
+	return r.queryTemplates(ctx, `
+		SELECT DISTINCT ON (name) name, version, COALESCE(description, ''), COALESCE(system, ''), body, variables, created_at
+		FROM prompt_templates
+		ORDER BY name, version DESC
+	`)
+}
+
+func (r *PostgresRepository) ListTemplateVersions(ctx context.Context, name string) ([]ports.PromptTemplate, error) {
+	return r.queryTemplates(ctx, `
+		SELECT name, version, COALESCE(description, ''), COALESCE(system, ''), body, variables, created_at
+		FROM prompt_templates
+		WHERE name = $1
+		ORDER BY version
+	`, name)
+}
+
+func (r *PostgresRepository) DeleteTemplate(ctx context.Context, name string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM prompt_templates WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("template %s: %w", name, ports.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) queryTemplates(ctx context.Context, query string, args ...any) ([]ports.PromptTemplate, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ports.PromptTemplate
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func scanTemplate(row pgx.Row) (*ports.PromptTemplate, error) {
+	var t ports.PromptTemplate
+	var variables []byte
+	if err := row.Scan(&t.Name, &t.Version, &t.Description, &t.System, &t.Body, &variables, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if len(variables) > 0 {
+		if err := json.Unmarshal(variables, &t.Variables); err != nil {
+			return nil, fmt.Errorf("failed to decode variables of %s@%d: %w", t.Name, t.Version, err)
+		}
+	}
+	return &t, nil
+}
@@ -2,21 +2,63 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	LLM      LLMConfig
+	Server    ServerConfig
+	CORS      CORSConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Cache     CacheConfig
+	Routing   RoutingConfig
+	Hedge     HedgeConfig
+	Agent     AgentConfig
+	Uploads   UploadConfig
+	Limits    LimitConfig
+	Knowledge KnowledgeConfig
+	Jobs      JobConfig
+	Batch     BatchConfig
+	Tracing   TracingConfig
+	Log       LogConfig
+	Health    HealthConfig
+	LLM       LLMConfig
 }
 
 type ServerConfig struct {
-	Port string `mapstructure:"SERVER_PORT"`
-	Env  string `mapstructure:"ENV"`
+	Port        string `mapstructure:"SERVER_PORT"`
+	GRPCPort    string `mapstructure:"GRPC_PORT"`
+	Env         string `mapstructure:"ENV"`
+	AdminAPIKey string `mapstructure:"ADMIN_API_KEY"`
+	// HTTP server timeouts. WriteTimeout bounds whole responses, streams
+	// and agent runs included, so it is generous.
+	ReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long a stopping server may spend draining
+	// requests and flushing writes before it exits anyway.
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// HSTSMaxAge sends Strict-Transport-Security when set. Only set it
+	// when the server is reached over HTTPS alone.
+	HSTSMaxAge time.Duration `mapstructure:"HSTS_MAX_AGE"`
+}
+
+// CORSConfig controls which browser origins may call the HTTP API. Lists
+// are comma separated; with no origins, cross-origin requests are refused.
+type CORSConfig struct {
+	// AllowedOrigins are parsed from CORS_ALLOWED_ORIGINS; "*" allows any.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP auth.
+	AllowCredentials bool `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	// MaxAge is how long browsers may cache a preflight answer.
+	MaxAge time.Duration `mapstructure:"CORS_MAX_AGE"`
 }
 
 type DatabaseConfig struct {
@@ -32,6 +74,135 @@ type RedisConfig struct {
 	Password string `mapstructure:"REDIS_PASSWORD"`
 }
 
+type CacheConfig struct {
+	TTL    time.Duration `mapstructure:"CACHE_TTL"`
+	MaxTTL time.Duration `mapstructure:"CACHE_MAX_TTL"`
+}
+
+type RoutingConfig struct {
+	// Strategy picks a provider when a request does not name one.
+	Strategy string `mapstructure:"ROUTING_STRATEGY"`
+	// Weights are parsed from ROUTING_WEIGHTS, e.g. "openai=3,gemini=1".
+	Weights map[string]int
+}
+
+type HedgeConfig struct {
+	// Mode is "off", "delay" or "race".
+	Mode      string        `mapstructure:"HEDGE_MODE"`
+	Delay     time.Duration `mapstructure:"HEDGE_DELAY"`
+	Secondary string        `mapstructure:"HEDGE_SECONDARY"`
+}
+
+type AgentConfig struct {
+	// MaxSteps caps the model calls in one agent run; requests may lower it.
+	MaxSteps int `mapstructure:"AGENT_MAX_STEPS"`
+	// MaxCostUSD caps the spend of one agent run; 0 leaves it to the request.
+	MaxCostUSD float64 `mapstructure:"AGENT_MAX_COST_USD"`
+	// HTTPAllowedHosts is parsed from TOOLS_HTTP_ALLOWED_HOSTS (comma
+	// separated). The http_fetch tool is only offered when it is non-empty.
+	HTTPAllowedHosts []string
+}
+
+type UploadConfig struct {
+	// MaxBytes caps a single inline image or file.
+	MaxBytes int64 `mapstructure:"ATTACHMENT_MAX_BYTES"`
+	// MaxTotalBytes caps all inline attachments of one request.
+	MaxTotalBytes int64 `mapstructure:"ATTACHMENT_MAX_TOTAL_BYTES"`
+}
+
+type LimitConfig struct {
+	// MaxRequestBytes caps the body of a JSON API request, inline
+	// attachments included.
+	MaxRequestBytes int64 `mapstructure:"MAX_REQUEST_BYTES"`
+	// MaxPromptBytes caps the text of a prompt or conversation.
+	MaxPromptBytes int `mapstructure:"MAX_PROMPT_BYTES"`
+	// RejectUnknownFields refuses JSON API requests with fields the
+	// endpoint does not know, instead of ignoring them.
+	RejectUnknownFields bool `mapstructure:"REJECT_UNKNOWN_FIELDS"`
+	// ContextOverflow is what happens to a prompt that, with max_tokens of
+	// output, does not fit the model's context window: "reject" or
+	// "truncate" the oldest messages.
+	ContextOverflow string `mapstructure:"CONTEXT_OVERFLOW"`
+}
+
+type KnowledgeConfig struct {
+	// VectorStore is "postgres" (pgvector) or "memory"; empty disables collections.
+	VectorStore string `mapstructure:"VECTOR_STORE"`
+	// ChunkSize and ChunkOverlap are measured in characters.
+	ChunkSize    int `mapstructure:"CHUNK_SIZE"`
+	ChunkOverlap int `mapstructure:"CHUNK_OVERLAP"`
+	// TopK is how many chunks a grounded request retrieves by default.
+	TopK int `mapstructure:"RAG_TOP_K"`
+}
+
+type JobConfig struct {
+	// Workers is how many jobs this process runs at once; 0 leaves the
+	// queue to other replicas.
+	Workers      int           `mapstructure:"JOB_WORKERS"`
+	PollInterval time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	// Lease is how long a worker may hold a job before another may take it
+	// over; it also bounds the run time of one job.
+	Lease time.Duration `mapstructure:"JOB_LEASE"`
+	// MaxAttempts caps how often a job is run: jobs failing with a transient
+	// error are retried, and abandoned ones picked up again, until it is
+	// reached.
+	MaxAttempts int `mapstructure:"JOB_MAX_ATTEMPTS"`
+	// Retention is how long finished jobs, request and result included, are
+	// kept before they are deleted.
+	Retention time.Duration `mapstructure:"JOB_RETENTION"`
+	// WebhookSecret signs webhook payloads; webhooks are refused without it.
+	WebhookSecret      string `mapstructure:"JOB_WEBHOOK_SECRET"`
+	WebhookMaxAttempts int    `mapstructure:"JOB_WEBHOOK_MAX_ATTEMPTS"`
+	// WebhookAllowedHosts is parsed from JOB_WEBHOOK_ALLOWED_HOSTS (comma
+	// separated). When non-empty, webhooks may only go to these hosts.
+	WebhookAllowedHosts []string
+}
+
+type BatchConfig struct {
+	// Dir holds uploaded batches, one directory each.
+	Dir         string `mapstructure:"BATCH_DIR"`
+	Concurrency int    `mapstructure:"BATCH_CONCURRENCY"`
+	// RateLimits are parsed from BATCH_RATE_LIMITS, requests per minute per
+	// provider, e.g. "openai=600,gemini=300".
+	RateLimits     map[string]int
+	MaxUploadBytes int64 `mapstructure:"BATCH_MAX_UPLOAD_BYTES"`
+}
+
+type TracingConfig struct {
+	// Exporter is "otlp", "stdout" or "none".
+	Exporter string `mapstructure:"TRACING_EXPORTER"`
+	// OTLPEndpoint is an OTLP/HTTP base URL. When empty the exporter
+	// falls back to its defaults and the process's OTEL_EXPORTER_OTLP_*
+	// environment.
+	OTLPEndpoint string  `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string  `mapstructure:"OTEL_SERVICE_NAME"`
+	SampleRatio  float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `mapstructure:"LOG_LEVEL"`
+	// Format is json or text.
+	Format string `mapstructure:"LOG_FORMAT"`
+	// Redaction is the policy for prompt and response text in application
+	// logs, StoredRedaction the one for request logs kept in the database:
+	// none, truncate or hash.
+	Redaction       string `mapstructure:"LOG_REDACTION"`
+	StoredRedaction string `mapstructure:"REQUEST_LOG_REDACTION"`
+	// RedactLength is how many characters the truncate policy keeps.
+	RedactLength int `mapstructure:"LOG_REDACT_LENGTH"`
+}
+
+type HealthConfig struct {
+	// Timeout bounds each dependency check of a readiness probe.
+	Timeout time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	// CacheTTL is how long readiness results are reused, so frequent probes
+	// do not each hit every dependency.
+	CacheTTL time.Duration `mapstructure:"HEALTH_CACHE_TTL"`
+	// ProviderProbes adds a cheap API call per provider to readiness.
+	ProviderProbes bool `mapstructure:"HEALTH_PROVIDER_PROBES"`
+}
+
 type LLMConfig struct {
 	OpenAIKey             string  `mapstructure:"OPENAI_API_KEY"`
 	GeminiKey             string  `mapstructure:"GEMINI_API_KEY"`
@@ -41,6 +212,17 @@ type LLMConfig struct {
 	OpenAIOutputCostPer1K float64 `mapstructure:"OPENAI_OUTPUT_COST_PER_1K"`
 	GeminiInputCostPer1K  float64 `mapstructure:"GEMINI_INPUT_COST_PER_1K"`
 	GeminiOutputCostPer1K float64 `mapstructure:"GEMINI_OUTPUT_COST_PER_1K"`
+	// Embedding models are used by /embeddings requests that name no model.
+	OpenAIEmbeddingModel     string  `mapstructure:"OPENAI_EMBEDDING_MODEL"`
+	GeminiEmbeddingModel     string  `mapstructure:"GEMINI_EMBEDDING_MODEL"`
+	OpenAIEmbeddingCostPer1K float64 `mapstructure:"OPENAI_EMBEDDING_COST_PER_1K"`
+	GeminiEmbeddingCostPer1K float64 `mapstructure:"GEMINI_EMBEDDING_COST_PER_1K"`
+	// Allowed models are parsed from OPENAI_ALLOWED_MODELS / GEMINI_ALLOWED_MODELS
+	// (comma separated upstream IDs). Empty allows every catalog model.
+	OpenAIAllowedModels []string
+	GeminiAllowedModels []string
+	// ModelCatalogPath points at a YAML/JSON model catalog; empty uses the flat settings above.
+	ModelCatalogPath string `mapstructure:"MODEL_CATALOG_PATH"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -50,8 +232,52 @@ func LoadConfig() (*Config, error) {
 	// Replace dots with underscores in env variables
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.SetDefault("SERVER_PORT", "8080")
+	viper.SetDefault("GRPC_PORT", "50051")
+	viper.SetDefault("HTTP_READ_HEADER_TIMEOUT", "10s")
+	viper.SetDefault("HTTP_READ_TIMEOUT", "2m")
+	viper.SetDefault("HTTP_WRITE_TIMEOUT", "10m")
+	viper.SetDefault("HTTP_IDLE_TIMEOUT", "2m")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("CORS_ALLOWED_METHODS", "GET,POST,DELETE")
+	viper.SetDefault("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Request-ID")
+	viper.SetDefault("CORS_MAX_AGE", "10m")
 	viper.SetDefault("OPENAI_MODEL", "gpt-3.5-turbo")
 	viper.SetDefault("GEMINI_MODEL", "gemini-2.0-flash-exp")
+	viper.SetDefault("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small")
+	viper.SetDefault("GEMINI_EMBEDDING_MODEL", "text-embedding-004")
+	viper.SetDefault("CACHE_TTL", "1h")
+	viper.SetDefault("CACHE_MAX_TTL", "24h")
+	viper.SetDefault("ROUTING_STRATEGY", "priority")
+	viper.SetDefault("HEDGE_MODE", "off")
+	viper.SetDefault("HEDGE_DELAY", "500ms")
+	viper.SetDefault("AGENT_MAX_STEPS", 5)
+	viper.SetDefault("ATTACHMENT_MAX_BYTES", 5<<20)
+	viper.SetDefault("ATTACHMENT_MAX_TOTAL_BYTES", 20<<20)
+	viper.SetDefault("MAX_REQUEST_BYTES", 32<<20)
+	viper.SetDefault("MAX_PROMPT_BYTES", 1<<20)
+	viper.SetDefault("CONTEXT_OVERFLOW", "reject")
+	viper.SetDefault("CHUNK_SIZE", 1000)
+	viper.SetDefault("CHUNK_OVERLAP", 150)
+	viper.SetDefault("RAG_TOP_K", 4)
+	viper.SetDefault("JOB_WORKERS", 2)
+	viper.SetDefault("JOB_POLL_INTERVAL", "1s")
+	viper.SetDefault("JOB_LEASE", "10m")
+	viper.SetDefault("JOB_MAX_ATTEMPTS", 3)
+	viper.SetDefault("JOB_RETENTION", "168h")
+	viper.SetDefault("JOB_WEBHOOK_MAX_ATTEMPTS", 5)
+	viper.SetDefault("BATCH_DIR", "data/batches")
+	viper.SetDefault("BATCH_CONCURRENCY", 8)
+	viper.SetDefault("BATCH_MAX_UPLOAD_BYTES", 100<<20)
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("OTEL_SERVICE_NAME", "go-llm-nexus")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LOG_REDACTION", "truncate")
+	viper.SetDefault("REQUEST_LOG_REDACTION", "none")
+	viper.SetDefault("LOG_REDACT_LENGTH", 50)
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", "2s")
+	viper.SetDefault("HEALTH_CACHE_TTL", "5s")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -62,7 +288,20 @@ func LoadConfig() (*Config, error) {
 
 	keys := []string{
 		"SERVER_PORT",
+		"GRPC_PORT",
 		"ENV",
+		"ADMIN_API_KEY",
+		"HTTP_READ_HEADER_TIMEOUT",
+		"HTTP_READ_TIMEOUT",
+		"HTTP_WRITE_TIMEOUT",
+		"HTTP_IDLE_TIMEOUT",
+		"SHUTDOWN_TIMEOUT",
+		"HSTS_MAX_AGE",
+		"CORS_ALLOWED_ORIGINS",
+		"CORS_ALLOWED_METHODS",
+		"CORS_ALLOWED_HEADERS",
+		"CORS_ALLOW_CREDENTIALS",
+		"CORS_MAX_AGE",
 		"DB_HOST",
 		"DB_PORT",
 		"DB_USER",
@@ -70,6 +309,50 @@ func LoadConfig() (*Config, error) {
 		"DB_NAME",
 		"REDIS_ADDR",
 		"REDIS_PASSWORD",
+		"CACHE_TTL",
+		"CACHE_MAX_TTL",
+		"ROUTING_STRATEGY",
+		"ROUTING_WEIGHTS",
+		"HEDGE_MODE",
+		"HEDGE_DELAY",
+		"HEDGE_SECONDARY",
+		"AGENT_MAX_STEPS",
+		"AGENT_MAX_COST_USD",
+		"TOOLS_HTTP_ALLOWED_HOSTS",
+		"ATTACHMENT_MAX_BYTES",
+		"ATTACHMENT_MAX_TOTAL_BYTES",
+		"MAX_REQUEST_BYTES",
+		"MAX_PROMPT_BYTES",
+		"REJECT_UNKNOWN_FIELDS",
+		"CONTEXT_OVERFLOW",
+		"VECTOR_STORE",
+		"CHUNK_SIZE",
+		"CHUNK_OVERLAP",
+		"RAG_TOP_K",
+		"JOB_WORKERS",
+		"JOB_POLL_INTERVAL",
+		"JOB_LEASE",
+		"JOB_MAX_ATTEMPTS",
+		"JOB_RETENTION",
+		"JOB_WEBHOOK_SECRET",
+		"JOB_WEBHOOK_MAX_ATTEMPTS",
+		"JOB_WEBHOOK_ALLOWED_HOSTS",
+		"BATCH_DIR",
+		"BATCH_CONCURRENCY",
+		"BATCH_RATE_LIMITS",
+		"BATCH_MAX_UPLOAD_BYTES",
+		"TRACING_EXPORTER",
+		"OTEL_EXPORTER_OTLP_ENDPOINT",
+		"OTEL_SERVICE_NAME",
+		"TRACING_SAMPLE_RATIO",
+		"LOG_LEVEL",
+		"LOG_FORMAT",
+		"LOG_REDACTION",
+		"REQUEST_LOG_REDACTION",
+		"LOG_REDACT_LENGTH",
+		"HEALTH_CHECK_TIMEOUT",
+		"HEALTH_CACHE_TTL",
+		"HEALTH_PROVIDER_PROBES",
 		"OPENAI_API_KEY",
 		"GEMINI_API_KEY",
 		"OPENAI_MODEL",
@@ -78,6 +361,13 @@ func LoadConfig() (*Config, error) {
 		"OPENAI_OUTPUT_COST_PER_1K",
 		"GEMINI_INPUT_COST_PER_1K",
 		"GEMINI_OUTPUT_COST_PER_1K",
+		"OPENAI_EMBEDDING_MODEL",
+		"GEMINI_EMBEDDING_MODEL",
+		"OPENAI_EMBEDDING_COST_PER_1K",
+		"GEMINI_EMBEDDING_COST_PER_1K",
+		"MODEL_CATALOG_PATH",
+		"OPENAI_ALLOWED_MODELS",
+		"GEMINI_ALLOWED_MODELS",
 	}
 	for _, key := range keys {
 		if err := viper.BindEnv(key); err != nil {
@@ -85,10 +375,35 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	weights, err := ParseProviderInts("ROUTING_WEIGHTS", viper.GetString("ROUTING_WEIGHTS"))
+	if err != nil {
+		return nil, err
+	}
+	rateLimits, err := ParseProviderInts("BATCH_RATE_LIMITS", viper.GetString("BATCH_RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: viper.GetString("SERVER_PORT"),
-			Env:  viper.GetString("ENV"),
+			Port:        viper.GetString("SERVER_PORT"),
+			GRPCPort:    viper.GetString("GRPC_PORT"),
+			Env:         viper.GetString("ENV"),
+			AdminAPIKey: viper.GetString("ADMIN_API_KEY"),
+
+			ReadHeaderTimeout: viper.GetDuration("HTTP_READ_HEADER_TIMEOUT"),
+			ReadTimeout:       viper.GetDuration("HTTP_READ_TIMEOUT"),
+			WriteTimeout:      viper.GetDuration("HTTP_WRITE_TIMEOUT"),
+			IdleTimeout:       viper.GetDuration("HTTP_IDLE_TIMEOUT"),
+			ShutdownTimeout:   viper.GetDuration("SHUTDOWN_TIMEOUT"),
+			HSTSMaxAge:        viper.GetDuration("HSTS_MAX_AGE"),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   splitList(viper.GetString("CORS_ALLOWED_ORIGINS")),
+			AllowedMethods:   splitList(viper.GetString("CORS_ALLOWED_METHODS")),
+			AllowedHeaders:   splitList(viper.GetString("CORS_ALLOWED_HEADERS")),
+			AllowCredentials: viper.GetBool("CORS_ALLOW_CREDENTIALS"),
+			MaxAge:           viper.GetDuration("CORS_MAX_AGE"),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("DB_HOST"),
@@ -101,17 +416,125 @@ func LoadConfig() (*Config, error) {
 			Addr:     viper.GetString("REDIS_ADDR"),
 			Password: viper.GetString("REDIS_PASSWORD"),
 		},
+		Cache: CacheConfig{
+			TTL:    viper.GetDuration("CACHE_TTL"),
+			MaxTTL: viper.GetDuration("CACHE_MAX_TTL"),
+		},
+		Routing: RoutingConfig{
+			Strategy: viper.GetString("ROUTING_STRATEGY"),
+			Weights:  weights,
+		},
+		Hedge: HedgeConfig{
+			Mode:      viper.GetString("HEDGE_MODE"),
+			Delay:     viper.GetDuration("HEDGE_DELAY"),
+			Secondary: viper.GetString("HEDGE_SECONDARY"),
+		},
+		Agent: AgentConfig{
+			MaxSteps:         viper.GetInt("AGENT_MAX_STEPS"),
+			MaxCostUSD:       viper.GetFloat64("AGENT_MAX_COST_USD"),
+			HTTPAllowedHosts: splitList(viper.GetString("TOOLS_HTTP_ALLOWED_HOSTS")),
+		},
+		Uploads: UploadConfig{
+			MaxBytes:      viper.GetInt64("ATTACHMENT_MAX_BYTES"),
+			MaxTotalBytes: viper.GetInt64("ATTACHMENT_MAX_TOTAL_BYTES"),
+		},
+		Limits: LimitConfig{
+			MaxRequestBytes:     viper.GetInt64("MAX_REQUEST_BYTES"),
+			MaxPromptBytes:      viper.GetInt("MAX_PROMPT_BYTES"),
+			RejectUnknownFields: viper.GetBool("REJECT_UNKNOWN_FIELDS"),
+			ContextOverflow:     viper.GetString("CONTEXT_OVERFLOW"),
+		},
+		Knowledge: KnowledgeConfig{
+			VectorStore:  viper.GetString("VECTOR_STORE"),
+			ChunkSize:    viper.GetInt("CHUNK_SIZE"),
+			ChunkOverlap: viper.GetInt("CHUNK_OVERLAP"),
+			TopK:         viper.GetInt("RAG_TOP_K"),
+		},
+		Jobs: JobConfig{
+			Workers:             viper.GetInt("JOB_WORKERS"),
+			PollInterval:        viper.GetDuration("JOB_POLL_INTERVAL"),
+			Lease:               viper.GetDuration("JOB_LEASE"),
+			MaxAttempts:         viper.GetInt("JOB_MAX_ATTEMPTS"),
+			Retention:           viper.GetDuration("JOB_RETENTION"),
+			WebhookSecret:       viper.GetString("JOB_WEBHOOK_SECRET"),
+			WebhookMaxAttempts:  viper.GetInt("JOB_WEBHOOK_MAX_ATTEMPTS"),
+			WebhookAllowedHosts: splitList(viper.GetString("JOB_WEBHOOK_ALLOWED_HOSTS")),
+		},
+		Batch: BatchConfig{
+			Dir:            viper.GetString("BATCH_DIR"),
+			Concurrency:    viper.GetInt("BATCH_CONCURRENCY"),
+			RateLimits:     rateLimits,
+			MaxUploadBytes: viper.GetInt64("BATCH_MAX_UPLOAD_BYTES"),
+		},
+		Tracing: TracingConfig{
+			Exporter:     viper.GetString("TRACING_EXPORTER"),
+			OTLPEndpoint: viper.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"),
+			ServiceName:  viper.GetString("OTEL_SERVICE_NAME"),
+			SampleRatio:  viper.GetFloat64("TRACING_SAMPLE_RATIO"),
+		},
+		Log: LogConfig{
+			Level:           viper.GetString("LOG_LEVEL"),
+			Format:          viper.GetString("LOG_FORMAT"),
+			Redaction:       viper.GetString("LOG_REDACTION"),
+			StoredRedaction: viper.GetString("REQUEST_LOG_REDACTION"),
+			RedactLength:    viper.GetInt("LOG_REDACT_LENGTH"),
+		},
+		Health: HealthConfig{
+			Timeout:        viper.GetDuration("HEALTH_CHECK_TIMEOUT"),
+			CacheTTL:       viper.GetDuration("HEALTH_CACHE_TTL"),
+			ProviderProbes: viper.GetBool("HEALTH_PROVIDER_PROBES"),
+		},
 		LLM: LLMConfig{
-			OpenAIKey:             viper.GetString("OPENAI_API_KEY"),
-			GeminiKey:             viper.GetString("GEMINI_API_KEY"),
-			OpenAIModel:           viper.GetString("OPENAI_MODEL"),
-			GeminiModel:           viper.GetString("GEMINI_MODEL"),
-			OpenAIInputCostPer1K:  viper.GetFloat64("OPENAI_INPUT_COST_PER_1K"),
-			OpenAIOutputCostPer1K: viper.GetFloat64("OPENAI_OUTPUT_COST_PER_1K"),
-			GeminiInputCostPer1K:  viper.GetFloat64("GEMINI_INPUT_COST_PER_1K"),
-			GeminiOutputCostPer1K: viper.GetFloat64("GEMINI_OUTPUT_COST_PER_1K"),
+			OpenAIKey:                viper.GetString("OPENAI_API_KEY"),
+			GeminiKey:                viper.GetString("GEMINI_API_KEY"),
+			OpenAIModel:              viper.GetString("OPENAI_MODEL"),
+			GeminiModel:              viper.GetString("GEMINI_MODEL"),
+			OpenAIInputCostPer1K:     viper.GetFloat64("OPENAI_INPUT_COST_PER_1K"),
+			OpenAIOutputCostPer1K:    viper.GetFloat64("OPENAI_OUTPUT_COST_PER_1K"),
+			GeminiInputCostPer1K:     viper.GetFloat64("GEMINI_INPUT_COST_PER_1K"),
+			GeminiOutputCostPer1K:    viper.GetFloat64("GEMINI_OUTPUT_COST_PER_1K"),
+			OpenAIEmbeddingModel:     viper.GetString("OPENAI_EMBEDDING_MODEL"),
+			GeminiEmbeddingModel:     viper.GetString("GEMINI_EMBEDDING_MODEL"),
+			OpenAIEmbeddingCostPer1K: viper.GetFloat64("OPENAI_EMBEDDING_COST_PER_1K"),
+			GeminiEmbeddingCostPer1K: viper.GetFloat64("GEMINI_EMBEDDING_COST_PER_1K"),
+			OpenAIAllowedModels:      splitList(viper.GetString("OPENAI_ALLOWED_MODELS")),
+			GeminiAllowedModels:      splitList(viper.GetString("GEMINI_ALLOWED_MODELS")),
+			ModelCatalogPath:         viper.GetString("MODEL_CATALOG_PATH"),
 		},
 	}
 
 	return cfg, nil
 }
+
+// ParseProviderInts reads a comma separated list of provider=number pairs
+// from the setting named key.
+func ParseProviderInts(key, raw string) (map[string]int, error) {
+	values := make(map[string]int)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected provider=number", key, pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s value for %s: %q", key, name, value)
+		}
+		values[strings.TrimSpace(name)] = n
+	}
+	return values, nil
+}
+
+// splitList reads a comma separated list, dropping empty entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,247 @@
+// Package catalog holds the models the gateway can route to, their pricing
+// and the logical aliases (such as "fast" or "cheap") callers may use instead
+// of provider model IDs.
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// Catalog is an immutable, in-memory ports.ModelCatalog.
+type Catalog struct {
+	models   map[string]ports.ModelInfo
+	upstream map[string]ports.ModelInfo
+	aliases  map[string]string
+	defaults map[string]string
+}
+
+// New builds a catalog. defaults maps a provider to the model ID it uses when
+// a request does not name one. Every alias and default must point at a model
+// in the list.
+func New(models []ports.ModelInfo, aliases, defaults map[string]string) (*Catalog, error) {
+	c := &Catalog{
+		models:   make(map[string]ports.ModelInfo),
+		upstream: make(map[string]ports.ModelInfo),
+		aliases:  make(map[string]string),
+		defaults: make(map[string]string),
+	}
+	for _, m := range models {
+		if m.ID == "" || m.Provider == "" {
+			return nil, fmt.Errorf("catalog model requires id and provider: %+v", m)
+		}
+		if m.UpstreamID == "" {
+			m.UpstreamID = m.ID
+		}
+		if _, dup := c.models[m.ID]; dup {
+			return nil, fmt.Errorf("duplicate catalog model %q", m.ID)
+		}
+		key := upstreamKey(m.Provider, m.UpstreamID)
+		if prev, dup := c.upstream[key]; dup {
+			return nil, fmt.Errorf("catalog models %q and %q both map to %s", prev.ID, m.ID, key)
+		}
+		c.models[m.ID] = m
+		c.upstream[key] = m
+	}
+	for alias, target := range aliases {
+		if _, ok := c.models[target]; !ok {
+			return nil, fmt.Errorf("alias %q points at unknown model %q", alias, target)
+		}
+		c.aliases[alias] = target
+	}
+	for provider, id := range defaults {
+		m, ok := c.models[id]
+		if !ok {
+			return nil, fmt.Errorf("default model %q for %s is not in the catalog", id, provider)
+		}
+		if m.Provider != provider {
+			return nil, fmt.Errorf("default model %q for %s belongs to %s", id, provider, m.Provider)
+		}
+		c.defaults[provider] = id
+	}
+	return c, nil
+}
+
+func (c *Catalog) Resolve(name string) (ports.ModelInfo, bool) {
+	if target, ok := c.aliases[name]; ok {
+		name = target
+	}
+	m, ok := c.models[name]
+	return m, ok
+}
+
+func (c *Catalog) Lookup(provider, upstreamID string) (ports.ModelInfo, bool) {
+	m, ok := c.upstream[upstreamKey(provider, upstreamID)]
+	return m, ok
+}
+
+func (c *Catalog) Default(provider string) (ports.ModelInfo, bool) {
+	id, ok := c.defaults[provider]
+	if !ok {
+		return ports.ModelInfo{}, false
+	}
+	return c.models[id], true
+}
+
+// Models returns every model sorted by provider and ID.
+func (c *Catalog) Models() []ports.ModelInfo {
+	out := make([]ports.ModelInfo, 0, len(c.models))
+	for _, m := range c.models {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (c *Catalog) Aliases() map[string]string {
+	out := make(map[string]string, len(c.aliases))
+	for k, v := range c.aliases {
+		out[k] = v
+	}
+	return out
+}
+
+func upstreamKey(provider, upstreamID string) string {
+	return provider + "/" + upstreamID
+}
+
+type fileModel struct {
+	ID              string   `mapstructure:"id"`
+	Provider        string   `mapstructure:"provider"`
+	UpstreamID      string   `mapstructure:"upstream_id"`
+	ContextWindow   int32    `mapstructure:"context_window"`
+	MaxOutputTokens int32    `mapstructure:"max_output_tokens"`
+	MaxTemperature  float32  `mapstructure:"max_temperature"`
+	InputPer1K      float64  `mapstructure:"input_cost_per_1k"`
+	OutputPer1K     float64  `mapstructure:"output_cost_per_1k"`
+	CachedInPer1K   float64  `mapstructure:"cached_input_cost_per_1k"`
+	Capabilities    []string `mapstructure:"capabilities"`
+}
+
+type file struct {
+	Models   []fileModel       `mapstructure:"models"`
+	Aliases  map[string]string `mapstructure:"aliases"`
+	Defaults map[string]string `mapstructure:"defaults"`
+}
+
+// Load reads a catalog file (YAML, JSON or TOML, chosen by extension). The
+// legacy OPENAI_*/GEMINI_* settings in llm are used for any provider whose
+// default model the file does not declare, so existing deployments keep their
+// pricing when they adopt a catalog.
+func Load(path string, llm config.LLMConfig) (*Catalog, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading model catalog: %w", err)
+	}
+	var f file
+	if err := v.Unmarshal(&f); err != nil {
+		return nil, fmt.Errorf("error parsing model catalog: %w", err)
+	}
+
+	models := make([]ports.ModelInfo, 0, len(f.Models))
+	for _, fm := range f.Models {
+		m := ports.ModelInfo{
+			ID:                   fm.ID,
+			Provider:             strings.ToLower(fm.Provider),
+			UpstreamID:           fm.UpstreamID,
+			ContextWindow:        fm.ContextWindow,
+			MaxOutputTokens:      fm.MaxOutputTokens,
+			MaxTemperature:       fm.MaxTemperature,
+			InputCostPer1K:       fm.InputPer1K,
+			OutputCostPer1K:      fm.OutputPer1K,
+			CachedInputCostPer1K: fm.CachedInPer1K,
+		}
+		for _, capability := range fm.Capabilities {
+			switch strings.ToLower(capability) {
+			case "vision":
+				m.Capabilities.Vision = true
+			case "tools":
+				m.Capabilities.Tools = true
+			case "json":
+				m.Capabilities.JSON = true
+			default:
+				return nil, fmt.Errorf("model %q has unknown capability %q", fm.ID, capability)
+			}
+		}
+		models = append(models, m)
+	}
+
+	defaults := f.Defaults
+	if defaults == nil {
+		defaults = make(map[string]string)
+	}
+	for _, legacy := range legacyModels(llm) {
+		if _, ok := defaults[legacy.Provider]; ok {
+			continue
+		}
+		// Reuse a file entry that already describes the legacy model.
+		found := false
+		for _, m := range models {
+			if m.Provider == legacy.Provider && (m.UpstreamID == legacy.UpstreamID || m.ID == legacy.ID) {
+				defaults[legacy.Provider] = m.ID
+				found = true
+				break
+			}
+		}
+		if !found {
+			models = append(models, legacy)
+			defaults[legacy.Provider] = legacy.ID
+		}
+	}
+	return New(models, f.Aliases, defaults)
+}
+
+// Legacy builds a catalog with one model per provider from the flat
+// OPENAI_*/GEMINI_* settings, for deployments without a catalog file.
+func Legacy(llm config.LLMConfig) *Catalog {
+	c := &Catalog{
+		models:   make(map[string]ports.ModelInfo),
+		upstream: make(map[string]ports.ModelInfo),
+		aliases:  make(map[string]string),
+		defaults: make(map[string]string),
+	}
+	for _, m := range legacyModels(llm) {
+		c.models[m.ID] = m
+		c.upstream[upstreamKey(m.Provider, m.UpstreamID)] = m
+		c.defaults[m.Provider] = m.ID
+	}
+	return c
+}
+
+func legacyModels(llm config.LLMConfig) []ports.ModelInfo {
+	openAIModel := llm.OpenAIModel
+	if openAIModel == "" {
+		openAIModel = "gpt-3.5-turbo"
+	}
+	geminiModel := llm.GeminiModel
+	if geminiModel == "" {
+		geminiModel = "gemini-2.0-flash-exp"
+	}
+	return []ports.ModelInfo{
+		{
+			ID:              openAIModel,
+			Provider:        "openai",
+			UpstreamID:      openAIModel,
+			InputCostPer1K:  llm.OpenAIInputCostPer1K,
+			OutputCostPer1K: llm.OpenAIOutputCostPer1K,
+		},
+		{
+			ID:              geminiModel,
+			Provider:        "gemini",
+			UpstreamID:      geminiModel,
+			InputCostPer1K:  llm.GeminiInputCostPer1K,
+			OutputCostPer1K: llm.GeminiOutputCostPer1K,
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package catalog
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const testCatalog = `
+models:
+  - id: gpt-4o-mini
+    provider: openai
+    context_window: 128000
+    input_cost_per_1k: 0.00015
+    output_cost_per_1k: 0.0006
+    cached_input_cost_per_1k: 0.000075
+    capabilities: [vision, tools]
+aliases:
+  cheap: gpt-4o-mini
+defaults:
+  openai: gpt-4o-mini
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	if err := os.WriteFile(path, []byte(testCatalog), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path, config.LLMConfig{GeminiModel: "gemini-test", GeminiInputCostPer1K: 0.001})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, ok := c.Resolve("cheap")
+	if !ok || m.ID != "gpt-4o-mini" || m.UpstreamID != "gpt-4o-mini" {
+		t.Fatalf("expected alias to resolve to gpt-4o-mini, got %+v", m)
+	}
+	if !m.Capabilities.Vision || !m.Capabilities.Tools || m.Capabilities.JSON {
+		t.Fatalf("unexpected capabilities: %+v", m.Capabilities)
+	}
+
+	// Providers the file does not cover fall back to the legacy settings.
+	g, ok := c.Default("gemini")
+	if !ok || g.UpstreamID != "gemini-test" || g.InputCostPer1K != 0.001 {
+		t.Fatalf("expected legacy gemini default, got %+v", g)
+	}
+
+	cost := m.Cost(ports.UsageInfo{PromptTokens: 2000, CachedTokens: 1000, CompletionTokens: 1000})
+	want := 0.00015 + 0.000075 + 0.0006
+	if math.Abs(cost-want) > 1e-12 {
+		t.Fatalf("expected cost %f, got %f", want, cost)
+	}
+}
+
+func TestNewRejectsDanglingAlias(t *testing.T) {
+	_, err := New([]ports.ModelInfo{{ID: "a", Provider: "openai"}}, map[string]string{"fast": "b"}, nil)
+	if err == nil {
+		t.Fatal("expected an error for an alias to an unknown model")
+	}
+}
+
+func TestNewRejectsDuplicateUpstreamModel(t *testing.T) {
+	_, err := New([]ports.ModelInfo{
+		{ID: "gpt-4o", Provider: "openai"},
+		{ID: "gpt-4o-latest", Provider: "openai", UpstreamID: "gpt-4o"},
+	}, nil, nil)
+	if err == nil {
+		t.Fatal("expected an error for two models with the same upstream id")
+	}
+}
@@ -0,0 +1,140 @@
+package knowledge
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// Chunker splits text into pieces of at most Size characters. Paragraphs are
+// kept whole where they fit; longer ones are split between words. Each chunk
+// after the first starts with up to Overlap characters from the end of the
+// previous one, so a sentence cut at a boundary is still found by search.
+type Chunker struct {
+	Size    int
+	Overlap int
+}
+
+// NewChunker returns a chunker with the defaults applied to non-positive
+// values. Overlap is kept below half the chunk size.
+func NewChunker(size, overlap int) Chunker {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 {
+		overlap = DefaultChunkOverlap
+	}
+	if overlap > size/2 {
+		overlap = size / 2
+	}
+	return Chunker{Size: size, Overlap: overlap}
+}
+
+// Split returns the chunks of text in order. Blank text has no chunks.
+func (c Chunker) Split(text string) []string {
+	var pieces []string
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if utf8.RuneCountInString(para) <= c.budget() {
+			pieces = append(pieces, para)
+			continue
+		}
+		pieces = append(pieces, splitWords(para, c.budget())...)
+	}
+
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		chunks = append(chunks, current.String())
+		current.Reset()
+		if tail := overlapTail(chunks[len(chunks)-1], c.Overlap); tail != "" {
+			current.WriteString(tail)
+		}
+	}
+	for _, piece := range pieces {
+		sep := ""
+		if current.Len() > 0 {
+			sep = "\n\n"
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+len(sep)+utf8.RuneCountInString(piece) > c.Size {
+			flush()
+			if current.Len() > 0 {
+				sep = "\n\n"
+			} else {
+				sep = ""
+			}
+		}
+		current.WriteString(sep + piece)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// budget is the room left for new text once the overlap is carried over.
+func (c Chunker) budget() int {
+	return max(c.Size-c.Overlap-2, 1)
+}
+
+// splitWords breaks text into pieces of at most size characters between
+// words; a single word longer than size is cut.
+func splitWords(text string, size int) []string {
+	var out []string
+	var b strings.Builder
+	n := 0
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > size {
+			if n > 0 {
+				out = append(out, b.String())
+				b.Reset()
+				n = 0
+			}
+			r := []rune(word)
+			out = append(out, string(r[:size]))
+			word = string(r[size:])
+		}
+		wl := utf8.RuneCountInString(word)
+		if n > 0 && n+1+wl > size {
+			out = append(out, b.String())
+			b.Reset()
+			n = 0
+		}
+		if n > 0 {
+			b.WriteString(" ")
+			n++
+		}
+		b.WriteString(word)
+		n += wl
+	}
+	if n > 0 {
+		out = append(out, b.String())
+	}
+	return out
+}
+
+// overlapTail is the end of chunk, at most n characters, starting at a word.
+func overlapTail(chunk string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(chunk)
+	if len(r) <= n {
+		return ""
+	}
+	tail := string(r[len(r)-n:])
+	if i := strings.IndexAny(tail, " \n"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return strings.TrimSpace(tail)
+}
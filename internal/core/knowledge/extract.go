@@ -0,0 +1,107 @@
+// Package knowledge turns uploaded documents into chunks ready to embed.
+package knowledge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Supported document content types.
+const (
+	ContentText     = "text"
+	ContentMarkdown = "markdown"
+	ContentHTML     = "html"
+)
+
+// ErrUnsupportedContentType is returned for documents that are not text,
+// Markdown or HTML.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// NormalizeContentType maps MIME types and short names onto the supported
+// content types; empty means plain text.
+func NormalizeContentType(contentType string) (string, error) {
+	mediaType, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(contentType)), ";")
+	switch strings.TrimSpace(mediaType) {
+	case "", ContentText, "text/plain":
+		return ContentText, nil
+	case ContentMarkdown, "md", "text/markdown":
+		return ContentMarkdown, nil
+	case ContentHTML, "text/html":
+		return ContentHTML, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+}
+
+// Extract returns the readable text of a document. Markdown is kept as is,
+// since models read it well; HTML is reduced to its visible text with block
+// elements on their own lines.
+func Extract(contentType, content string) (string, error) {
+	kind, err := NormalizeContentType(contentType)
+	if err != nil {
+		return "", err
+	}
+	if kind != ContentHTML {
+		return content, nil
+	}
+	return htmlText(content), nil
+}
+
+// skippedElements hold no readable text.
+var skippedElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "head": true}
+
+// blockElements start a new paragraph.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "table": true, "tr": true, "pre": true, "blockquote": true, "br": true,
+}
+
+func htmlText(content string) string {
+	var b strings.Builder
+	skipDepth := 0
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return collapseWhitespace(b.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedElements[tag] {
+				skipDepth++
+			}
+			if blockElements[tag] {
+				b.WriteString("\n\n")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if skippedElements[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+			if blockElements[tag] {
+				b.WriteString("\n\n")
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+// collapseWhitespace collapses the whitespace within each paragraph and keeps
+// one blank line between paragraphs. Single line breaks in the source are
+// layout, not structure, so they become spaces.
+func collapseWhitespace(text string) string {
+	var out []string
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.Join(strings.Fields(para), " "); para != "" {
+			out = append(out, para)
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
@@ -0,0 +1,66 @@
+package knowledge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunker_Split(t *testing.T) {
+	c := NewChunker(60, 15)
+
+	if got := c.Split("  \n\n "); len(got) != 0 {
+		t.Fatalf("expected no chunks for blank text, got %q", got)
+	}
+	if got := c.Split("Short paragraph.\n\nAnother one."); len(got) != 1 || got[0] != "Short paragraph.\n\nAnother one." {
+		t.Fatalf("expected paragraphs packed into one chunk, got %q", got)
+	}
+
+	text := strings.Repeat("alpha beta gamma delta epsilon ", 12)
+	chunks := c.Split(text)
+	if len(chunks) < 3 {
+		t.Fatalf("expected the long paragraph to be split, got %d chunks", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > c.Size {
+			t.Fatalf("chunk %d has %d characters, limit %d", i, n, c.Size)
+		}
+		if i > 0 {
+			prev := strings.Fields(chunks[i-1])
+			if first := strings.Fields(chunk)[0]; first != prev[len(prev)-2] && first != prev[len(prev)-1] {
+				t.Fatalf("chunk %d does not overlap the previous one: %q / %q", i, chunks[i-1], chunk)
+			}
+		}
+	}
+
+	long := strings.Repeat("x", 130)
+	for _, chunk := range c.Split(long) {
+		if utf8.RuneCountInString(chunk) > c.Size {
+			t.Fatalf("unbroken word produced an oversized chunk: %d", utf8.RuneCountInString(chunk))
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	text, err := Extract("text/html; charset=utf-8", `<html><head><title>T</title><style>p{}</style></head>
+<body><h1>Refunds</h1><p>Refunds take   <b>5 days</b>.</p><script>alert(1)</script><ul><li>One</li><li>Two</li></ul></body></html>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Refunds", "Refunds take 5 days.", "One", "Two"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in %q", want, text)
+		}
+	}
+	if strings.Contains(text, "alert") || strings.Contains(text, "p{}") {
+		t.Fatalf("script or style leaked into %q", text)
+	}
+
+	if md, _ := Extract("markdown", "# Title\n\nBody"); md != "# Title\n\nBody" {
+		t.Fatalf("expected markdown unchanged, got %q", md)
+	}
+	if _, err := Extract("application/pdf", "%PDF"); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Fatalf("expected unsupported content type, got %v", err)
+	}
+}
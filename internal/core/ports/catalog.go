@@ -0,0 +1,49 @@
+package ports
+
+// ModelCapabilities lists the optional features a model supports.
+type ModelCapabilities struct {
+	Vision bool
+	Tools  bool
+	JSON   bool
+}
+
+// ModelInfo describes one model in the catalog. ID is the name callers use;
+// UpstreamID is what the provider's API expects.
+type ModelInfo struct {
+	ID              string
+	Provider        string
+	UpstreamID      string
+	ContextWindow   int32
+	MaxOutputTokens int32
+	// MaxTemperature overrides the provider's temperature limit when set.
+	MaxTemperature       float32
+	InputCostPer1K       float64
+	OutputCostPer1K      float64
+	CachedInputCostPer1K float64
+	Capabilities         ModelCapabilities
+}
+
+// Cost prices a completion. Cached prompt tokens are billed at the cached
+// rate when one is set and at the normal input rate otherwise.
+func (m ModelInfo) Cost(usage UsageInfo) float64 {
+	cachedRate := m.CachedInputCostPer1K
+	if cachedRate == 0 {
+		cachedRate = m.InputCostPer1K
+	}
+	uncached := usage.PromptTokens - usage.CachedTokens
+	return float64(uncached)/1000.0*m.InputCostPer1K +
+		float64(usage.CachedTokens)/1000.0*cachedRate +
+		float64(usage.CompletionTokens)/1000.0*m.OutputCostPer1K
+}
+
+// ModelCatalog resolves the models and aliases the gateway knows about.
+type ModelCatalog interface {
+	// Resolve looks up an alias (e.g. "fast") or a model ID.
+	Resolve(name string) (ModelInfo, bool)
+	// Lookup finds a model by provider and upstream model ID.
+	Lookup(provider, upstreamID string) (ModelInfo, bool)
+	// Default returns the model used by provider when a request names none.
+	Default(provider string) (ModelInfo, bool)
+	Models() []ModelInfo
+	Aliases() map[string]string
+}
@@ -0,0 +1,27 @@
+package ports
+
+import "context"
+
+// EmbeddingRequest asks for one vector per input.
+type EmbeddingRequest struct {
+	UserID string
+	// Model is the upstream embedding model; empty uses the provider's default.
+	Model  string
+	Inputs []string
+	// Dimensions shortens the vectors on models that support it; 0 keeps the model's size.
+	Dimensions int32
+}
+
+type EmbeddingResponse struct {
+	// Vectors holds one embedding per input, in input order.
+	Vectors [][]float32
+	Model   string
+	Usage   *UsageInfo
+}
+
+// EmbeddingProvider turns text into vectors. Implementations split large
+// inputs into as many upstream calls as their API limits require.
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+	Name() string
+}
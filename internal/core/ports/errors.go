@@ -0,0 +1,162 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error classes. Failures anywhere in the service are, or wrap, one of these
+// so every adapter can report them the same way, whatever produced them.
+// Match them with errors.Is.
+var (
+	// ErrNotFound is returned by stores for records they do not hold.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidArgument marks requests that are malformed or name values
+	// the service does not accept.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrTooLarge marks requests or uploads over a configured size limit.
+	ErrTooLarge = errors.New("too large")
+	// ErrUnauthenticated marks calls without valid credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrRateLimited is wrapped by providers into errors for calls the
+	// upstream API refused because a rate limit was exceeded.
+	ErrRateLimited = errors.New("rate limited by provider")
+	// ErrBudgetExceeded marks calls refused because a quota or spending
+	// limit is used up.
+	ErrBudgetExceeded = errors.New("budget exceeded")
+	// ErrContentFiltered marks prompts or answers blocked by a provider's
+	// safety filters.
+	ErrContentFiltered = errors.New("content filtered")
+	// ErrProviderUnavailable marks providers that cannot be reached, fail,
+	// or are not set up to serve the request.
+	ErrProviderUnavailable = errors.New("provider unavailable")
+	// ErrInvalidOutput marks provider answers the service cannot use.
+	ErrInvalidOutput = errors.New("invalid provider output")
+	// ErrNotConfigured marks features the server was started without.
+	ErrNotConfigured = errors.New("not configured")
+	// ErrTimeout marks work that did not finish within its deadline.
+	// context.DeadlineExceeded is reported the same way.
+	ErrTimeout = errors.New("timeout")
+)
+
+// errorCodes names the error classes for clients, in the order they are
+// matched.
+var errorCodes = []struct {
+	class error
+	code  string
+}{
+	{ErrInvalidArgument, "invalid_argument"},
+	{ErrUnauthenticated, "unauthenticated"},
+	{ErrBudgetExceeded, "budget_exceeded"},
+	{ErrNotFound, "not_found"},
+	{ErrTooLarge, "too_large"},
+	{ErrContentFiltered, "content_filtered"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrInvalidOutput, "invalid_output"},
+	{ErrProviderUnavailable, "provider_unavailable"},
+	{ErrNotConfigured, "not_configured"},
+	{ErrTimeout, "timeout"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+// ErrorCode names the class of err for clients, e.g. "rate_limited", or
+// "internal" when err has none.
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.class) {
+			return c.code
+		}
+	}
+	return "internal"
+}
+
+// PublicMessage describes err without detail clients must not see: provider
+// failures by their Message, other classified errors by their text, and
+// unclassified errors, which may carry internal detail, as "internal error".
+func PublicMessage(err error) string {
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		return provErr.Message()
+	}
+	if ErrorCode(err) == "internal" {
+		return "internal error"
+	}
+	return err.Error()
+}
+
+// RetryableCode reports whether a failure with the code ErrorCode gave it is
+// transient, so the same request may succeed when sent again later.
+func RetryableCode(code string) bool {
+	switch code {
+	case "rate_limited", "timeout", "provider_unavailable":
+		return true
+	}
+	return false
+}
+
+// NewError returns an error with the given text that matches class.
+// Packages use it to declare their sentinel errors.
+func NewError(class error, text string) error {
+	return &classError{class: class, err: errors.New(text)}
+}
+
+// Errorf formats an error like fmt.Errorf that also matches class.
+func Errorf(class error, format string, args ...any) error {
+	return &classError{class: class, err: fmt.Errorf(format, args...)}
+}
+
+type classError struct {
+	class error
+	err   error
+}
+
+func (e *classError) Error() string        { return e.err.Error() }
+func (e *classError) Unwrap() error        { return e.err }
+func (e *classError) Is(target error) bool { return target == e.class }
+
+// ProviderError is a call an upstream provider refused or failed. Its
+// Message is safe to show to clients; the upstream response stays in Err,
+// for logs.
+type ProviderError struct {
+	// Provider is the name the provider is registered under, e.g. "openai".
+	Provider string
+	// Class is one of the error classes above.
+	Class error
+	Err   error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Provider, e.Class, e.Err)
+}
+
+func (e *ProviderError) Unwrap() []error { return []error{e.Class, e.Err} }
+
+// Message describes the failure without upstream detail.
+func (e *ProviderError) Message() string {
+	return e.Provider + ": " + e.Class.Error()
+}
+
+// FieldError is one invalid field of a request. Field is named as on the
+// wire, e.g. "max_tokens".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError is a request with one or more invalid fields. It matches
+// ErrInvalidArgument.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool { return target == ErrInvalidArgument }
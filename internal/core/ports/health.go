@@ -0,0 +1,10 @@
+package ports
+
+import "context"
+
+// Pinger is implemented by dependencies that can cheaply check they are
+// reachable: the database, the cache and providers whose APIs have a free
+// call to make.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,88 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// JobStatus is the state of an asynchronous generation job.
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	// JobSucceeded and JobFailed are final.
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// WebhookStatus tracks delivery of a finished job to its webhook.
+type WebhookStatus string
+
+const (
+	WebhookPending   WebhookStatus = "pending"
+	WebhookDelivered WebhookStatus = "delivered"
+	// WebhookFailed means every delivery attempt was refused or timed out.
+	WebhookFailed WebhookStatus = "failed"
+)
+
+// Job is a generation run in the background. Request is stored as given and
+// processed exactly like a synchronous request for Provider.
+type Job struct {
+	ID       string
+	UserID   string
+	Provider string
+	Request  LLMRequest
+	Status   JobStatus
+	// Attempts counts how often a worker has claimed the job. It grows past
+	// one when a transient failure is retried or a worker stopped before
+	// finishing.
+	Attempts     int
+	Result       *LLMResponse
+	ProviderUsed string
+	// Error describes a failure without upstream detail, so it can be shown
+	// to the caller; ErrorCode is its class, see ErrorCode. On a queued job
+	// they describe the failure of the attempt that will be retried.
+	Error     string
+	ErrorCode string
+
+	// WebhookURL receives the finished job; empty means the caller polls.
+	WebhookURL      string
+	WebhookStatus   WebhookStatus
+	WebhookAttempts int
+	WebhookError    string
+
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// JobQueue stores jobs and hands them to workers. Claims must be safe when
+// workers in several processes share the queue: a job or webhook is leased
+// to one worker at a time, and a lease that runs out makes it claimable
+// again.
+type JobQueue interface {
+	EnqueueJob(ctx context.Context, job Job) (*Job, error)
+	GetJob(ctx context.Context, id string) (*Job, error)
+	// ClaimJob leases the oldest queued job that is due, or a running job
+	// whose lease has expired, marks it running and counts the attempt. It
+	// returns ErrNotFound when there is nothing to run.
+	ClaimJob(ctx context.Context, lease time.Duration) (*Job, error)
+	// RetryJob queues a job claimed at job.Attempts again with its Error and
+	// ErrorCode, due after retryIn. It returns ErrNotFound if the lease was
+	// lost to another worker in the meantime.
+	RetryJob(ctx context.Context, job Job, retryIn time.Duration) error
+	// FinishJob stores the Status, Result, ProviderUsed and Error of a job
+	// claimed at job.Attempts and makes its webhook due. It returns
+	// ErrNotFound if the lease was lost to another worker in the meantime.
+	FinishJob(ctx context.Context, job Job) error
+	// PruneJobs deletes jobs that finished more than olderThan ago and have
+	// no webhook delivery pending, and returns how many it deleted.
+	PruneJobs(ctx context.Context, olderThan time.Duration) (int64, error)
+	// ClaimWebhook leases the next due webhook delivery and counts the
+	// attempt. It returns ErrNotFound when none is due.
+	ClaimWebhook(ctx context.Context, lease time.Duration) (*Job, error)
+	// RecordWebhook stores the outcome of a delivery attempt. A pending
+	// status is retried after retryIn.
+	RecordWebhook(ctx context.Context, id string, status WebhookStatus, errMsg string, retryIn time.Duration) error
+}
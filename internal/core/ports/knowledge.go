@@ -0,0 +1,66 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// Collection groups documents that are searched together. Its vectors all
+// come from one embedding model, fixed when the collection is created.
+type Collection struct {
+	ID                string
+	UserID            string
+	Name              string
+	EmbeddingProvider string
+	EmbeddingModel    string
+	CreatedAt         time.Time
+}
+
+type Document struct {
+	ID           string
+	CollectionID string
+	Title        string
+	// ContentType is "text", "markdown" or "html".
+	ContentType string
+	Chunks      int
+	CreatedAt   time.Time
+}
+
+// Chunk is a retrievable piece of a document with its embedding.
+type Chunk struct {
+	ID            string
+	CollectionID  string
+	DocumentID    string
+	DocumentTitle string
+	Index         int
+	Text          string
+	Embedding     []float32
+}
+
+// ScoredChunk is a search hit; Score is the cosine similarity to the query.
+type ScoredChunk struct {
+	Chunk
+	Score float64
+}
+
+// Citation points an answer back at a chunk that was put in its prompt.
+// Index is the [n] marker used in the prompt.
+type Citation struct {
+	Index         int
+	DocumentID    string
+	DocumentTitle string
+	ChunkID       string
+	Score         float64
+	Text          string
+}
+
+// VectorIndex stores collections, their documents and chunk embeddings.
+type VectorIndex interface {
+	CreateCollection(ctx context.Context, c Collection) (*Collection, error)
+	GetCollection(ctx context.Context, id string) (*Collection, error)
+	// AddDocument stores doc and its chunks; chunk IDs and document fields
+	// are filled in by the index.
+	AddDocument(ctx context.Context, doc Document, chunks []Chunk) (*Document, error)
+	// Search returns the k chunks of the collection closest to query, best first.
+	Search(ctx context.Context, collectionID string, query []float32, k int) ([]ScoredChunk, error)
+}
@@ -2,18 +2,217 @@ package ports
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 )
 
+// CacheMode controls how a single request interacts with the response cache.
+type CacheMode string
+
+const (
+	// CacheDefault reads from the cache and stores fresh responses.
+	CacheDefault CacheMode = ""
+	// CacheBypass neither reads nor writes the cache.
+	CacheBypass CacheMode = "bypass"
+	// CacheRefresh skips the lookup but stores the fresh response.
+	CacheRefresh CacheMode = "refresh"
+	// CacheOnly answers from the cache and never calls a provider.
+	CacheOnly CacheMode = "only"
+)
+
+// HedgeMode controls whether a request is duplicated to a second provider.
+type HedgeMode string
+
+const (
+	HedgeOff HedgeMode = "off"
+	// HedgeDelay sends to the secondary only if the primary has not answered within Delay.
+	HedgeDelay HedgeMode = "delay"
+	// HedgeRace sends to both providers at once.
+	HedgeRace HedgeMode = "race"
+)
+
+// HedgePolicy trades extra provider spend for lower tail latency. The first
+// successful answer wins and the other call is cancelled.
+type HedgePolicy struct {
+	Mode      HedgeMode
+	Delay     time.Duration
+	Secondary string
+}
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	// RoleTool messages carry the result of a tool call back to the model.
+	RoleTool = "tool"
+)
+
+// PartType is the kind of a multimodal content part.
+type PartType string
+
+const (
+	PartText  PartType = "text"
+	PartImage PartType = "image"
+	// PartFile is a document such as a PDF.
+	PartFile PartType = "file"
+)
+
+// ContentPart is one piece of a multimodal message. Media is given either by
+// URL or inline as Data with its MIME type.
+type ContentPart struct {
+	Type     PartType
+	Text     string `json:",omitempty"`
+	URL      string `json:",omitempty"`
+	Data     []byte `json:",omitempty"`
+	MIMEType string `json:",omitempty"`
+	Filename string `json:",omitempty"`
+}
+
+// IsMedia reports whether the part is an image or file rather than text.
+func (p ContentPart) IsMedia() bool {
+	return p.Type == PartImage || p.Type == PartFile
+}
+
+// Message is one turn of a conversation.
+type Message struct {
+	Role    string
+	Content string
+	// Parts carries multimodal content. When set it replaces Content.
+	Parts []ContentPart `json:",omitempty"`
+	// ToolCalls are the calls an assistant turn asked for.
+	ToolCalls []ToolCall `json:",omitempty"`
+	// ToolCallID links a RoleTool message to the call it answers, and Name
+	// is the tool that produced it. Content holds the result.
+	ToolCallID string `json:",omitempty"`
+	Name       string `json:",omitempty"`
+}
+
+// Tool is a function the model may ask the caller to run.
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is a JSON Schema object describing the arguments.
+	Parameters json.RawMessage
+}
+
+// ToolCall is a model's request to run a tool.
+type ToolCall struct {
+	// ID is assigned by the provider (or the adapter when the provider has
+	// none) and must be echoed back in the result's ToolCallID.
+	ID   string
+	Name string
+	// Arguments is a JSON object matching the tool's Parameters schema.
+	Arguments json.RawMessage
+}
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call a tool.
+	ToolChoiceAuto = "auto"
+	// ToolChoiceNone forbids tool calls.
+	ToolChoiceNone = "none"
+	// ToolChoiceRequired forces at least one tool call.
+	ToolChoiceRequired = "required"
+)
+
+// ResponseFormatType selects between free text and JSON answers.
+type ResponseFormatType string
+
+const (
+	ResponseFormatText ResponseFormatType = "text"
+	// ResponseFormatJSONObject asks for any JSON object.
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	// ResponseFormatJSONSchema asks for a JSON value matching Schema.
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat constrains the shape of an answer.
+type ResponseFormat struct {
+	Type ResponseFormatType
+	// Name labels the schema for providers that require one.
+	Name   string
+	Schema json.RawMessage
+	// Repair retries once, showing the model its validation errors, when
+	// the answer does not parse or match the schema.
+	Repair bool
+}
+
 type LLMRequest struct {
-	UserID      string
-	Prompt      string
+	UserID string
+	// Model is a catalog alias or model ID; the service resolves it to the
+	// provider's upstream model ID before calling an adapter. Empty uses the
+	// provider's default model.
+	Model  string
+	Prompt string
+	// Messages carries a full conversation. When set, Prompt is ignored by
+	// adapters and only used for logging.
+	Messages    []Message
 	Temperature float32
 	MaxTokens   int32
+	CacheMode   CacheMode
+	CacheTTL    time.Duration
+	// Routing names the strategy used when no provider is requested; empty uses the configured default.
+	Routing string
+	// Hedge overrides the service's hedging policy for this request.
+	Hedge *HedgePolicy
+	// MaxCostUSD refuses the request when the estimated cost of its prompt
+	// and MaxTokens of output is higher; 0 means no limit.
+	MaxCostUSD float64
+	// Tools the model may call, and how: "auto" (the default), "none",
+	// "required" or the name of one tool to force.
+	Tools      []Tool
+	ToolChoice string
+	// ResponseFormat requests JSON output; nil means free text.
+	ResponseFormat *ResponseFormat
+	// TraceID and TraceStep group the calls of one agent run in request logs.
+	TraceID   string
+	TraceStep int
+	// CollectionID grounds the answer on a knowledge collection; the top
+	// RetrievalTopK chunks are added to the prompt (0 uses the server default).
+	CollectionID  string
+	RetrievalTopK int
+	// Template renders the prompt from a stored template instead of Prompt.
+	Template *TemplateRef
+}
+
+// Conversation returns the messages to send: Messages when set, otherwise
+// Prompt as a single user message.
+func (r LLMRequest) Conversation() []Message {
+	if len(r.Messages) > 0 {
+		return r.Messages
+	}
+	return []Message{{Role: RoleUser, Content: r.Prompt}}
+}
+
+// HasMedia reports whether any message carries an image or file part.
+func (r LLMRequest) HasMedia() bool {
+	for _, m := range r.Messages {
+		for _, p := range m.Parts {
+			if p.IsMedia() {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 type LLMResponse struct {
 	Content string
-	Usage   *UsageInfo
+	// ToolCalls holds the tools the model wants run before it can answer.
+	ToolCalls []ToolCall
+	// JSON is the validated answer for JSON response formats, with any
+	// markdown fencing around Content removed.
+	JSON  json.RawMessage
+	Usage *UsageInfo
+	// FinishReason is "stop", "length", "content_filter" or "tool_calls" when the provider reports one.
+	FinishReason string
+	// Model is the upstream model that produced the answer, as reported by the provider.
+	Model string
+	// Citations lists the knowledge chunks put in the prompt, numbered as
+	// the answer cites them.
+	Citations []Citation
+	// Fingerprint identifies the prompt as sent, after templates and
+	// retrieval; it is set by the service and keys the cached answer.
+	Fingerprint string
 }
 
 type UsageInfo struct {
@@ -21,9 +220,21 @@ type UsageInfo struct {
 	CompletionTokens int32
 	TotalTokens      int32
 	CostUSD          float64
+	// CachedTokens is the part of PromptTokens served from the provider's prompt cache.
+	CachedTokens int32
 }
 
 type LLMProvider interface {
+	// Generate answers req. A call that fails after the provider has billed
+	// for it, such as one cancelled mid-generation, may return a response
+	// holding the Usage incurred alongside the error.
 	Generate(ctx context.Context, req LLMRequest) (*LLMResponse, error)
 	Name() string
 }
+
+// StreamingProvider is implemented by providers that can deliver an answer
+// incrementally. onChunk is called with each content delta; the returned
+// response holds the full content and usage once the stream has finished.
+type StreamingProvider interface {
+	GenerateStream(ctx context.Context, req LLMRequest, onChunk func(delta string) error) (*LLMResponse, error)
+}
@@ -0,0 +1,31 @@
+package ports
+
+import "time"
+
+// RequestMetric describes one finished generation.
+type RequestMetric struct {
+	// Provider is the provider that answered, or CachedProvider's name for
+	// cache hits. It is empty for requests rejected before routing.
+	Provider string
+	Model    string
+	Success  bool
+	CacheHit bool
+	Duration time.Duration
+	// Usage is nil for failures and cache hits.
+	Usage *UsageInfo
+}
+
+// Metrics records what the service does for monitoring. Implementations
+// must be safe for concurrent use.
+type Metrics interface {
+	ObserveRequest(m RequestMetric)
+	// AddInFlight adjusts the number of generations in progress.
+	AddInFlight(delta int)
+	ObserveCacheLookup(hit bool)
+	// ObserveRateLimited counts a call a provider refused with
+	// ErrRateLimited.
+	ObserveRateLimited(provider string)
+	// AddPendingLogs adjusts the number of request logs waiting to be
+	// written.
+	AddPendingLogs(delta int)
+}
@@ -9,6 +9,7 @@ type RequestLog struct {
 	ID               string
 	Prompt           string
 	Provider         string
+	Model            string
 	Response         string
 	DurationMs       int64
 	UserID           string
@@ -16,7 +17,37 @@ type RequestLog struct {
 	CompletionTokens int32
 	TotalTokens      int32
 	CostUSD          float64
-	CreatedAt        time.Time
+	RoutingStrategy  string
+	RoutingReason    string
+	// HedgeRole is "primary" or "secondary" for hedged calls and empty otherwise.
+	HedgeRole   string
+	HedgeWinner bool
+	Error       string
+	// TraceID groups the model calls and tool invocations of one agent run;
+	// TraceStep numbers the model call they belong to.
+	TraceID   string
+	TraceStep int
+	// Attachments describes the request's images and files; their bytes are
+	// never stored.
+	Attachments []AttachmentMeta
+	// TemplateName and TemplateVersion identify the prompt template the
+	// request was rendered from, if any.
+	TemplateName    string
+	TemplateVersion int
+	// RequestID is the X-Request-ID of the API call that caused the log.
+	RequestID string
+	CreatedAt time.Time
+}
+
+// AttachmentMeta identifies an attachment without keeping its content.
+type AttachmentMeta struct {
+	Type     string `json:"type"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	// URL is set for media passed by reference; Size and SHA256 for inline data.
+	URL    string `json:"url,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 type User struct {
@@ -25,13 +56,36 @@ type User struct {
 	CreatedAt time.Time
 }
 
+// APIKey is a gateway credential issued to a user. Only a hash of the
+// secret is stored; Prefix keeps keys recognisable in listings.
+type APIKey struct {
+	ID        string
+	UserID    string
+	Name      string
+	Prefix    string
+	CreatedAt time.Time
+}
+
 type Repository interface {
 	LogRequest(ctx context.Context, log RequestLog) error
 	CreateUser(ctx context.Context, name string) (*User, error)
 	GetUser(ctx context.Context, id string) (*User, error)
+	CreateAPIKey(ctx context.Context, key APIKey, keyHash string) (*APIKey, error)
+	// GetUserByAPIKey returns the owner of the key with the given hash.
+	GetUserByAPIKey(ctx context.Context, keyHash string) (*User, error)
 }
 
+// Cache stores generated responses. Entries can be tagged on write so that
+// related keys (for example everything cached for one user) can later be
+// invalidated together without scanning the keyspace.
 type Cache interface {
 	Get(ctx context.Context, key string) (string, error)
-	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Set(ctx context.Context, key string, value string, ttl time.Duration, tags ...string) error
+	Delete(ctx context.Context, keys ...string) (int64, error)
+	// DeletePrefix removes every key starting with prefix and reports how many were deleted.
+	DeletePrefix(ctx context.Context, prefix string) (int64, error)
+	// InvalidateTag removes every key written with tag and reports how many were deleted.
+	InvalidateTag(ctx context.Context, tag string) (int64, error)
+	// Count reports how many keys start with prefix.
+	Count(ctx context.Context, prefix string) (int64, error)
 }
@@ -0,0 +1,56 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// PromptTemplate is one immutable version of a named prompt. System and Body
+// are text/template sources rendered with the request's variables; System is
+// optional and becomes a system message, Body becomes the user message.
+type PromptTemplate struct {
+	Name        string
+	Version     int
+	Description string
+	System      string
+	Body        string
+	Variables   []TemplateVariable
+	CreatedAt   time.Time
+}
+
+// TemplateVariable declares a value a template expects.
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+	// Enum restricts the value to one of the listed strings when set.
+	Enum []string `json:"enum,omitempty"`
+}
+
+// TemplateRef selects a template version and the values to render it with.
+// Version 0 means the latest version.
+type TemplateRef struct {
+	Name      string
+	Version   int
+	Variables map[string]any
+	// Rendered is set once the template has been rendered into the
+	// request's prompt, so later steps of an agent run keep it as is.
+	Rendered bool
+}
+
+// TemplateStore keeps prompt templates. Versions are never changed once
+// written; saving a template again adds a version.
+type TemplateStore interface {
+	// CreateTemplateVersion stores t as the next version of t.Name and
+	// returns it with Version and CreatedAt set.
+	CreateTemplateVersion(ctx context.Context, t PromptTemplate) (*PromptTemplate, error)
+	// GetTemplate returns a version of name, or the latest when version is 0.
+	GetTemplate(ctx context.Context, name string, version int) (*PromptTemplate, error)
+	// ListTemplates returns the latest version of every template.
+	ListTemplates(ctx context.Context) ([]PromptTemplate, error)
+	// ListTemplateVersions returns every version of name, oldest first.
+	ListTemplateVersions(ctx context.Context, name string) ([]PromptTemplate, error)
+	// DeleteTemplate removes every version of name.
+	DeleteTemplate(ctx context.Context, name string) error
+}
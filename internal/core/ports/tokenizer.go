@@ -0,0 +1,20 @@
+package ports
+
+// TokenCount is the number of tokens a model would see in a text.
+type TokenCount struct {
+	Tokens int
+	// Encoding names how the count was made: a BPE encoding such as
+	// "o200k_base", or "estimate" for a heuristic.
+	Encoding string
+	// Exact is set when Encoding is the one the model is known to use.
+	Exact bool
+}
+
+// Tokenizer counts tokens before a request is sent. Models are named by
+// provider and upstream model ID.
+type Tokenizer interface {
+	Count(provider, model, text string) TokenCount
+	// Truncate returns the longest prefix of text of at most maxTokens
+	// tokens.
+	Truncate(provider, model, text string, maxTokens int) string
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/tools"
+)
+
+const (
+	defaultAgentMaxSteps = 5
+
+	// AgentStopFinal means the model answered without asking for more tools.
+	AgentStopFinal = "final"
+	// AgentStopMaxSteps means the run hit its step cap with tool calls pending.
+	AgentStopMaxSteps = "max_steps"
+	// AgentStopBudget means the run hit its cost cap with tool calls pending.
+	AgentStopBudget = "budget"
+)
+
+// ErrToolsNotConfigured is returned for agent runs when no tool registry is set.
+var ErrToolsNotConfigured = ports.NewError(ports.ErrNotConfigured, "agent tools not configured")
+
+// WithTools sets the registry of server-side tools agent runs may call.
+func WithTools(r *tools.Registry) Option {
+	return func(s *LLMService) {
+		s.tools = r
+	}
+}
+
+// AgentOptions narrows an agent run. Limits can only be lowered below the
+// service's configured caps.
+type AgentOptions struct {
+	MaxSteps   int
+	MaxCostUSD float64
+	// Tools names the registry tools to offer; empty offers all of them.
+	Tools []string
+}
+
+// AgentStep is one model call of an agent run and the tools it ran.
+type AgentStep struct {
+	Step        int
+	Provider    string
+	Content     string
+	ToolCalls   []ports.ToolCall
+	ToolResults []ports.Message
+	Usage       *ports.UsageInfo
+}
+
+type AgentResult struct {
+	TraceID string
+	// Response is the last model response: the final answer, or the pending
+	// tool calls when the run was stopped by a limit.
+	Response   *ports.LLMResponse
+	Provider   string
+	Steps      []AgentStep
+	Usage      ports.UsageInfo
+	StopReason string
+}
+
+// RunAgent answers req by looping model → tool calls → tool results until
+// the model stops asking for tools or a step or budget cap is reached. Every
+// model call and tool invocation is logged under one trace ID. Agent steps
+// bypass the response cache since they depend on live tool output.
+func (s *LLMService) RunAgent(ctx context.Context, req ports.LLMRequest, providerName string, opts AgentOptions) (*AgentResult, error) {
+	if s.tools == nil {
+		return nil, ErrToolsNotConfigured
+	}
+	if len(req.Tools) > 0 {
+		return nil, fmt.Errorf("%w: agent runs only use server tools", ErrInvalidTools)
+	}
+	defs, err := s.tools.Definitions(opts.Tools...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTools, err)
+	}
+	// Only the tools offered to the model may run, whatever it asks for.
+	offered := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		offered[def.Name] = true
+	}
+
+	maxSteps := s.agentMaxSteps
+	if opts.MaxSteps > 0 && opts.MaxSteps < maxSteps {
+		maxSteps = opts.MaxSteps
+	}
+	maxCost := s.agentMaxCost
+	if opts.MaxCostUSD > 0 && (maxCost == 0 || opts.MaxCostUSD < maxCost) {
+		maxCost = opts.MaxCostUSD
+	}
+
+	// The template is rendered once; later steps extend its messages.
+	req, err = s.applyTemplate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	req.Tools = defs
+	req.Messages = req.Conversation()
+	req.CacheMode = ports.CacheBypass
+	req.TraceID = newTraceID()
+
+	result := &AgentResult{TraceID: req.TraceID}
+	for step := 1; ; step++ {
+		req.TraceStep = step
+		if maxCost > 0 {
+			// Each step may only spend what earlier steps left over.
+			req.MaxCostUSD = maxCost - result.Usage.CostUSD
+		}
+		resp, provider, err := s.ProcessRequest(ctx, req, providerName)
+		if errors.Is(err, ErrOverBudget) && result.Response != nil {
+			// The tool results gathered so far would cost too much to send
+			// back; stop with the calls of the last step pending.
+			result.StopReason = AgentStopBudget
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("agent step %d: %w", step, err)
+		}
+		result.Response = resp
+		result.Provider = provider
+		if resp.Usage != nil {
+			result.Usage.PromptTokens += resp.Usage.PromptTokens
+			result.Usage.CompletionTokens += resp.Usage.CompletionTokens
+			result.Usage.TotalTokens += resp.Usage.TotalTokens
+			result.Usage.CostUSD += resp.Usage.CostUSD
+		}
+		current := AgentStep{Step: step, Provider: provider, Content: resp.Content, ToolCalls: resp.ToolCalls, Usage: resp.Usage}
+
+		switch {
+		case len(resp.ToolCalls) == 0:
+			result.StopReason = AgentStopFinal
+		case step >= maxSteps:
+			result.StopReason = AgentStopMaxSteps
+		case maxCost > 0 && result.Usage.CostUSD >= maxCost:
+			result.StopReason = AgentStopBudget
+		}
+		if result.StopReason != "" {
+			result.Steps = append(result.Steps, current)
+			return result, nil
+		}
+
+		req.Messages = append(req.Messages, ports.Message{Role: ports.RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			msg := s.invokeTool(ctx, req, call, offered)
+			current.ToolResults = append(current.ToolResults, msg)
+			req.Messages = append(req.Messages, msg)
+		}
+		result.Steps = append(result.Steps, current)
+	}
+}
+
+// invokeTool runs one tool call, if it names an offered tool, and logs it.
+// Failures are reported to the model as the tool result so it can correct
+// itself.
+func (s *LLMService) invokeTool(ctx context.Context, req ports.LLMRequest, call ports.ToolCall, offered map[string]bool) ports.Message {
+	start := time.Now()
+	var output string
+	var err error
+	if tool, ok := s.tools.Get(call.Name); ok && offered[call.Name] {
+		output, err = tool.Invoke(ctx, call.Arguments)
+	} else {
+		err = fmt.Errorf("%w: %q", tools.ErrUnknownTool, call.Name)
+	}
+
+	log := ports.RequestLog{
+		Prompt:     string(call.Arguments),
+		Provider:   "tool",
+		Model:      call.Name,
+		Response:   output,
+		DurationMs: time.Since(start).Milliseconds(),
+		UserID:     req.UserID,
+		TraceID:    req.TraceID,
+		TraceStep:  req.TraceStep,
+		CreatedAt:  time.Now(),
+	}
+	if err != nil {
+		log.Error = err.Error()
+		output = "error: " + err.Error()
+	}
+	s.logRequest(ctx, log)
+
+	return ports.Message{Role: ports.RoleTool, ToolCallID: call.ID, Name: call.Name, Content: output}
+}
+
+func newTraceID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/tools"
+)
+
+// agentProvider asks for tool until it has seen rounds results, then
+// answers with the last one.
+func agentProvider(rounds int, tool string) *mockProvider {
+	return &mockProvider{name: "agent", respond: func(_ int, req ports.LLMRequest) (*ports.LLMResponse, error) {
+		var results []ports.Message
+		for _, m := range req.Messages {
+			if m.Role == ports.RoleTool {
+				results = append(results, m)
+			}
+		}
+		usage := &ports.UsageInfo{TotalTokens: 10, CostUSD: 0.01}
+		if len(results) >= rounds {
+			return &ports.LLMResponse{Content: "The answer is " + results[len(results)-1].Content, FinishReason: "stop", Usage: usage}, nil
+		}
+		return &ports.LLMResponse{
+			ToolCalls:    []ports.ToolCall{{ID: "call_1", Name: tool, Arguments: json.RawMessage(`{"expression":"6 * 7"}`)}},
+			FinishReason: "tool_calls",
+			Usage:        usage,
+		}, nil
+	}}
+}
+
+func TestLLMService_RunAgent(t *testing.T) {
+	repo := newTestRepo(t)
+	registry := tools.NewRegistry(tools.Calculator{}, tools.Clock{})
+	ctx := context.Background()
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "What is 6 * 7?"}
+
+	svc := NewLLMService(&config.Config{}, repo, nil, WithTools(registry), WithProvider("agent", agentProvider(1, "calculator")))
+	result, err := svc.RunAgent(ctx, req, "agent", AgentOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StopReason != AgentStopFinal || result.Response.Content != "The answer is 42" {
+		t.Fatalf("unexpected result: %s %q", result.StopReason, result.Response.Content)
+	}
+	if len(result.Steps) != 2 || len(result.Steps[0].ToolResults) != 1 {
+		t.Fatalf("expected a tool step then an answer, got %+v", result.Steps)
+	}
+	if result.Usage.TotalTokens != 20 {
+		t.Fatalf("expected usage summed over steps, got %d", result.Usage.TotalTokens)
+	}
+
+	// Two model calls and one tool invocation, all under the run's trace.
+	for i := 0; i < 3; i++ {
+		select {
+		case log := <-repo.logs:
+			if log.TraceID != result.TraceID || log.TraceStep == 0 {
+				t.Fatalf("expected log under trace %s, got %q step %d", result.TraceID, log.TraceID, log.TraceStep)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 logs, got %d", i)
+		}
+	}
+
+	svc = NewLLMService(&config.Config{Agent: config.AgentConfig{MaxSteps: 3}}, repo, nil, WithTools(registry), WithProvider("agent", agentProvider(10, "calculator")))
+	result, err = svc.RunAgent(ctx, req, "agent", AgentOptions{MaxSteps: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StopReason != AgentStopMaxSteps || len(result.Steps) != 3 {
+		t.Fatalf("expected the configured cap of 3 steps to win, got %s after %d", result.StopReason, len(result.Steps))
+	}
+
+	result, err = svc.RunAgent(ctx, req, "agent", AgentOptions{MaxCostUSD: 0.015})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StopReason != AgentStopBudget || len(result.Steps) != 2 {
+		t.Fatalf("expected budget stop after 2 steps, got %s after %d", result.StopReason, len(result.Steps))
+	}
+
+	// A registered tool that was not offered is refused, not run.
+	svc = NewLLMService(&config.Config{}, repo, nil, WithTools(registry), WithProvider("agent", agentProvider(1, "clock")))
+	result, err = svc.RunAgent(ctx, req, "agent", AgentOptions{Tools: []string{"calculator"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Steps[0].ToolResults[0].Content; !strings.Contains(got, tools.ErrUnknownTool.Error()) {
+		t.Fatalf("expected the clock to be refused, got %q", got)
+	}
+
+	if _, err := svc.RunAgent(ctx, req, "agent", AgentOptions{Tools: []string{"teleport"}}); !errors.Is(err, ErrInvalidTools) {
+		t.Fatalf("expected invalid tools error, got %v", err)
+	}
+	svc = NewLLMService(&config.Config{}, repo, nil)
+	if _, err := svc.RunAgent(ctx, req, "agent", AgentOptions{}); !errors.Is(err, ErrToolsNotConfigured) {
+		t.Fatalf("expected tools not configured error, got %v", err)
+	}
+}
+
+func TestLLMService_RunAgentTemplate(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil,
+		WithTools(tools.NewRegistry(tools.Calculator{})),
+		WithProvider("agent", agentProvider(1, "calculator")),
+	)
+	templates := NewTemplateService(svc, &memoryTemplates{})
+	ctx := context.Background()
+	if _, err := templates.CreateTemplate(ctx, ports.PromptTemplate{
+		Name:      "math",
+		System:    "Use the calculator.",
+		Body:      "What is {{.expr}}?",
+		Variables: []ports.TemplateVariable{{Name: "expr", Required: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := ports.LLMRequest{UserID: "user-123", Template: &ports.TemplateRef{Name: "math", Variables: map[string]any{"expr": "6 * 7"}}}
+	result, err := svc.RunAgent(ctx, req, "agent", AgentOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StopReason != AgentStopFinal || len(result.Steps) != 2 {
+		t.Fatalf("expected a tool step then an answer, got %s after %d", result.StopReason, len(result.Steps))
+	}
+	// Two model calls and one tool invocation; the model calls name the template.
+	for i := 0; i < 3; i++ {
+		select {
+		case log := <-repo.logs:
+			if log.Provider != "tool" && (log.TemplateName != "math" || log.TemplateVersion != 1) {
+				t.Fatalf("expected model calls logged under math v1, got %q v%d", log.TemplateName, log.TemplateVersion)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 logs, got %d", i)
+		}
+	}
+
+	req.Template = &ports.TemplateRef{Name: "math"}
+	if _, err := svc.RunAgent(ctx, req, "agent", AgentOptions{}); !errors.Is(err, ErrInvalidVariables) {
+		t.Fatalf("expected missing variables to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// apiKeyPrefix marks gateway keys so they are easy to tell apart from provider keys.
+const apiKeyPrefix = "nx-"
+
+// ErrInvalidAPIKey is returned when a presented gateway key is unknown.
+var ErrInvalidAPIKey = ports.NewError(ports.ErrUnauthenticated, "invalid api key")
+
+// CreateAPIKey issues a new gateway key for userID. The plaintext key is only
+// returned here; afterwards the gateway can verify it but not show it again.
+func (s *LLMService) CreateAPIKey(ctx context.Context, userID, name string) (string, *ports.APIKey, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key, err := s.repo.CreateAPIKey(ctx, ports.APIKey{
+		UserID: userID,
+		Name:   name,
+		Prefix: plaintext[:len(apiKeyPrefix)+6],
+	}, hashAPIKey(plaintext))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store api key: %w", err)
+	}
+	return plaintext, key, nil
+}
+
+// AuthenticateAPIKey resolves a gateway key to the user it was issued to.
+func (s *LLMService) AuthenticateAPIKey(ctx context.Context, key string) (*ports.User, error) {
+	if s.repo == nil {
+		return nil, ErrUsersNotConfigured
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	user, err := s.repo.GetUserByAPIKey(ctx, hashAPIKey(key))
+	if errors.Is(err, ports.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	return user, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
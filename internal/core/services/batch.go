@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const defaultBatchConcurrency = 8
+
+// BatchItem is one request of a batch.
+type BatchItem struct {
+	Request  ports.LLMRequest
+	Provider string
+}
+
+// BatchResult is the outcome of the item at Index.
+type BatchResult struct {
+	Index    int
+	Response *ports.LLMResponse
+	Provider string
+	Err      error
+	Duration time.Duration
+}
+
+// BatchOptions bound how hard a batch may push the providers.
+type BatchOptions struct {
+	// Concurrency is the number of requests in flight at once.
+	Concurrency int
+	// RateLimits caps requests per minute for each named provider. Limits
+	// apply to the provider actually called, including routed requests,
+	// hedges and repairs.
+	RateLimits map[string]int
+}
+
+// RunBatch processes items like ProcessRequest, at most opts.Concurrency at
+// a time. done is called once per item as it finishes, never concurrently;
+// when it returns an error the batch stops and that error is returned.
+func (s *LLMService) RunBatch(ctx context.Context, items []BatchItem, opts BatchOptions, done func(BatchResult) error) error {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if len(opts.RateLimits) > 0 {
+		limiters := make(map[string]*rate.Limiter, len(opts.RateLimits))
+		for name, perMinute := range opts.RateLimits {
+			if perMinute > 0 {
+				limiters[name] = rate.NewLimiter(rate.Limit(float64(perMinute)/60), 1)
+			}
+		}
+		ctx = context.WithValue(ctx, rateLimitsKey{}, limiters)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range min(concurrency, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				start := time.Now()
+				resp, provider, err := s.ProcessRequest(ctx, items[i].Request, items[i].Provider)
+				result := BatchResult{Index: i, Response: resp, Provider: provider, Err: err, Duration: time.Since(start)}
+				mu.Lock()
+				// Items cut short by a stop are not reported.
+				if ctx.Err() == nil {
+					if err := done(result); err != nil {
+						cancel(err)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range items {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+// rateLimitsKey carries a batch's per-provider limiters to generate.
+type rateLimitsKey struct{}
+
+// waitForRateLimit blocks until the batch in ctx, if any, may call provider.
+func waitForRateLimit(ctx context.Context, provider string) error {
+	limiters, _ := ctx.Value(rateLimitsKey{}).(map[string]*rate.Limiter)
+	if l := limiters[provider]; l != nil {
+		return l.Wait(ctx)
+	}
+	return nil
+}
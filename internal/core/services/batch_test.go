@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestLLMService_RunBatch(t *testing.T) {
+	repo := newTestRepo(t)
+	provider := &mockProvider{name: "counting", delay: 10 * time.Millisecond, respond: replies("ok")}
+	svc := NewLLMService(&config.Config{}, repo, nil, WithProvider("counting", provider))
+
+	items := make([]BatchItem, 12)
+	for i := range items {
+		items[i] = BatchItem{Request: ports.LLMRequest{UserID: "user-123", Prompt: "hi"}, Provider: "counting"}
+	}
+	items[5].Request.UserID = ""
+
+	var mu sync.Mutex
+	seen := make(map[int]BatchResult)
+	err := svc.RunBatch(context.Background(), items, BatchOptions{Concurrency: 3}, func(r BatchResult) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.Index] = r
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != len(items) {
+		t.Fatalf("expected %d results, got %d", len(items), len(seen))
+	}
+	if seen[5].Err == nil || seen[0].Err != nil || seen[0].Response.Content != "ok" {
+		t.Fatalf("unexpected results: %+v / %+v", seen[0], seen[5])
+	}
+	if peak := provider.peak.Load(); peak > 3 {
+		t.Fatalf("expected at most 3 requests in flight, saw %d", peak)
+	}
+
+	// 1200 a minute is one every 50ms, so five calls take at least 200ms
+	// however many workers there are.
+	start := time.Now()
+	err = svc.RunBatch(context.Background(), items[:5], BatchOptions{Concurrency: 5, RateLimits: map[string]int{"counting": 1200}}, func(BatchResult) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Fatalf("expected the rate limit to spread calls out, took %v", elapsed)
+	}
+
+	// An error from done stops the batch.
+	stop := errors.New("disk full")
+	provider.calls.Store(0)
+	err = svc.RunBatch(context.Background(), items, BatchOptions{Concurrency: 1}, func(BatchResult) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected the done error, got %v", err)
+	}
+	if calls := provider.calls.Load(); calls > 2 {
+		t.Fatalf("expected the batch to stop early, made %d calls", calls)
+	}
+}
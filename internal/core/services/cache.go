@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	// cacheKeyPrefix namespaces response entries: llm:<provider>:<user>:<fingerprint>.
+	cacheKeyPrefix = "llm:"
+	// autoProvider is the provider segment used when the caller let the service choose.
+	autoProvider    = "auto"
+	defaultCacheTTL = 1 * time.Hour
+
+	// CachedProvider is reported as the provider for responses served from the cache.
+	CachedProvider = "cache"
+)
+
+var (
+	// ErrCacheMiss is returned for cache-only requests that have no stored answer.
+	ErrCacheMiss = ports.NewError(ports.ErrNotFound, "no cached response")
+	// ErrInvalidCacheMode is returned when a request carries an unknown cache mode.
+	ErrInvalidCacheMode = ports.NewError(ports.ErrInvalidArgument, "invalid cache mode")
+	// ErrCacheNotConfigured is returned by cache administration calls without a cache.
+	ErrCacheNotConfigured = ports.NewError(ports.ErrNotConfigured, "cache not configured")
+	// ErrEmptyPurge is returned when a purge names neither user, provider nor fingerprint.
+	ErrEmptyPurge = ports.NewError(ports.ErrInvalidArgument, "purge requires user_id, provider or fingerprint")
+)
+
+// CacheStats summarises how the response cache has performed since startup.
+type CacheStats struct {
+	Enabled           bool
+	Hits              int64
+	Misses            int64
+	HitRate           float64
+	Entries           int64
+	EntriesByProvider map[string]int64
+}
+
+// CachePurge selects cached responses to remove. Set fields are combined, so
+// a purge with both UserID and Provider only removes that user's entries for
+// that provider.
+type CachePurge struct {
+	UserID      string
+	Provider    string
+	Fingerprint string
+}
+
+// Fingerprint identifies the content of a request independently of the user
+// and provider, so the same question can be purged everywhere it was cached.
+func Fingerprint(req ports.LLMRequest) string {
+	payload, _ := json.Marshal(struct {
+		Model       string          `json:"model"`
+		Messages    []ports.Message `json:"messages"`
+		Temperature float32         `json:"temperature"`
+		MaxTokens   int32           `json:"max_tokens"`
+		Tools       []ports.Tool    `json:"tools,omitempty"`
+		ToolChoice  string          `json:"tool_choice,omitempty"`
+		Format      any             `json:"response_format,omitempty"`
+	}{req.Model, req.Conversation(), req.Temperature, req.MaxTokens, req.Tools, req.ToolChoice, formatKey(req.ResponseFormat)})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// formatKey is the part of a response format that changes the answer; the
+// repair flag only changes how a bad answer is handled.
+func formatKey(format *ports.ResponseFormat) any {
+	if format == nil || format.Type == "" || format.Type == ports.ResponseFormatText {
+		return nil
+	}
+	return struct {
+		Type   ports.ResponseFormatType `json:"type"`
+		Name   string                   `json:"name,omitempty"`
+		Schema json.RawMessage          `json:"schema,omitempty"`
+	}{format.Type, format.Name, format.Schema}
+}
+
+func cacheKey(provider, userID, fingerprint string) string {
+	return fmt.Sprintf("%s%s:%s:%s", cacheKeyPrefix, cacheProvider(provider), userID, fingerprint)
+}
+
+func cacheProvider(provider string) string {
+	if provider == "" {
+		return autoProvider
+	}
+	return provider
+}
+
+// cacheTags are matched exactly, unlike key prefixes, so they select a
+// user's entries even when user IDs contain the key's ":" separator.
+func cacheTags(provider, userID, fingerprint string) []string {
+	return []string{
+		"user:" + userID,
+		"provider:" + cacheProvider(provider) + ":user:" + userID,
+		"fingerprint:" + fingerprint,
+		"fingerprint:" + cacheProvider(provider) + ":" + fingerprint,
+	}
+}
+
+func validCacheMode(mode ports.CacheMode) bool {
+	switch mode {
+	case ports.CacheDefault, ports.CacheBypass, ports.CacheRefresh, ports.CacheOnly:
+		return true
+	}
+	return false
+}
+
+func (s *LLMService) lookupCache(ctx context.Context, key string) (string, bool) {
+	ctx, span := tracer.Start(ctx, "LLMService.lookupCache")
+	cached, err := s.cache.Get(ctx, key)
+	hit := err == nil && cached != ""
+	span.SetAttributes(attribute.Bool("cache.hit", hit))
+	endSpan(span, err)
+	s.metrics.ObserveCacheLookup(hit)
+	if !hit {
+		s.cacheMisses.Add(1)
+		return "", false
+	}
+	s.cacheHits.Add(1)
+	return cached, true
+}
+
+// cacheTTL resolves the expiry for a request, honouring a caller-supplied TTL
+// up to the configured maximum.
+func (s *LLMService) cacheTTL(req ports.LLMRequest) time.Duration {
+	if req.CacheTTL <= 0 {
+		return s.defaultTTL
+	}
+	if s.maxTTL > 0 && req.CacheTTL > s.maxTTL {
+		return s.maxTTL
+	}
+	return req.CacheTTL
+}
+
+// CacheStats reports hit/miss counters and the number of stored entries.
+func (s *LLMService) CacheStats(ctx context.Context) (*CacheStats, error) {
+	hits, misses := s.cacheHits.Load(), s.cacheMisses.Load()
+	stats := &CacheStats{
+		Enabled:           s.cache != nil,
+		Hits:              hits,
+		Misses:            misses,
+		EntriesByProvider: make(map[string]int64),
+	}
+	if hits+misses > 0 {
+		stats.HitRate = float64(hits) / float64(hits+misses)
+	}
+	if s.cache == nil {
+		return stats, nil
+	}
+
+	total, err := s.cache.Count(ctx, cacheKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count cache entries: %w", err)
+	}
+	stats.Entries = total
+	for _, name := range s.cacheProviderNames() {
+		n, err := s.cache.Count(ctx, cacheKeyPrefix+name+":")
+		if err != nil {
+			return nil, fmt.Errorf("failed to count cache entries for %s: %w", name, err)
+		}
+		stats.EntriesByProvider[name] = n
+	}
+	return stats, nil
+}
+
+// PurgeCache removes cached responses matching p and reports how many were deleted.
+func (s *LLMService) PurgeCache(ctx context.Context, p CachePurge) (int64, error) {
+	if s.cache == nil {
+		return 0, ErrCacheNotConfigured
+	}
+
+	switch {
+	case p.Provider != "" && p.UserID != "" && p.Fingerprint != "":
+		return s.cache.Delete(ctx, cacheKey(p.Provider, p.UserID, p.Fingerprint))
+	case p.Provider != "" && p.UserID != "":
+		return s.cache.InvalidateTag(ctx, "provider:"+p.Provider+":user:"+p.UserID)
+	case p.Provider != "" && p.Fingerprint != "":
+		return s.cache.InvalidateTag(ctx, "fingerprint:"+p.Provider+":"+p.Fingerprint)
+	case p.Provider != "":
+		return s.cache.DeletePrefix(ctx, cacheKeyPrefix+p.Provider+":")
+	case p.UserID != "" && p.Fingerprint != "":
+		var deleted int64
+		for _, name := range s.cacheProviderNames() {
+			n, err := s.PurgeCache(ctx, CachePurge{Provider: name, UserID: p.UserID, Fingerprint: p.Fingerprint})
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		return deleted, nil
+	case p.UserID != "":
+		return s.cache.InvalidateTag(ctx, "user:"+p.UserID)
+	case p.Fingerprint != "":
+		return s.cache.InvalidateTag(ctx, "fingerprint:"+p.Fingerprint)
+	}
+	return 0, ErrEmptyPurge
+}
+
+// cacheProviderNames lists every provider segment that can appear in a cache key.
+func (s *LLMService) cacheProviderNames() []string {
+	names := []string{autoProvider}
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
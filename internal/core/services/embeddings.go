@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	// embeddingKeyPrefix namespaces embedding entries: emb:<provider>:<hash>.
+	embeddingKeyPrefix = "emb:"
+	// maxEmbeddingInputs caps the inputs of one request; providers batch below it.
+	maxEmbeddingInputs = 2048
+)
+
+var (
+	// ErrEmbeddingsNotConfigured is returned when no provider can serve embeddings.
+	ErrEmbeddingsNotConfigured = ports.NewError(ports.ErrNotConfigured, "embeddings not configured")
+	// ErrInvalidEmbeddingRequest is returned for empty or oversized input lists.
+	ErrInvalidEmbeddingRequest = ports.NewError(ports.ErrInvalidArgument, "invalid embedding request")
+)
+
+// WithEmbeddingProvider registers an embedding provider under name, taking
+// precedence over the chat provider of the same name.
+func WithEmbeddingProvider(name string, p ports.EmbeddingProvider) Option {
+	return func(s *LLMService) {
+		s.embedders[name] = p
+	}
+}
+
+// Embed returns one vector per input. Vectors are cached by provider, model
+// and input hash; only the inputs missing from the cache are sent upstream,
+// in a single batched call. The provider name is CachedProvider when every
+// input was cached.
+func (s *LLMService) Embed(ctx context.Context, req ports.EmbeddingRequest, providerName string) (*ports.EmbeddingResponse, string, error) {
+	if err := s.ensureUser(ctx, req.UserID); err != nil {
+		return nil, "", err
+	}
+	if len(req.Inputs) == 0 {
+		return nil, "", fmt.Errorf("%w: input is required", ErrInvalidEmbeddingRequest)
+	}
+	if len(req.Inputs) > maxEmbeddingInputs {
+		return nil, "", fmt.Errorf("%w: at most %d inputs per request", ErrInvalidEmbeddingRequest, maxEmbeddingInputs)
+	}
+	for i, input := range req.Inputs {
+		if strings.TrimSpace(input) == "" {
+			return nil, "", fmt.Errorf("%w: input %d is empty", ErrInvalidEmbeddingRequest, i)
+		}
+	}
+
+	name, embedder, err := s.embedder(providerName)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.Model == "" {
+		req.Model = s.embeddingModels[name]
+	}
+
+	resp := &ports.EmbeddingResponse{Model: req.Model, Vectors: make([][]float32, len(req.Inputs)), Usage: &ports.UsageInfo{}}
+	keys := make([]string, len(req.Inputs))
+	var missing []int
+	for i, input := range req.Inputs {
+		keys[i] = embeddingKey(name, req.Model, req.Dimensions, input)
+		if vector, ok := s.cachedEmbedding(ctx, keys[i]); ok {
+			resp.Vectors[i] = vector
+			continue
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return resp, CachedProvider, nil
+	}
+
+	upstream := req
+	upstream.Inputs = make([]string, len(missing))
+	for j, i := range missing {
+		upstream.Inputs[j] = req.Inputs[i]
+	}
+	start := time.Now()
+	fresh, err := embedder.Embed(ctx, upstream)
+	if err != nil {
+		return nil, embedder.Name(), err
+	}
+	if len(fresh.Vectors) != len(missing) {
+		return nil, embedder.Name(), ports.Errorf(ports.ErrInvalidOutput, "%s returned %d embeddings for %d inputs", embedder.Name(), len(fresh.Vectors), len(missing))
+	}
+	s.logRequest(ctx, s.embeddingLog(upstream, embedder.Name(), fresh, time.Since(start)))
+
+	for j, i := range missing {
+		resp.Vectors[i] = fresh.Vectors[j]
+	}
+	if fresh.Model != "" {
+		resp.Model = fresh.Model
+	}
+	if fresh.Usage != nil {
+		resp.Usage = fresh.Usage
+	}
+	s.storeEmbeddings(keys, missing, fresh.Vectors)
+	return resp, embedder.Name(), nil
+}
+
+// HasEmbeddings reports whether any provider can serve embeddings.
+func (s *LLMService) HasEmbeddings() bool {
+	return len(s.embedders) > 0
+}
+
+// embedder picks the named embedding provider, or the highest priority one
+// when name is empty.
+func (s *LLMService) embedder(name string) (string, ports.EmbeddingProvider, error) {
+	if name != "" {
+		e, ok := s.embedders[name]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s does not serve embeddings", ErrEmbeddingsNotConfigured, name)
+		}
+		return name, e, nil
+	}
+	names := make([]string, 0, len(s.embedders))
+	for n := range s.embedders {
+		names = append(names, n)
+	}
+	if len(names) == 0 {
+		return "", nil, ErrEmbeddingsNotConfigured
+	}
+	name = orderByPriority(names)[0]
+	return name, s.embedders[name], nil
+}
+
+// embeddingKey hashes everything that determines a vector. The user is left
+// out on purpose: a vector reveals nothing beyond its input, so sharing
+// entries across users is safe and saves re-embedding common documents.
+func embeddingKey(provider, model string, dimensions int32, input string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s", model, dimensions, input)))
+	return embeddingKeyPrefix + provider + ":" + hex.EncodeToString(sum[:])
+}
+
+func (s *LLMService) cachedEmbedding(ctx context.Context, key string) ([]float32, bool) {
+	if s.cache == nil {
+		return nil, false
+	}
+	raw, err := s.cache.Get(ctx, key)
+	if err != nil || raw == "" {
+		return nil, false
+	}
+	var vector []float32
+	if err := json.Unmarshal([]byte(raw), &vector); err != nil {
+		return nil, false
+	}
+	return vector, true
+}
+
+// storeEmbeddings caches fresh vectors in the background.
+func (s *LLMService) storeEmbeddings(keys []string, missing []int, vectors [][]float32) {
+	if s.cache == nil {
+		return
+	}
+	ttl := s.defaultTTL
+	s.goBackground(func() {
+		for j, i := range missing {
+			raw, err := json.Marshal(vectors[j])
+			if err != nil {
+				continue
+			}
+			_ = s.cache.Set(context.Background(), keys[i], string(raw), ttl)
+		}
+	})
+}
+
+// embeddingLog records an embedding call. The inputs stand in for the prompt;
+// vectors are not stored.
+func (s *LLMService) embeddingLog(req ports.EmbeddingRequest, provider string, resp *ports.EmbeddingResponse, latency time.Duration) ports.RequestLog {
+	log := ports.RequestLog{
+		Prompt:     strings.Join(req.Inputs, "\n"),
+		Provider:   provider,
+		Model:      resp.Model,
+		Response:   fmt.Sprintf("[%d embeddings]", len(resp.Vectors)),
+		DurationMs: latency.Milliseconds(),
+		UserID:     req.UserID,
+		CreatedAt:  time.Now(),
+	}
+	if log.Model == "" {
+		log.Model = req.Model
+	}
+	if resp.Usage != nil {
+		log.PromptTokens = resp.Usage.PromptTokens
+		log.TotalTokens = resp.Usage.TotalTokens
+		log.CostUSD = resp.Usage.CostUSD
+	}
+	return log
+}
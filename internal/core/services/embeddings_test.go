@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// mockEmbedder returns a one-dimensional vector holding each input's length
+// and records the inputs of every call.
+type mockEmbedder struct {
+	mu    sync.Mutex
+	calls [][]string
+	model string
+}
+
+func (m *mockEmbedder) Embed(ctx context.Context, req ports.EmbeddingRequest) (*ports.EmbeddingResponse, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, req.Inputs)
+	m.model = req.Model
+	m.mu.Unlock()
+	resp := &ports.EmbeddingResponse{Model: req.Model, Usage: &ports.UsageInfo{PromptTokens: int32(len(req.Inputs)), TotalTokens: int32(len(req.Inputs)), CostUSD: 0.0001}}
+	for _, in := range req.Inputs {
+		resp.Vectors = append(resp.Vectors, []float32{float32(len(in))})
+	}
+	return resp, nil
+}
+
+func (m *mockEmbedder) Name() string { return "embedder" }
+
+func TestLLMService_Embed(t *testing.T) {
+	repo := newTestRepo(t)
+	cache := &mockCache{data: make(map[string]string)}
+	embedder := &mockEmbedder{}
+	cfg := &config.Config{LLM: config.LLMConfig{OpenAIEmbeddingModel: "text-embedding-3-small"}}
+	svc := NewLLMService(cfg, repo, cache, WithEmbeddingProvider("openai", embedder))
+	ctx := context.Background()
+
+	req := ports.EmbeddingRequest{UserID: "user-123", Inputs: []string{"a", "bb"}}
+	resp, provider, err := svc.Embed(ctx, req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "embedder" || len(resp.Vectors) != 2 || resp.Vectors[1][0] != 2 {
+		t.Fatalf("unexpected response from %s: %+v", provider, resp.Vectors)
+	}
+	if embedder.model != "text-embedding-3-small" {
+		t.Fatalf("expected the configured default model, got %q", embedder.model)
+	}
+	select {
+	case log := <-repo.logs:
+		if log.Provider != "embedder" || log.PromptTokens != 2 || log.CostUSD == 0 {
+			t.Fatalf("expected usage in request log, got %+v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("embedding call was not logged")
+	}
+
+	// Cached vectors are written in the background.
+	deadline := time.Now().Add(time.Second)
+	for n, _ := cache.Count(ctx, embeddingKeyPrefix); n < 2; n, _ = cache.Count(ctx, embeddingKeyPrefix) {
+		if time.Now().After(deadline) {
+			t.Fatal("embeddings were not cached")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Only the uncached input goes upstream, and order is preserved.
+	req.Inputs = []string{"ccc", "a"}
+	resp, _, err = svc.Embed(ctx, req, "openai")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(embedder.calls) != 2 || len(embedder.calls[1]) != 1 || embedder.calls[1][0] != "ccc" {
+		t.Fatalf("expected a single upstream input, got %v", embedder.calls)
+	}
+	if resp.Vectors[0][0] != 3 || resp.Vectors[1][0] != 1 {
+		t.Fatalf("vectors out of order: %+v", resp.Vectors)
+	}
+
+	// A different model is a different cache entry.
+	req.Inputs = []string{"a"}
+	req.Model = "text-embedding-3-large"
+	if _, provider, _ := svc.Embed(ctx, req, ""); provider == CachedProvider {
+		t.Fatal("expected a cache miss for another model")
+	}
+
+	if _, _, err := svc.Embed(ctx, ports.EmbeddingRequest{UserID: "user-123"}, ""); !errors.Is(err, ErrInvalidEmbeddingRequest) {
+		t.Fatalf("expected invalid request error, got %v", err)
+	}
+	if _, _, err := svc.Embed(ctx, ports.EmbeddingRequest{UserID: "user-123", Inputs: []string{" "}}, ""); !errors.Is(err, ErrInvalidEmbeddingRequest) {
+		t.Fatalf("expected invalid request error for blank input, got %v", err)
+	}
+	if _, _, err := svc.Embed(ctx, req, "gemini"); !errors.Is(err, ErrEmbeddingsNotConfigured) {
+		t.Fatalf("expected embeddings not configured, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const defaultHealthTimeout = 2 * time.Second
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+
+	// ReadinessReady means every dependency answered.
+	ReadinessReady = "ready"
+	// ReadinessDegraded means requests can be served but something they use
+	// is down: the cache, or some of the probed providers.
+	ReadinessDegraded = "degraded"
+	// ReadinessUnavailable means requests would fail: the database is down,
+	// or every probed provider is.
+	ReadinessUnavailable = "unavailable"
+)
+
+const (
+	ComponentDatabase = "database"
+	ComponentCache    = "cache"
+	ComponentProvider = "provider"
+)
+
+// ComponentHealth is the outcome of checking one dependency.
+type ComponentHealth struct {
+	Name string
+	// Kind is ComponentDatabase, ComponentCache or ComponentProvider.
+	Kind    string
+	Status  string
+	Latency time.Duration
+}
+
+// Readiness reports whether the service can take requests.
+type Readiness struct {
+	Status     string
+	Components []ComponentHealth
+	CheckedAt  time.Time
+}
+
+// HealthService reports on the dependencies of an LLMService: its database,
+// its cache and, optionally, its providers.
+type HealthService struct {
+	llm            *LLMService
+	timeout        time.Duration
+	cacheTTL       time.Duration
+	probeProviders bool
+
+	mu        sync.Mutex
+	readiness *Readiness
+}
+
+func NewHealthService(cfg config.HealthConfig, llm *LLMService) *HealthService {
+	s := &HealthService{
+		llm:            llm,
+		timeout:        cfg.Timeout,
+		cacheTTL:       cfg.CacheTTL,
+		probeProviders: cfg.ProviderProbes,
+	}
+	if s.timeout <= 0 {
+		s.timeout = defaultHealthTimeout
+	}
+	return s
+}
+
+type healthCheck struct {
+	name, kind string
+	pinger     ports.Pinger
+}
+
+// Readiness checks the database, the cache and, when enabled, the providers
+// concurrently, each within the health timeout. Results are reused for the
+// health cache TTL, and concurrent callers share one round of checks.
+func (s *HealthService) Readiness(ctx context.Context) Readiness {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readiness != nil && time.Since(s.readiness.CheckedAt) < s.cacheTTL {
+		return *s.readiness
+	}
+
+	checks := s.healthChecks()
+	components := make([]ComponentHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = s.check(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	r := Readiness{Status: readinessStatus(components), Components: components, CheckedAt: time.Now()}
+	s.readiness = &r
+	return r
+}
+
+func (s *HealthService) healthChecks() []healthCheck {
+	var checks []healthCheck
+	if p, ok := s.llm.repo.(ports.Pinger); ok {
+		checks = append(checks, healthCheck{name: ComponentDatabase, kind: ComponentDatabase, pinger: p})
+	}
+	if p, ok := s.llm.cache.(ports.Pinger); ok {
+		checks = append(checks, healthCheck{name: ComponentCache, kind: ComponentCache, pinger: p})
+	}
+	if s.probeProviders {
+		for _, name := range slices.Sorted(maps.Keys(s.llm.providers)) {
+			if p, ok := s.llm.providers[name].(ports.Pinger); ok {
+				checks = append(checks, healthCheck{name: name, kind: ComponentProvider, pinger: p})
+			}
+		}
+	}
+	return checks
+}
+
+func (s *HealthService) check(ctx context.Context, c healthCheck) ComponentHealth {
+	// Results are shared, so a caller giving up must not fail the check.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.pinger.Ping(ctx)
+	h := ComponentHealth{Name: c.name, Kind: c.kind, Status: HealthUp, Latency: time.Since(start)}
+	if err != nil {
+		h.Status = HealthDown
+		slog.WarnContext(ctx, "Health check failed", "component", c.name, "err", err)
+	}
+	return h
+}
+
+func readinessStatus(components []ComponentHealth) string {
+	status := ReadinessReady
+	providers, providersUp := 0, 0
+	for _, c := range components {
+		if c.Kind == ComponentProvider {
+			providers++
+			if c.Status == HealthUp {
+				providersUp++
+			}
+		}
+		if c.Status == HealthUp {
+			continue
+		}
+		if c.Kind == ComponentDatabase {
+			return ReadinessUnavailable
+		}
+		status = ReadinessDegraded
+	}
+	if providers > 0 && providersUp == 0 {
+		return ReadinessUnavailable
+	}
+	return status
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+// pinger answers health checks with err after delay, counting them.
+type pinger struct {
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (p *pinger) Ping(ctx context.Context) error {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type pingRepo struct {
+	mockRepo
+	*pinger
+}
+
+type pingCache struct {
+	mockCache
+	*pinger
+}
+
+type pingProvider struct {
+	mockProvider
+	*pinger
+}
+
+func TestHealthService_Readiness(t *testing.T) {
+	down := errors.New("connection refused")
+	tests := []struct {
+		name                   string
+		db, cache, openai, gem error
+		probes                 bool
+		want                   string
+	}{
+		{name: "all up", probes: true, want: ReadinessReady},
+		{name: "cache down", cache: down, want: ReadinessDegraded},
+		{name: "database down", db: down, want: ReadinessUnavailable},
+		{name: "provider failures ignored without probes", openai: down, gem: down, want: ReadinessReady},
+		{name: "one provider down", probes: true, openai: down, want: ReadinessDegraded},
+		{name: "every provider down", probes: true, openai: down, gem: down, want: ReadinessUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := NewLLMService(&config.Config{},
+				&pingRepo{pinger: &pinger{err: tt.db}},
+				&pingCache{mockCache: mockCache{data: make(map[string]string)}, pinger: &pinger{err: tt.cache}},
+				WithProvider("openai", &pingProvider{mockProvider: mockProvider{name: "openai"}, pinger: &pinger{err: tt.openai}}),
+				WithProvider("gemini", &pingProvider{mockProvider: mockProvider{name: "gemini"}, pinger: &pinger{err: tt.gem}}))
+			svc := NewHealthService(config.HealthConfig{ProviderProbes: tt.probes}, llm)
+
+			got := svc.Readiness(context.Background())
+			if got.Status != tt.want {
+				t.Fatalf("expected %s, got %s (%+v)", tt.want, got.Status, got.Components)
+			}
+			wantComponents := 2
+			if tt.probes {
+				wantComponents = 4
+			}
+			if len(got.Components) != wantComponents {
+				t.Fatalf("expected %d components, got %+v", wantComponents, got.Components)
+			}
+		})
+	}
+}
+
+func TestHealthService_ReadinessCachedAndBounded(t *testing.T) {
+	db := &pinger{}
+	stuck := &pinger{delay: time.Hour}
+	llm := NewLLMService(&config.Config{}, &pingRepo{pinger: db},
+		&pingCache{mockCache: mockCache{data: make(map[string]string)}, pinger: stuck})
+	svc := NewHealthService(config.HealthConfig{Timeout: 20 * time.Millisecond, CacheTTL: time.Hour}, llm)
+
+	start := time.Now()
+	first := svc.Readiness(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check ignored its timeout: took %v", elapsed)
+	}
+	if first.Status != ReadinessDegraded || first.Components[1].Status != HealthDown {
+		t.Fatalf("expected the stuck cache to be reported down, got %+v", first)
+	}
+
+	second := svc.Readiness(context.Background())
+	if db.calls.Load() != 1 || !second.CheckedAt.Equal(first.CheckedAt) {
+		t.Fatalf("expected the cached result to be reused, got %d checks", db.calls.Load())
+	}
+}
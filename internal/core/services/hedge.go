@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	hedgePrimary   = "primary"
+	hedgeSecondary = "secondary"
+)
+
+// ErrInvalidHedgeMode is returned when a request or the configuration names an unknown hedge mode.
+var ErrInvalidHedgeMode = ports.NewError(ports.ErrInvalidArgument, "invalid hedge mode")
+
+type hedgeResult struct {
+	name string
+	role string
+	// req is the request as sent to this provider.
+	req     ports.LLMRequest
+	resp    *ports.LLMResponse
+	latency time.Duration
+	err     error
+}
+
+// hedgePolicy resolves the policy for req, falling back to the service default.
+func (s *LLMService) hedgePolicy(req ports.LLMRequest) (ports.HedgePolicy, error) {
+	policy := s.hedge
+	if req.Hedge != nil {
+		policy = *req.Hedge
+	}
+	switch policy.Mode {
+	case "", ports.HedgeOff, ports.HedgeDelay, ports.HedgeRace:
+		return policy, nil
+	}
+	return policy, fmt.Errorf("%w: %q", ErrInvalidHedgeMode, policy.Mode)
+}
+
+// hedgeSecondary picks the provider to hedge against primary, or returns ""
+// when hedging is off or there is nothing else to call.
+func (s *LLMService) hedgeSecondary(policy ports.HedgePolicy, primary string) string {
+	if policy.Mode != ports.HedgeDelay && policy.Mode != ports.HedgeRace {
+		return ""
+	}
+	if policy.Secondary != "" {
+		if _, ok := s.providers[policy.Secondary]; ok && policy.Secondary != primary {
+			return policy.Secondary
+		}
+		return ""
+	}
+	for _, name := range orderByPriority(s.providerNames()) {
+		if name != primary {
+			return name
+		}
+	}
+	return ""
+}
+
+// hedged sends req to the primary provider and, after the policy's delay (or
+// immediately when racing, or as soon as the primary fails), to the
+// secondary. The first successful response is returned and the other call is
+// cancelled. Every attempt is logged, including the loser, so the extra spend
+// shows up in request_logs.
+func (s *LLMService) hedged(ctx context.Context, req ports.LLMRequest, decision RoutingDecision, secondary string, policy ports.HedgePolicy) (*ports.LLMResponse, string, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	launch := func(name, role string) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		attemptReq := req
+		if role == hedgeSecondary {
+			// A resolved model belongs to the primary's provider; the
+			// secondary answers with its own default model.
+			attemptReq.Model = defaultModel(s.catalog, name, "")
+		}
+		go func() {
+			resp, latency, err := s.generate(attemptCtx, name, attemptReq)
+			results <- hedgeResult{name: name, role: role, req: attemptReq, resp: resp, latency: latency, err: err}
+		}()
+	}
+	cancelAll := func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+
+	secondaryDecision := RoutingDecision{
+		Provider: secondary,
+		Strategy: "hedge",
+		Reason:   fmt.Sprintf("%s hedge for %s", policy.Mode, decision.Provider),
+	}
+	logAttempt := func(r hedgeResult, winner bool) {
+		d := decision
+		if r.role == hedgeSecondary {
+			d = secondaryDecision
+		}
+		// A failed attempt's response, if any, holds the usage it was
+		// billed for before it stopped.
+		log := s.requestLog(r.req, r.name, d, r.resp, r.latency)
+		log.HedgeRole = r.role
+		log.HedgeWinner = winner
+		if r.err != nil {
+			log.Error = r.err.Error()
+		}
+		s.logRequest(ctx, log)
+	}
+
+	launch(decision.Provider, hedgePrimary)
+	launched := 1
+	var timer <-chan time.Time
+	if policy.Mode == ports.HedgeRace {
+		launch(secondary, hedgeSecondary)
+		launched++
+	} else {
+		t := time.NewTimer(policy.Delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var firstErr error
+	for received := 0; received < launched; {
+		select {
+		case <-timer:
+			timer = nil
+			launch(secondary, hedgeSecondary)
+			launched++
+		case r := <-results:
+			received++
+			if r.err == nil {
+				logAttempt(r, true)
+				cancelAll()
+				// The loser reports back once its cancellation lands; log it
+				// then so its usage is not lost, and before Flush returns.
+				pending := launched - received
+				s.goBackground(func() {
+					for ; pending > 0; pending-- {
+						logAttempt(<-results, false)
+					}
+				})
+				return r.resp, r.name, nil
+			}
+			logAttempt(r, false)
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			// The primary failed before the hedge fired: use the secondary as a fallback now.
+			if timer != nil {
+				timer = nil
+				launch(secondary, hedgeSecondary)
+				launched++
+			}
+		}
+	}
+	cancelAll()
+	return nil, decision.Provider, firstErr
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	defaultJobPollInterval = time.Second
+	defaultJobLease        = 10 * time.Minute
+	defaultJobMaxAttempts  = 3
+	// defaultJobRetryBackoff is the wait before a job that failed with a
+	// transient error runs again; it doubles with every further attempt up
+	// to maxJobRetryBackoff.
+	defaultJobRetryBackoff = 30 * time.Second
+	maxJobRetryBackoff     = 10 * time.Minute
+	defaultJobRetention    = 7 * 24 * time.Hour
+	// jobPruneInterval is how often workers delete jobs past retention.
+	jobPruneInterval          = time.Hour
+	defaultWebhookMaxAttempts = 5
+	// defaultWebhookBackoff is the wait before the first webhook retry; it
+	// doubles with every further attempt up to maxWebhookBackoff.
+	defaultWebhookBackoff = 30 * time.Second
+	maxWebhookBackoff     = time.Hour
+	webhookTimeout        = 10 * time.Second
+)
+
+var (
+	// ErrJobsNotConfigured is returned when no job queue is configured.
+	ErrJobsNotConfigured = ports.NewError(ports.ErrNotConfigured, "jobs not configured")
+	// ErrJobNotFound is returned for unknown jobs and for jobs owned by
+	// another user.
+	ErrJobNotFound = ports.NewError(ports.ErrNotFound, "job not found")
+	// ErrInvalidJob is returned for job submissions that cannot be queued.
+	ErrInvalidJob = ports.NewError(ports.ErrInvalidArgument, "invalid job")
+)
+
+// JobService runs generation requests in the background. Jobs wait in a
+// queue shared by every replica, run on workers through the LLMService and
+// report their outcome to an optional webhook.
+type JobService struct {
+	llm          *LLMService
+	queue        ports.JobQueue
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	retryBackoff time.Duration
+	retention    time.Duration
+
+	webhookSecret      []byte
+	webhookMaxAttempts int
+	webhookBackoff     time.Duration
+	webhookClient      *http.Client
+	// webhookAllowedHosts limits webhooks to these hosts when non-empty.
+	webhookAllowedHosts []string
+	// webhookAddrAllowed decides which resolved addresses webhooks may
+	// reach; publicAddr unless a test swaps it.
+	webhookAddrAllowed func(netip.Addr) bool
+}
+
+// NewJobService builds a job service on queue. A nil queue disables jobs.
+func NewJobService(cfg config.JobConfig, llm *LLMService, queue ports.JobQueue) *JobService {
+	s := &JobService{
+		llm:                llm,
+		queue:              queue,
+		pollInterval:       cfg.PollInterval,
+		lease:              cfg.Lease,
+		maxAttempts:        cfg.MaxAttempts,
+		retryBackoff:       defaultJobRetryBackoff,
+		retention:          cfg.Retention,
+		webhookSecret:      []byte(cfg.WebhookSecret),
+		webhookMaxAttempts: cfg.WebhookMaxAttempts,
+		webhookBackoff:     defaultWebhookBackoff,
+		webhookAddrAllowed: publicAddr,
+	}
+	if s.pollInterval <= 0 {
+		s.pollInterval = defaultJobPollInterval
+	}
+	if s.lease <= 0 {
+		s.lease = defaultJobLease
+	}
+	if s.maxAttempts <= 0 {
+		s.maxAttempts = defaultJobMaxAttempts
+	}
+	if s.retention <= 0 {
+		s.retention = defaultJobRetention
+	}
+	if s.webhookMaxAttempts <= 0 {
+		s.webhookMaxAttempts = defaultWebhookMaxAttempts
+	}
+	for _, h := range cfg.WebhookAllowedHosts {
+		s.webhookAllowedHosts = append(s.webhookAllowedHosts, strings.ToLower(h))
+	}
+	s.webhookClient = s.newWebhookClient()
+	return s
+}
+
+// SubmitJob queues req for a background worker. The request is checked for
+// what can be known up front; everything else surfaces as the job's error.
+func (s *JobService) SubmitJob(ctx context.Context, req ports.LLMRequest, providerName, webhookURL string) (*ports.Job, error) {
+	if s.queue == nil {
+		return nil, ErrJobsNotConfigured
+	}
+	if err := s.llm.ensureUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+	if providerName != "" {
+		if _, ok := s.llm.providers[providerName]; !ok {
+			return nil, fmt.Errorf("%w: provider %s not found", ErrInvalidJob, providerName)
+		}
+	}
+	if req.CacheMode == ports.CacheOnly {
+		return nil, fmt.Errorf("%w: cache mode %q cannot be queued", ErrInvalidJob, req.CacheMode)
+	}
+	// The request is stored with the job until it is pruned, so media must
+	// be linked rather than sent inline.
+	for _, m := range req.Messages {
+		for _, p := range m.Parts {
+			if len(p.Data) > 0 {
+				return nil, fmt.Errorf("%w: inline attachments cannot be queued, link images by url", ErrInvalidJob)
+			}
+		}
+	}
+	// Templates are rendered when the job runs, so their prompts are checked then.
+	if req.Template == nil {
+		if err := s.llm.validateRequest(req); err != nil {
+			return nil, err
+		}
+	}
+	if webhookURL != "" {
+		if len(s.webhookSecret) == 0 {
+			return nil, fmt.Errorf("%w: webhooks are disabled on this server", ErrInvalidJob)
+		}
+		if err := s.checkWebhookURL(ctx, webhookURL); err != nil {
+			return nil, err
+		}
+	}
+	return s.queue.EnqueueJob(ctx, ports.Job{
+		UserID:     req.UserID,
+		Provider:   providerName,
+		Request:    req,
+		Status:     ports.JobQueued,
+		WebhookURL: webhookURL,
+	})
+}
+
+// GetJob returns a job of userID, hiding other users' jobs behind the same
+// error as missing ones.
+func (s *JobService) GetJob(ctx context.Context, userID, id string) (*ports.Job, error) {
+	if s.queue == nil {
+		return nil, ErrJobsNotConfigured
+	}
+	job, err := s.queue.GetJob(ctx, id)
+	if errors.Is(err, ports.ErrNotFound) || (err == nil && job.UserID != userID) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunJobWorkers runs n workers that take jobs and webhook deliveries off the
+// queue until ctx is cancelled, and returns once they have all stopped. A
+// job interrupted by cancellation is left to be picked up again after its
+// lease expires.
+func (s *JobService) RunJobWorkers(ctx context.Context, n int) {
+	if s.queue == nil || n <= 0 {
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.pruneJobsEvery(ctx, jobPruneInterval)
+	}()
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.jobWorker(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *JobService) jobWorker(ctx context.Context) {
+	for ctx.Err() == nil {
+		if s.runNextJob(ctx) || s.deliverNextWebhook(ctx) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// pruneJobsEvery deletes jobs past retention now and then every interval
+// until ctx is cancelled.
+func (s *JobService) pruneJobsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := s.queue.PruneJobs(ctx, s.retention)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.ErrorContext(ctx, "Failed to prune jobs", "err", err)
+		case deleted > 0:
+			slog.InfoContext(ctx, "Pruned finished jobs", "deleted", deleted, "retention", s.retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNextJob claims and runs one job, reporting whether there was one.
+func (s *JobService) runNextJob(ctx context.Context) bool {
+	job, err := s.queue.ClaimJob(ctx, s.lease)
+	if err != nil {
+		if !errors.Is(err, ports.ErrNotFound) && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to claim job", "err", err)
+		}
+		return false
+	}
+
+	if job.Attempts > s.maxAttempts {
+		job.Status = ports.JobFailed
+		job.Error, job.ErrorCode = fmt.Sprintf("abandoned after %d attempts", s.maxAttempts), "internal"
+	} else {
+		// The run must end before the lease does, or another worker would
+		// start the same job.
+		runCtx, cancel := context.WithTimeout(ctx, s.lease)
+		resp, provider, err := s.llm.ProcessRequest(runCtx, job.Request, job.Provider)
+		cancel()
+		if ctx.Err() != nil {
+			return true
+		}
+		job.ProviderUsed = provider
+		if err != nil {
+			// The full error may hold the provider's response; only its
+			// public description is stored for the caller.
+			job.Error, job.ErrorCode = ports.PublicMessage(err), ports.ErrorCode(err)
+			if ports.RetryableCode(job.ErrorCode) && job.Attempts < s.maxAttempts {
+				retryIn := min(s.retryBackoff<<(job.Attempts-1), maxJobRetryBackoff)
+				slog.WarnContext(ctx, "Job failed, retrying", "job_id", job.ID, "attempt", job.Attempts, "retry_in", retryIn, "err", err)
+				if err := s.queue.RetryJob(ctx, *job, retryIn); err != nil {
+					slog.ErrorContext(ctx, "Failed to requeue job", "job_id", job.ID, "err", err)
+				}
+				return true
+			}
+			slog.WarnContext(ctx, "Job failed", "job_id", job.ID, "attempt", job.Attempts, "err", err)
+			job.Status = ports.JobFailed
+		} else {
+			job.Status = ports.JobSucceeded
+			job.Result = resp
+		}
+	}
+
+	if err := s.queue.FinishJob(ctx, *job); err != nil {
+		slog.ErrorContext(ctx, "Failed to finish job", "job_id", job.ID, "err", err)
+		return true
+	}
+	slog.InfoContext(ctx, "Job finished", "job_id", job.ID, "status", job.Status, "provider", job.ProviderUsed, "attempt", job.Attempts)
+	return true
+}
+
+// jobEvent is the body POSTed to a job's webhook.
+type jobEvent struct {
+	ID           string          `json:"id"`
+	Status       ports.JobStatus `json:"status"`
+	Error        string          `json:"error,omitempty"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	ProviderUsed string          `json:"provider_used,omitempty"`
+	ModelUsed    string          `json:"model_used,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	JSON         json.RawMessage `json:"json,omitempty"`
+	FinishReason string          `json:"finish_reason,omitempty"`
+	Usage        *jobEventUsage  `json:"usage,omitempty"`
+}
+
+type jobEventUsage struct {
+	PromptTokens     int32   `json:"prompt_tokens"`
+	CompletionTokens int32   `json:"completion_tokens"`
+	TotalTokens      int32   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// deliverNextWebhook claims and sends one due webhook, reporting whether
+// there was one. Failed deliveries are retried with exponential backoff.
+func (s *JobService) deliverNextWebhook(ctx context.Context) bool {
+	job, err := s.queue.ClaimWebhook(ctx, webhookTimeout*2)
+	if err != nil {
+		if !errors.Is(err, ports.ErrNotFound) && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Failed to claim webhook", "err", err)
+		}
+		return false
+	}
+
+	status, errMsg, retryIn := ports.WebhookDelivered, "", time.Duration(0)
+	if err := s.sendWebhook(ctx, job); err != nil {
+		if ctx.Err() != nil {
+			return true
+		}
+		errMsg = err.Error()
+		if job.WebhookAttempts >= s.webhookMaxAttempts {
+			status = ports.WebhookFailed
+		} else {
+			status = ports.WebhookPending
+			retryIn = min(s.webhookBackoff<<(job.WebhookAttempts-1), maxWebhookBackoff)
+		}
+		slog.WarnContext(ctx, "Webhook delivery failed", "job_id", job.ID, "attempt", job.WebhookAttempts, "err", err)
+	}
+	if err := s.queue.RecordWebhook(ctx, job.ID, status, errMsg, retryIn); err != nil {
+		slog.ErrorContext(ctx, "Failed to record webhook", "job_id", job.ID, "err", err)
+	}
+	return true
+}
+
+// sendWebhook POSTs the job's outcome. The body is signed with HMAC-SHA256
+// over "<timestamp>.<body>" so receivers can check both origin and age.
+func (s *JobService) sendWebhook(ctx context.Context, job *ports.Job) error {
+	event := jobEvent{ID: job.ID, Status: job.Status, Error: job.Error, ErrorCode: job.ErrorCode, ProviderUsed: job.ProviderUsed}
+	if r := job.Result; r != nil {
+		event.ModelUsed = r.Model
+		event.Content = r.Content
+		event.JSON = r.JSON
+		event.FinishReason = r.FinishReason
+		if u := r.Usage; u != nil {
+			event.Usage = &jobEventUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens, CostUSD: u.CostUSD}
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Nexus-Job-Id", job.ID)
+	req.Header.Set("X-Nexus-Timestamp", timestamp)
+	req.Header.Set("X-Nexus-Signature", "sha256="+SignWebhook(s.webhookSecret, timestamp, body))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>", as sent
+// in the X-Nexus-Signature header.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// memoryJobs is an in-process JobQueue with the same lease rules as the
+// Postgres one.
+type memoryJobs struct {
+	mu           sync.Mutex
+	jobs         []*ports.Job
+	leases       map[string]time.Time
+	runAfter     map[string]time.Time
+	webhookDueAt map[string]time.Time
+}
+
+func newMemoryJobs() *memoryJobs {
+	return &memoryJobs{leases: make(map[string]time.Time), runAfter: make(map[string]time.Time), webhookDueAt: make(map[string]time.Time)}
+}
+
+func (m *memoryJobs) EnqueueJob(ctx context.Context, job ports.Job) (*ports.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = strconv.Itoa(len(m.jobs) + 1)
+	job.CreatedAt = time.Now()
+	m.jobs = append(m.jobs, &job)
+	out := job
+	return &out, nil
+}
+
+func (m *memoryJobs) GetJob(ctx context.Context, id string) (*ports.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			out := *job
+			return &out, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s: %w", id, ports.ErrNotFound)
+}
+
+func (m *memoryJobs) ClaimJob(ctx context.Context, lease time.Duration) (*ports.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, job := range m.jobs {
+		if (job.Status == ports.JobQueued && !m.runAfter[job.ID].After(now)) || (job.Status == ports.JobRunning && m.leases[job.ID].Before(now)) {
+			job.Status = ports.JobRunning
+			job.Attempts++
+			job.StartedAt = &now
+			m.leases[job.ID] = now.Add(lease)
+			out := *job
+			return &out, nil
+		}
+	}
+	return nil, ports.ErrNotFound
+}
+
+func (m *memoryJobs) FinishJob(ctx context.Context, done ports.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == done.ID && job.Attempts == done.Attempts && job.Status == ports.JobRunning {
+			now := time.Now()
+			job.Status, job.Result, job.ProviderUsed, job.Error, job.ErrorCode = done.Status, done.Result, done.ProviderUsed, done.Error, done.ErrorCode
+			job.FinishedAt = &now
+			if job.WebhookURL != "" {
+				job.WebhookStatus = ports.WebhookPending
+				m.webhookDueAt[job.ID] = now
+			}
+			return nil
+		}
+	}
+	return ports.ErrNotFound
+}
+
+func (m *memoryJobs) RetryJob(ctx context.Context, failed ports.Job, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == failed.ID && job.Attempts == failed.Attempts && job.Status == ports.JobRunning {
+			job.Status, job.ProviderUsed, job.Error, job.ErrorCode = ports.JobQueued, failed.ProviderUsed, failed.Error, failed.ErrorCode
+			m.runAfter[job.ID] = time.Now().Add(retryIn)
+			return nil
+		}
+	}
+	return ports.ErrNotFound
+}
+
+func (m *memoryJobs) PruneJobs(ctx context.Context, olderThan time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := time.Now().Add(-olderThan)
+	kept := m.jobs[:0]
+	for _, job := range m.jobs {
+		if job.FinishedAt == nil || !job.FinishedAt.Before(cutoff) || job.WebhookStatus == ports.WebhookPending {
+			kept = append(kept, job)
+		}
+	}
+	deleted := int64(len(m.jobs) - len(kept))
+	m.jobs = kept
+	return deleted, nil
+}
+
+func (m *memoryJobs) ClaimWebhook(ctx context.Context, lease time.Duration) (*ports.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, job := range m.jobs {
+		if job.WebhookStatus == ports.WebhookPending && !m.webhookDueAt[job.ID].After(now) {
+			job.WebhookAttempts++
+			m.webhookDueAt[job.ID] = now.Add(lease)
+			out := *job
+			return &out, nil
+		}
+	}
+	return nil, ports.ErrNotFound
+}
+
+func (m *memoryJobs) RecordWebhook(ctx context.Context, id string, status ports.WebhookStatus, errMsg string, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			job.WebhookStatus, job.WebhookError = status, errMsg
+			m.webhookDueAt[id] = time.Now().Add(retryIn)
+			return nil
+		}
+	}
+	return ports.ErrNotFound
+}
+
+func TestJobService(t *testing.T) {
+	repo := newTestRepo(t)
+	queue := newMemoryJobs()
+	cfg := config.JobConfig{PollInterval: 5 * time.Millisecond, WebhookSecret: "s3cret"}
+	svc := NewJobService(cfg, NewLLMService(&config.Config{}, repo, nil, WithProvider("mock", &mockProvider{name: "mock"})), queue)
+	svc.webhookBackoff = 10 * time.Millisecond
+	// The receiver runs on loopback.
+	svc.webhookAddrAllowed = func(netip.Addr) bool { return true }
+
+	// The receiver refuses the first delivery to exercise the retry.
+	type delivery struct {
+		event  jobEvent
+		signed bool
+	}
+	deliveries := make(chan delivery, 2)
+	var calls int
+	hook := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var event jobEvent
+		json.Unmarshal(body, &event)
+		want := "sha256=" + SignWebhook([]byte("s3cret"), r.Header.Get("X-Nexus-Timestamp"), body)
+		deliveries <- delivery{event: event, signed: r.Header.Get("X-Nexus-Signature") == want}
+	}))
+	defer hook.Close()
+	svc.webhookClient.Transport.(*http.Transport).TLSClientConfig = hook.Client().Transport.(*http.Transport).TLSClientConfig
+
+	ctx := context.Background()
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Summarize this"}
+	job, err := svc.SubmitJob(ctx, req, "mock", hook.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.Status != ports.JobQueued {
+		t.Fatalf("expected a queued job, got %s", job.Status)
+	}
+
+	workerCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		svc.RunJobWorkers(workerCtx, 2)
+		close(stopped)
+	}()
+
+	select {
+	case d := <-deliveries:
+		if !d.signed {
+			t.Fatalf("expected a valid signature")
+		}
+		if d.event.ID != job.ID || d.event.Status != ports.JobSucceeded || d.event.Content != "mock response from mock" {
+			t.Fatalf("unexpected webhook event: %+v", d.event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	stop()
+	<-stopped
+
+	got, err := svc.GetJob(ctx, "user-123", job.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != ports.JobSucceeded || got.Result == nil || got.Attempts != 1 || got.WebhookAttempts != 2 {
+		t.Fatalf("unexpected finished job: %+v", got)
+	}
+	if _, err := svc.GetJob(ctx, "user-456", job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected job not found for another user, got %v", err)
+	}
+
+	if _, err := svc.SubmitJob(ctx, req, "nope", ""); !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("expected invalid job for an unknown provider, got %v", err)
+	}
+	unsigned := NewJobService(config.JobConfig{}, svc.llm, queue)
+	if _, err := unsigned.SubmitJob(ctx, req, "mock", hook.URL); !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("expected webhooks to need a secret, got %v", err)
+	}
+}
+
+func TestJobService_WebhookURL(t *testing.T) {
+	repo := newTestRepo(t)
+	newService := func(allowed ...string) *JobService {
+		cfg := config.JobConfig{WebhookSecret: "s3cret", WebhookAllowedHosts: allowed}
+		return NewJobService(cfg, NewLLMService(&config.Config{}, repo, nil, WithProvider("mock", &mockProvider{name: "mock"})), newMemoryJobs())
+	}
+	svc := newService()
+	ctx := context.Background()
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "hi"}
+
+	for _, hook := range []string{
+		"http://example.com/hook",
+		"ftp://example.com/hook",
+		"https:///hook",
+		"https://127.0.0.1/hook",
+		"https://localhost:8443/hook",
+		"https://10.1.2.3/hook",
+		"https://192.168.0.10/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://[fd00::1]/hook",
+		"https://[::ffff:127.0.0.1]/hook",
+		"https://0.0.0.0/hook",
+	} {
+		if _, err := svc.SubmitJob(ctx, req, "mock", hook); !errors.Is(err, ErrInvalidJob) {
+			t.Errorf("%s: expected invalid job, got %v", hook, err)
+		}
+	}
+	if _, err := svc.SubmitJob(ctx, req, "mock", "https://8.8.8.8/hook"); err != nil {
+		t.Fatalf("expected a public address to be accepted, got %v", err)
+	}
+
+	allowList := newService("Hooks.example.com")
+	if _, err := allowList.SubmitJob(ctx, req, "mock", "https://8.8.8.8/hook"); !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("expected hosts off the allow-list to be refused, got %v", err)
+	}
+
+	// Addresses are checked again when dialled, in case DNS has changed.
+	hook := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hook.Close()
+	svc.webhookClient.Transport.(*http.Transport).TLSClientConfig = hook.Client().Transport.(*http.Transport).TLSClientConfig
+	err := svc.sendWebhook(ctx, &ports.Job{ID: "job-1", Status: ports.JobSucceeded, WebhookURL: hook.URL})
+	if err == nil || !strings.Contains(err.Error(), "not public") {
+		t.Fatalf("expected the loopback dial to be refused, got %v", err)
+	}
+}
+
+func TestJobService_Abandoned(t *testing.T) {
+	repo := newTestRepo(t)
+	queue := newMemoryJobs()
+	llm := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("mock", &mockProvider{name: "mock"}))
+	svc := NewJobService(config.JobConfig{MaxAttempts: 2}, llm, queue)
+	ctx := context.Background()
+
+	job, err := svc.SubmitJob(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "hi"}, "mock", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Two workers died holding the job; the third claim gives up on it.
+	queue.jobs[0].Attempts = 2
+	queue.jobs[0].Status = ports.JobRunning
+	queue.leases[job.ID] = time.Now().Add(-time.Second)
+
+	if !svc.runNextJob(ctx) {
+		t.Fatal("expected the expired job to be claimed")
+	}
+	got, _ := svc.GetJob(ctx, "user-123", job.ID)
+	if got.Status != ports.JobFailed || got.Attempts != 3 || got.Result != nil {
+		t.Fatalf("expected the job to be abandoned, got %+v", got)
+	}
+}
+
+func TestJobService_Failed(t *testing.T) {
+	repo := newTestRepo(t)
+	llm := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("broken", &mockProvider{name: "broken", respond: upstreamFailure("broken", ports.ErrContentFiltered)}))
+	svc := NewJobService(config.JobConfig{}, llm, newMemoryJobs())
+	ctx := context.Background()
+
+	job, err := svc.SubmitJob(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "hi"}, "broken", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !svc.runNextJob(ctx) {
+		t.Fatal("expected the job to be claimed")
+	}
+	got, _ := svc.GetJob(ctx, "user-123", job.ID)
+	if got.Status != ports.JobFailed || got.ErrorCode != "content_filtered" || got.Attempts != 1 {
+		t.Fatalf("expected a classified failure without retries, got %+v", got)
+	}
+	if strings.Contains(got.Error, "secret") {
+		t.Fatalf("expected the upstream response to stay out of the job, got %q", got.Error)
+	}
+}
+
+func TestJobService_Retried(t *testing.T) {
+	repo := newTestRepo(t)
+	// The provider is rate limited on its first call only.
+	limitedOnce := func(call int, req ports.LLMRequest) (*ports.LLMResponse, error) {
+		if call == 1 {
+			return upstreamFailure("flaky", ports.ErrRateLimited)(call, req)
+		}
+		return nil, nil
+	}
+	queue := newMemoryJobs()
+	llm := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("flaky", &mockProvider{name: "flaky", respond: limitedOnce}),
+		WithProvider("limited", &mockProvider{name: "limited", respond: upstreamFailure("limited", ports.ErrRateLimited)}))
+	svc := NewJobService(config.JobConfig{MaxAttempts: 2}, llm, queue)
+	svc.retryBackoff = 20 * time.Millisecond
+	ctx := context.Background()
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "hi"}
+
+	job, err := svc.SubmitJob(ctx, req, "flaky", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !svc.runNextJob(ctx) {
+		t.Fatal("expected the job to be claimed")
+	}
+	got, _ := svc.GetJob(ctx, "user-123", job.ID)
+	if got.Status != ports.JobQueued || got.ErrorCode != "rate_limited" || got.Attempts != 1 {
+		t.Fatalf("expected the job queued for a retry, got %+v", got)
+	}
+	if svc.runNextJob(ctx) {
+		t.Fatal("expected the retry to wait for its backoff")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !svc.runNextJob(ctx) {
+		t.Fatal("expected the retry to be claimed")
+	}
+	got, _ = svc.GetJob(ctx, "user-123", job.ID)
+	if got.Status != ports.JobSucceeded || got.Result == nil || got.Attempts != 2 {
+		t.Fatalf("expected the retry to succeed, got %+v", got)
+	}
+
+	// Retries stop at the attempt limit.
+	job, err = svc.SubmitJob(ctx, req, "limited", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc.retryBackoff = 0
+	for svc.runNextJob(ctx) {
+	}
+	got, _ = svc.GetJob(ctx, "user-123", job.ID)
+	if got.Status != ports.JobFailed || got.ErrorCode != "rate_limited" || got.Attempts != 2 {
+		t.Fatalf("expected the job to fail after 2 attempts, got %+v", got)
+	}
+}
+
+func TestJobService_Storage(t *testing.T) {
+	repo := newTestRepo(t)
+	queue := newMemoryJobs()
+	llm := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("mock", &mockProvider{name: "mock"}))
+	svc := NewJobService(config.JobConfig{Retention: time.Hour}, llm, queue)
+	ctx := context.Background()
+
+	inline := ports.LLMRequest{UserID: "user-123", Messages: []ports.Message{{Role: "user", Parts: []ports.ContentPart{
+		{Type: ports.PartImage, MIMEType: "image/png", Data: []byte("png")},
+	}}}}
+	if _, err := svc.SubmitJob(ctx, inline, "mock", ""); !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("expected inline attachments to be refused, got %v", err)
+	}
+	linked := ports.LLMRequest{UserID: "user-123", Messages: []ports.Message{{Role: "user", Parts: []ports.ContentPart{
+		{Type: ports.PartImage, MIMEType: "image/png", URL: "https://example.com/cat.png"},
+	}}}}
+	old, err := svc.SubmitJob(ctx, linked, "mock", "")
+	if err != nil {
+		t.Fatalf("expected linked images to be accepted, got %v", err)
+	}
+	recent, err := svc.SubmitJob(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "hi"}, "mock", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for svc.runNextJob(ctx) {
+	}
+	finished := time.Now().Add(-2 * time.Hour)
+	queue.jobs[0].FinishedAt = &finished
+
+	// The worker prunes once on start.
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		svc.pruneJobsEvery(runCtx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if _, err := svc.GetJob(ctx, "user-123", old.ID); !errors.Is(err, ports.ErrNotFound) {
+		t.Fatalf("expected the expired job to be pruned, got %v", err)
+	}
+	if _, err := svc.GetJob(ctx, "user-123", recent.ID); err != nil {
+		t.Fatalf("expected the recent job to be kept, got %v", err)
+	}
+}
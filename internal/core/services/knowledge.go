@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/knowledge"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	defaultRetrievalTopK = 4
+	maxRetrievalTopK     = 20
+)
+
+var (
+	// ErrKnowledgeNotConfigured is returned when no vector index is configured.
+	ErrKnowledgeNotConfigured = ports.NewError(ports.ErrNotConfigured, "knowledge base not configured")
+	// ErrCollectionNotFound is returned for unknown collections and for
+	// collections owned by another user.
+	ErrCollectionNotFound = ports.NewError(ports.ErrNotFound, "collection not found")
+	// ErrInvalidDocument is returned for documents that are empty or of an
+	// unsupported type.
+	ErrInvalidDocument = ports.NewError(ports.ErrInvalidArgument, "invalid document")
+)
+
+// KnowledgeService manages document collections and grounds the requests
+// of its LLMService that name one.
+type KnowledgeService struct {
+	llm     *LLMService
+	index   ports.VectorIndex
+	chunker knowledge.Chunker
+	topK    int
+}
+
+// NewKnowledgeService builds a knowledge service on index and has llm ground
+// requests with it. A nil index disables collections.
+func NewKnowledgeService(cfg config.KnowledgeConfig, llm *LLMService, index ports.VectorIndex) *KnowledgeService {
+	k := &KnowledgeService{
+		llm:     llm,
+		index:   index,
+		chunker: knowledge.NewChunker(cfg.ChunkSize, cfg.ChunkOverlap),
+		topK:    cfg.TopK,
+	}
+	if k.topK <= 0 {
+		k.topK = defaultRetrievalTopK
+	}
+	llm.knowledge = k
+	return k
+}
+
+// DocumentUpload is a document as sent by a client, before chunking.
+type DocumentUpload struct {
+	Title string
+	// ContentType is "text", "markdown" or "html", or the matching MIME type.
+	ContentType string
+	Content     string
+}
+
+// CreateCollection creates an empty collection for c.UserID. The embedding
+// provider and model default to the ones /embeddings would use and are fixed
+// for the collection's lifetime.
+func (s *KnowledgeService) CreateCollection(ctx context.Context, c ports.Collection) (*ports.Collection, error) {
+	if s.index == nil {
+		return nil, ErrKnowledgeNotConfigured
+	}
+	if err := s.llm.ensureUser(ctx, c.UserID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return nil, ports.NewError(ports.ErrInvalidArgument, "name is required")
+	}
+	name, _, err := s.llm.embedder(c.EmbeddingProvider)
+	if err != nil {
+		return nil, err
+	}
+	c.EmbeddingProvider = name
+	if c.EmbeddingModel == "" {
+		c.EmbeddingModel = s.llm.embeddingModels[name]
+	}
+	return s.index.CreateCollection(ctx, c)
+}
+
+// AddDocument extracts the document's text, splits it into chunks, embeds
+// them with the collection's model and stores them in the index.
+func (s *KnowledgeService) AddDocument(ctx context.Context, userID, collectionID string, upload DocumentUpload) (*ports.Document, error) {
+	c, err := s.collection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	contentType, err := knowledge.NormalizeContentType(upload.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	text, err := knowledge.Extract(contentType, upload.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	pieces := s.chunker.Split(text)
+	if len(pieces) == 0 {
+		return nil, fmt.Errorf("%w: document has no text", ErrInvalidDocument)
+	}
+
+	chunks := make([]ports.Chunk, len(pieces))
+	for start := 0; start < len(pieces); start += maxEmbeddingInputs {
+		batch := pieces[start:min(start+maxEmbeddingInputs, len(pieces))]
+		resp, _, err := s.llm.Embed(ctx, ports.EmbeddingRequest{UserID: userID, Model: c.EmbeddingModel, Inputs: batch}, c.EmbeddingProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed document: %w", err)
+		}
+		for i, vector := range resp.Vectors {
+			chunks[start+i] = ports.Chunk{Text: batch[i], Embedding: vector}
+		}
+	}
+
+	title := upload.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	return s.index.AddDocument(ctx, ports.Document{CollectionID: c.ID, Title: title, ContentType: contentType}, chunks)
+}
+
+// collection loads a collection, hiding other users' collections behind the
+// same error as missing ones.
+func (s *KnowledgeService) collection(ctx context.Context, userID, id string) (*ports.Collection, error) {
+	if s.index == nil {
+		return nil, ErrKnowledgeNotConfigured
+	}
+	if err := s.llm.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	c, err := s.index.GetCollection(ctx, id)
+	if errors.Is(err, ports.ErrNotFound) || (err == nil && c.UserID != userID) {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// retrieve grounds a request that names a collection on it.
+func (s *LLMService) retrieve(ctx context.Context, req ports.LLMRequest) (ports.LLMRequest, []ports.Citation, error) {
+	if req.CollectionID == "" {
+		return req, nil, nil
+	}
+	if s.knowledge == nil {
+		return req, nil, ErrKnowledgeNotConfigured
+	}
+	return s.knowledge.retrieve(ctx, req)
+}
+
+// retrieve grounds a request on its collection: the latest user message is
+// embedded, the closest chunks are searched, and they are put in front of
+// the conversation as a numbered system message the answer can cite.
+func (s *KnowledgeService) retrieve(ctx context.Context, req ports.LLMRequest) (ports.LLMRequest, []ports.Citation, error) {
+	c, err := s.collection(ctx, req.UserID, req.CollectionID)
+	if err != nil {
+		return req, nil, err
+	}
+	query := retrievalQuery(req)
+	if query == "" {
+		return req, nil, nil
+	}
+
+	k := req.RetrievalTopK
+	if k <= 0 {
+		k = s.topK
+	}
+	k = min(k, maxRetrievalTopK)
+	resp, _, err := s.llm.Embed(ctx, ports.EmbeddingRequest{UserID: req.UserID, Model: c.EmbeddingModel, Inputs: []string{query}}, c.EmbeddingProvider)
+	if err != nil {
+		return req, nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	hits, err := s.index.Search(ctx, c.ID, resp.Vectors[0], k)
+	if err != nil {
+		return req, nil, fmt.Errorf("failed to search collection: %w", err)
+	}
+	if len(hits) == 0 {
+		return req, nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Answer using the excerpts below. Cite the excerpts you use with their [n] markers. If they do not contain the answer, say so.")
+	citations := make([]ports.Citation, len(hits))
+	for i, hit := range hits {
+		citations[i] = ports.Citation{
+			Index:         i + 1,
+			DocumentID:    hit.DocumentID,
+			DocumentTitle: hit.DocumentTitle,
+			ChunkID:       hit.ID,
+			Score:         hit.Score,
+			Text:          hit.Text,
+		}
+		fmt.Fprintf(&b, "\n\n[%d] %s\n%s", i+1, hit.DocumentTitle, hit.Text)
+	}
+	grounding := ports.Message{Role: ports.RoleSystem, Content: b.String()}
+	req.Messages = append([]ports.Message{grounding}, req.Conversation()...)
+	return req, citations, nil
+}
+
+// retrievalQuery is the text of the latest user message.
+func retrievalQuery(req ports.LLMRequest) string {
+	conversation := req.Conversation()
+	for i := len(conversation) - 1; i >= 0; i-- {
+		if conversation[i].Role == ports.RoleUser {
+			return strings.TrimSpace(messageText(conversation[i]))
+		}
+	}
+	return ""
+}
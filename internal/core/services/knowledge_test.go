@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/adapters/repository"
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// keywordEmbedder embeds text as counts of a few keywords, so similarity
+// follows topic overlap.
+type keywordEmbedder struct{}
+
+var embedKeywords = []string{"refund", "shipping", "password"}
+
+func (keywordEmbedder) Embed(ctx context.Context, req ports.EmbeddingRequest) (*ports.EmbeddingResponse, error) {
+	resp := &ports.EmbeddingResponse{Model: req.Model, Usage: &ports.UsageInfo{}}
+	for _, in := range req.Inputs {
+		vector := make([]float32, len(embedKeywords))
+		for i, kw := range embedKeywords {
+			vector[i] = float32(strings.Count(strings.ToLower(in), kw))
+		}
+		resp.Vectors = append(resp.Vectors, vector)
+	}
+	return resp, nil
+}
+
+func (keywordEmbedder) Name() string { return "keywords" }
+
+func TestLLMService_Knowledge(t *testing.T) {
+	repo := newTestRepo(t)
+	provider := &mockProvider{name: "openai", respond: replies("Refunds take five days [1].")}
+	cfg := &config.Config{Knowledge: config.KnowledgeConfig{ChunkSize: 200, ChunkOverlap: 20, TopK: 1}}
+	svc := NewLLMService(cfg, repo, nil,
+		WithProvider("openai", provider),
+		WithEmbeddingProvider("openai", keywordEmbedder{}),
+	)
+	knowledge := NewKnowledgeService(cfg.Knowledge, svc, repository.NewMemoryVectorIndex())
+	ctx := context.Background()
+
+	c, err := knowledge.CreateCollection(ctx, ports.Collection{UserID: "user-123", Name: "support"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.EmbeddingProvider != "openai" {
+		t.Fatalf("expected the default embedding provider, got %q", c.EmbeddingProvider)
+	}
+	docs := []DocumentUpload{
+		{Title: "Refunds", ContentType: "markdown", Content: "# Refunds\n\nA refund is issued within five days of the refund request."},
+		{Title: "Shipping", ContentType: "text/html", Content: "<h1>Shipping</h1><p>Shipping is free over $50.</p>"},
+	}
+	for _, d := range docs {
+		doc, err := knowledge.AddDocument(ctx, "user-123", c.ID, d)
+		if err != nil {
+			t.Fatalf("unexpected error adding %s: %v", d.Title, err)
+		}
+		if doc.Chunks == 0 {
+			t.Fatalf("expected chunks for %s", d.Title)
+		}
+	}
+
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "How long does a refund take?", CollectionID: c.ID}
+	resp, _, err := svc.ProcessRequest(ctx, req, "openai")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Citations) != 1 || resp.Citations[0].DocumentTitle != "Refunds" || resp.Citations[0].Index != 1 {
+		t.Fatalf("expected one citation of the refunds document, got %+v", resp.Citations)
+	}
+	sent := provider.lastRequest().Messages
+	if len(sent) != 2 || sent[0].Role != ports.RoleSystem || !strings.Contains(sent[0].Content, "[1] Refunds") || sent[1].Content != req.Prompt {
+		t.Fatalf("expected excerpts before the question, got %+v", sent)
+	}
+
+	// Collections are private to their owner.
+	req.UserID = "user-456"
+	if _, _, err := svc.ProcessRequest(ctx, req, "openai"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected collection not found for another user, got %v", err)
+	}
+	if _, err := knowledge.AddDocument(ctx, "user-456", c.ID, docs[0]); !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("expected collection not found for another user, got %v", err)
+	}
+
+	if _, err := knowledge.AddDocument(ctx, "user-123", c.ID, DocumentUpload{ContentType: "application/pdf", Content: "%PDF"}); !errors.Is(err, ErrInvalidDocument) {
+		t.Fatalf("expected invalid document error, got %v", err)
+	}
+	if _, err := knowledge.AddDocument(ctx, "user-123", c.ID, DocumentUpload{Content: "   "}); !errors.Is(err, ErrInvalidDocument) {
+		t.Fatalf("expected invalid document error for empty text, got %v", err)
+	}
+
+	plain := NewKnowledgeService(cfg.Knowledge, NewLLMService(cfg, repo, nil), nil)
+	if _, err := plain.CreateCollection(ctx, ports.Collection{UserID: "user-123", Name: "x"}); !errors.Is(err, ErrKnowledgeNotConfigured) {
+		t.Fatalf("expected knowledge not configured, got %v", err)
+	}
+}
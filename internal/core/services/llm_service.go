@@ -2,144 +2,701 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/willexm1/go-llm-nexus/internal/adapters/llm"
 	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/core/tokens"
+	"github.com/willexm1/go-llm-nexus/internal/core/tools"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
 )
 
 type LLMService struct {
 	providers map[string]ports.LLMProvider
-	repo      ports.Repository
-	cache     ports.Cache
+	embedders map[string]ports.EmbeddingProvider
+	// embeddingModels is the default embedding model per provider.
+	embeddingModels map[string]string
+	catalog         ports.ModelCatalog
+	// allowedModels restricts per-request model overrides, keyed by provider.
+	allowedModels map[string][]string
+	router        *Router
+	repo          ports.Repository
+	cache         ports.Cache
+	defaultTTL    time.Duration
+	maxTTL        time.Duration
+
+	hedge ports.HedgePolicy
+
+	tools         *tools.Registry
+	agentMaxSteps int
+	agentMaxCost  float64
+
+	maxAttachmentBytes int64
+	maxAttachmentTotal int64
+	maxPromptBytes     int
+
+	tokenizer ports.Tokenizer
+	// contextOverflow is OverflowReject or OverflowTruncate.
+	contextOverflow string
+
+	// knowledge grounds requests on collections; nil until a
+	// KnowledgeService is built on the service.
+	knowledge *KnowledgeService
+
+	// templates renders templated requests; nil until a TemplateService is
+	// built on the service.
+	templates *TemplateService
+
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	metrics ports.Metrics
+	// logRedactor is applied to prompts and responses before request logs
+	// are stored.
+	logRedactor logging.Redactor
+
+	// background tracks writes that outlive the request that made them:
+	// request logs and cache stores. Flush waits for them.
+	background sync.WaitGroup
 }
 
-func NewLLMService(cfg *config.Config, repo ports.Repository, cache ports.Cache) *LLMService {
-	providers := make(map[string]ports.LLMProvider)
+// Option configures optional collaborators of the service.
+type Option func(*LLMService)
+
+// WithRequestLogRedaction redacts prompts and responses in stored request
+// logs. By default they are stored in full.
+func WithRequestLogRedaction(r logging.Redactor) Option {
+	return func(s *LLMService) {
+		s.logRedactor = r
+	}
+}
+
+// WithCatalog sets the model catalog used for aliases and pricing. Without
+// it the service builds a one-model-per-provider catalog from the legacy
+// OPENAI_*/GEMINI_* settings.
+func WithCatalog(c ports.ModelCatalog) Option {
+	return func(s *LLMService) {
+		s.catalog = c
+	}
+}
+
+// WithProvider registers a provider under name, taking precedence over the
+// one that would otherwise be built from the API key settings.
+func WithProvider(name string, p ports.LLMProvider) Option {
+	return func(s *LLMService) {
+		s.providers[name] = p
+	}
+}
+
+func NewLLMService(cfg *config.Config, repo ports.Repository, cache ports.Cache, opts ...Option) *LLMService {
+	defaultTTL := cfg.Cache.TTL
+	if defaultTTL <= 0 {
+		defaultTTL = defaultCacheTTL
+	}
+
+	s := &LLMService{
+		providers: make(map[string]ports.LLMProvider),
+		embedders: make(map[string]ports.EmbeddingProvider),
+		embeddingModels: map[string]string{
+			"openai": cfg.LLM.OpenAIEmbeddingModel,
+			"gemini": cfg.LLM.GeminiEmbeddingModel,
+		},
+		allowedModels: map[string][]string{
+			"openai": cfg.LLM.OpenAIAllowedModels,
+			"gemini": cfg.LLM.GeminiAllowedModels,
+		},
+		repo:       repo,
+		cache:      cache,
+		defaultTTL: defaultTTL,
+		maxTTL:     cfg.Cache.MaxTTL,
+		hedge: ports.HedgePolicy{
+			Mode:      ports.HedgeMode(cfg.Hedge.Mode),
+			Delay:     cfg.Hedge.Delay,
+			Secondary: cfg.Hedge.Secondary,
+		},
+		agentMaxSteps: cfg.Agent.MaxSteps,
+		agentMaxCost:  cfg.Agent.MaxCostUSD,
+
+		maxAttachmentBytes: cfg.Uploads.MaxBytes,
+		maxAttachmentTotal: cfg.Uploads.MaxTotalBytes,
+		maxPromptBytes:     cfg.Limits.MaxPromptBytes,
+
+		contextOverflow: OverflowReject,
 
-	if cfg.LLM.OpenAIKey != "" {
-		providers["openai"] = llm.NewOpenAIProvider(llm.OpenAIConfig{
-			APIKey:          cfg.LLM.OpenAIKey,
-			Model:           cfg.LLM.OpenAIModel,
-			InputCostPer1K:  cfg.LLM.OpenAIInputCostPer1K,
-			OutputCostPer1K: cfg.LLM.OpenAIOutputCostPer1K,
+		metrics:     nopMetrics{},
+		logRedactor: logging.Redactor{Policy: logging.RedactNone},
+	}
+	if s.agentMaxSteps <= 0 {
+		s.agentMaxSteps = defaultAgentMaxSteps
+	}
+	if s.maxAttachmentBytes <= 0 {
+		s.maxAttachmentBytes = defaultMaxAttachmentBytes
+	}
+	if s.maxAttachmentTotal <= 0 {
+		s.maxAttachmentTotal = defaultMaxAttachmentTotalBytes
+	}
+	if s.maxPromptBytes <= 0 {
+		s.maxPromptBytes = defaultMaxPromptBytes
+	}
+	if cfg.Limits.ContextOverflow == OverflowTruncate {
+		s.contextOverflow = OverflowTruncate
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.catalog == nil {
+		s.catalog = catalog.Legacy(cfg.LLM)
+	}
+	if s.tokenizer == nil {
+		s.tokenizer = tokens.New()
+	}
+
+	if _, ok := s.providers["openai"]; !ok && cfg.LLM.OpenAIKey != "" {
+		s.providers["openai"] = llm.NewOpenAIProvider(llm.OpenAIConfig{
+			APIKey:             cfg.LLM.OpenAIKey,
+			Model:              defaultModel(s.catalog, "openai", cfg.LLM.OpenAIModel),
+			InputCostPer1K:     cfg.LLM.OpenAIInputCostPer1K,
+			OutputCostPer1K:    cfg.LLM.OpenAIOutputCostPer1K,
+			EmbeddingModel:     cfg.LLM.OpenAIEmbeddingModel,
+			EmbeddingCostPer1K: cfg.LLM.OpenAIEmbeddingCostPer1K,
+			Catalog:            s.catalog,
 		})
 	}
-	if cfg.LLM.GeminiKey != "" {
-		providers["gemini"] = llm.NewGeminiProvider(llm.GeminiConfig{
-			APIKey:          cfg.LLM.GeminiKey,
-			Model:           cfg.LLM.GeminiModel,
-			InputCostPer1K:  cfg.LLM.GeminiInputCostPer1K,
-			OutputCostPer1K: cfg.LLM.GeminiOutputCostPer1K,
+	if _, ok := s.providers["gemini"]; !ok && cfg.LLM.GeminiKey != "" {
+		s.providers["gemini"] = llm.NewGeminiProvider(llm.GeminiConfig{
+			APIKey:             cfg.LLM.GeminiKey,
+			Model:              defaultModel(s.catalog, "gemini", cfg.LLM.GeminiModel),
+			InputCostPer1K:     cfg.LLM.GeminiInputCostPer1K,
+			OutputCostPer1K:    cfg.LLM.GeminiOutputCostPer1K,
+			EmbeddingModel:     cfg.LLM.GeminiEmbeddingModel,
+			EmbeddingCostPer1K: cfg.LLM.GeminiEmbeddingCostPer1K,
+			Catalog:            s.catalog,
 		})
 	}
 
-	return &LLMService{
-		providers: providers,
-		repo:      repo,
-		cache:     cache,
+	// Chat providers that can also embed serve /embeddings unless an
+	// embedding provider was registered under their name.
+	for name, p := range s.providers {
+		if e, ok := p.(ports.EmbeddingProvider); ok {
+			if _, taken := s.embedders[name]; !taken {
+				s.embedders[name] = e
+			}
+		}
+	}
+
+	// The cheapest strategy compares each provider's default model.
+	prices := make(map[string]float64)
+	for _, m := range s.catalog.Models() {
+		if d, ok := s.catalog.Default(m.Provider); ok {
+			prices[m.Provider] = d.InputCostPer1K + d.OutputCostPer1K
+		}
+	}
+	s.router = NewRouter(cfg.Routing.Strategy, prices, cfg.Routing.Weights)
+
+	return s
+}
+
+// defaultModel returns the catalog's default upstream model for provider, or fallback.
+func defaultModel(c ports.ModelCatalog, provider, fallback string) string {
+	if m, ok := c.Default(provider); ok {
+		return m.UpstreamID
 	}
+	return fallback
+}
+
+// Catalog exposes the models and aliases requests may use.
+func (s *LLMService) Catalog() ports.ModelCatalog {
+	return s.catalog
+}
+
+// HasProvider reports whether provider is configured with credentials.
+func (s *LLMService) HasProvider(provider string) bool {
+	_, ok := s.providers[provider]
+	return ok
+}
+
+// requestPlan is everything decided about a request before a provider is called.
+type requestPlan struct {
+	req          ports.LLMRequest
+	providerName string
+	fingerprint  string
+	cacheKey     string
+	decision     RoutingDecision
+	provider     ports.LLMProvider
+	// schema validates JSON answers for json_schema response formats.
+	schema *jsonschema.Schema
+	// citations are the knowledge chunks retrieved into the prompt.
+	citations []ports.Citation
 }
 
 func (s *LLMService) ProcessRequest(ctx context.Context, req ports.LLMRequest, providerName string) (*ports.LLMResponse, string, error) {
-	if err := s.ensureUser(ctx, req.UserID); err != nil {
+	ctx, span := tracer.Start(ctx, "LLMService.ProcessRequest")
+	done := s.beginRequest()
+	resp, used, err := s.processRequest(ctx, req, providerName)
+	done(resp, used, err)
+	span.SetAttributes(attribute.String("nexus.provider", used))
+	endSpan(span, err)
+	return resp, used, err
+}
+
+func (s *LLMService) processRequest(ctx context.Context, req ports.LLMRequest, providerName string) (*ports.LLMResponse, string, error) {
+	plan, cached, err := s.prepare(ctx, req, providerName)
+	if err != nil {
 		return nil, "", err
 	}
+	if cached != nil {
+		return cached, CachedProvider, nil
+	}
+	req = plan.req
+	decision := plan.decision
 
-	// 1. Check Cache (if configured). Incorporate user to avoid cross-user leakage.
-	cacheKey := fmt.Sprintf("%s:%s:%s", providerName, req.UserID, req.Prompt)
-	if s.cache != nil {
-		if cached, err := s.cache.Get(ctx, cacheKey); err == nil && cached != "" {
-			return &ports.LLMResponse{Content: cached}, "cache", nil
+	// 3. Call Provider (hedged against a second provider when enabled)
+	hedge, err := s.hedgePolicy(req)
+	if err != nil {
+		return nil, "", err
+	}
+	var resp *ports.LLMResponse
+	used := decision.Provider
+	secondary := s.hedgeSecondary(hedge, decision.Provider)
+	// The secondary answers with its default model, which must accept the media too.
+	if secondary != "" && req.HasMedia() && !s.supportsMedia(secondary, "") {
+		secondary = ""
+	}
+	if secondary != "" {
+		resp, used, err = s.hedged(ctx, req, decision, secondary, hedge)
+	} else {
+		var latency time.Duration
+		resp, latency, err = s.generate(ctx, decision.Provider, req)
+		if err == nil {
+			s.logRequest(ctx, s.requestLog(req, decision.Provider, decision, resp, latency))
 		}
 	}
+	if err != nil {
+		return nil, plan.provider.Name(), err
+	}
+	resp, err = s.enforceFormat(ctx, plan, resp, used)
+	if err != nil {
+		return nil, s.providers[used].Name(), err
+	}
 
-	// 2. Select Provider
-	var provider ports.LLMProvider
-	var ok bool
+	resp.Citations = plan.citations
+	resp.Fingerprint = plan.fingerprint
 
-	if providerName != "" {
-		provider, ok = s.providers[providerName]
+	// 4. Cache Response (Async)
+	s.storeCache(plan, resp)
+
+	return resp, s.providers[used].Name(), nil
+}
+
+// ProcessStream is ProcessRequest for callers that want the answer as it is
+// generated. onChunk receives each content delta in order; the complete
+// response is returned once the stream ends. Cached answers arrive as a
+// single chunk. Hedging does not apply to streams.
+func (s *LLMService) ProcessStream(ctx context.Context, req ports.LLMRequest, providerName string, onChunk func(delta string) error) (*ports.LLMResponse, string, error) {
+	ctx, span := tracer.Start(ctx, "LLMService.ProcessStream")
+	done := s.beginRequest()
+	resp, used, err := s.processStream(ctx, req, providerName, onChunk)
+	done(resp, used, err)
+	span.SetAttributes(attribute.String("nexus.provider", used))
+	endSpan(span, err)
+	return resp, used, err
+}
+
+func (s *LLMService) processStream(ctx context.Context, req ports.LLMRequest, providerName string, onChunk func(delta string) error) (*ports.LLMResponse, string, error) {
+	plan, cached, err := s.prepare(ctx, req, providerName)
+	if err != nil {
+		return nil, "", err
+	}
+	if cached != nil {
+		return cached, CachedProvider, onChunk(cached.Content)
+	}
+	req = plan.req
+
+	name := plan.decision.Provider
+	start := time.Now()
+	done := s.router.Begin(name)
+	spanCtx, span := s.startProviderSpan(ctx, name, req)
+	var resp *ports.LLMResponse
+	if streamer, ok := plan.provider.(ports.StreamingProvider); ok {
+		resp, err = streamer.GenerateStream(spanCtx, req, onChunk)
+	} else {
+		// Providers without native streaming deliver the whole answer at once.
+		resp, err = plan.provider.Generate(spanCtx, req)
+		if err == nil {
+			err = onChunk(resp.Content)
+		}
+	}
+	endProviderSpan(span, resp, err)
+	latency := time.Since(start)
+	done(latency, err)
+	if err != nil {
+		s.observeProviderError(name, err)
+		return nil, plan.provider.Name(), err
+	}
+
+	s.logRequest(ctx, s.requestLog(req, name, plan.decision, resp, latency))
+	// The answer has already been streamed, so a bad one cannot be repaired.
+	if wantsJSON(req.ResponseFormat) && len(resp.ToolCalls) == 0 {
+		parsed, verr := parseStructured(req.ResponseFormat, plan.schema, resp.Content)
+		if verr != nil {
+			return nil, plan.provider.Name(), fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, verr)
+		}
+		resp.JSON = parsed
+	}
+	resp.Citations = plan.citations
+	resp.Fingerprint = plan.fingerprint
+	s.storeCache(plan, resp)
+	return resp, plan.provider.Name(), nil
+}
+
+// prepare validates the caller, consults the cache and picks the provider. A
+// non-nil response means the request was answered from the cache.
+func (s *LLMService) prepare(ctx context.Context, req ports.LLMRequest, providerName string) (*requestPlan, *ports.LLMResponse, error) {
+	if err := s.ensureUser(ctx, req.UserID); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.applyTemplate(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.validateRequest(req); err != nil {
+		return nil, nil, err
+	}
+
+	// 1. Check Cache (if configured). Incorporate user to avoid cross-user leakage.
+	if !validCacheMode(req.CacheMode) {
+		return nil, nil, fmt.Errorf("%w: %q", ErrInvalidCacheMode, req.CacheMode)
+	}
+	if err := validateTools(req); err != nil {
+		return nil, nil, err
+	}
+	if err := s.validateContent(req); err != nil {
+		return nil, nil, err
+	}
+	schema, err := compileResponseFormat(req.ResponseFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Retrieved excerpts become part of the prompt, so they are part of the
+	// fingerprint too: new documents change the cache key.
+	req, citations, err := s.retrieve(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan := &requestPlan{providerName: providerName, fingerprint: Fingerprint(req), schema: schema, citations: citations}
+	plan.cacheKey = cacheKey(providerName, req.UserID, plan.fingerprint)
+	if s.cache != nil && (req.CacheMode == ports.CacheDefault || req.CacheMode == ports.CacheOnly) {
+		if cached, ok := s.lookupCache(ctx, plan.cacheKey); ok {
+			resp := &ports.LLMResponse{Content: cached, Citations: citations, Fingerprint: plan.fingerprint}
+			if wantsJSON(req.ResponseFormat) {
+				// Only validated answers are cached, so this cannot fail.
+				resp.JSON, _ = parseStructured(req.ResponseFormat, schema, cached)
+			}
+			return nil, resp, nil
+		}
+	}
+	if req.CacheMode == ports.CacheOnly {
+		return nil, nil, ErrCacheMiss
+	}
+
+	// 2. Resolve Model and Select Provider
+	if req.Model != "" {
+		name, upstream, err := s.resolveModel(req.Model, providerName)
+		if err != nil {
+			return nil, nil, err
+		}
+		p, ok := s.providers[name]
 		if !ok {
-			return nil, "", fmt.Errorf("provider %s not configured", providerName)
+			return nil, nil, providerNotConfigured(name)
 		}
+		if req.HasMedia() && !s.supportsMedia(name, upstream) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrVisionNotSupported, req.Model)
+		}
+		plan.provider = p
+		plan.decision = RoutingDecision{Provider: name, Reason: fmt.Sprintf("model %s is served by %s", req.Model, name)}
+		req.Model = upstream
+	} else if providerName != "" {
+		p, ok := s.providers[providerName]
+		if !ok {
+			return nil, nil, providerNotConfigured(providerName)
+		}
+		if req.HasMedia() && !s.supportsMedia(providerName, "") {
+			return nil, nil, fmt.Errorf("%w: %s default model", ErrVisionNotSupported, providerName)
+		}
+		plan.provider = p
+		plan.decision = RoutingDecision{Provider: providerName, Reason: "requested by caller"}
 	} else {
-		// Simple fallback strategy: try OpenAI, then Gemini, then HF
-		if p, exists := s.providers["openai"]; exists {
-			provider = p
-		} else if p, exists := s.providers["gemini"]; exists {
-			provider = p
-		} else {
-			return nil, "", fmt.Errorf("no llm providers configured")
+		candidates := s.providerNames()
+		if req.HasMedia() {
+			// Only route to providers whose default model can see the attachments.
+			if candidates = s.mediaProviders(candidates); len(candidates) == 0 {
+				return nil, nil, fmt.Errorf("%w: no configured provider accepts media", ErrVisionNotSupported)
+			}
 		}
+		decision, err := s.router.Route(req.Routing, candidates)
+		if err != nil {
+			return nil, nil, err
+		}
+		plan.provider = s.providers[decision.Provider]
+		plan.decision = decision
+	}
+	info := s.servingModel(plan.decision.Provider, req.Model)
+	if err := s.validateForModel(req, info); err != nil {
+		return nil, nil, err
+	}
+	req, est, err := s.fitContext(req, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkBudget(req, est); err != nil {
+		return nil, nil, err
 	}
 
-	// 3. Call Provider
+	plan.req = req
+	return plan, nil, nil
+}
+
+// storeCache writes a fresh answer to the cache in the background unless the
+// request opted out. Tool calls are not cached: the cache only holds text, and
+// a call is an action the caller should see requested afresh.
+func (s *LLMService) storeCache(plan *requestPlan, resp *ports.LLMResponse) {
+	if s.cache == nil || plan.req.CacheMode == ports.CacheBypass || len(resp.ToolCalls) > 0 {
+		return
+	}
+	ttl := s.cacheTTL(plan.req)
+	tags := cacheTags(plan.providerName, plan.req.UserID, plan.fingerprint)
+	s.goBackground(func() {
+		_ = s.cache.Set(context.Background(), plan.cacheKey, resp.Content, ttl, tags...)
+	})
+}
+
+// generate calls a single provider and feeds the outcome into the router's
+// statistics. Time spent waiting on a batch rate limit is not counted.
+func (s *LLMService) generate(ctx context.Context, name string, req ports.LLMRequest) (*ports.LLMResponse, time.Duration, error) {
+	if err := waitForRateLimit(ctx, name); err != nil {
+		return nil, 0, err
+	}
 	start := time.Now()
-	resp, err := provider.Generate(ctx, req)
+	done := s.router.Begin(name)
+	ctx, span := s.startProviderSpan(ctx, name, req)
+	resp, err := s.providers[name].Generate(ctx, req)
+	endProviderSpan(span, resp, err)
+	latency := time.Since(start)
+	done(latency, err)
 	if err != nil {
-		return nil, provider.Name(), err
+		s.observeProviderError(name, err)
 	}
-	duration := time.Since(start).Milliseconds()
+	return resp, latency, err
+}
 
-	// 4. Cache Response (Async)
-	if s.cache != nil {
-		go func() {
-			_ = s.cache.Set(context.Background(), cacheKey, resp.Content, 1*time.Hour)
-		}()
+// requestLog builds the audit record for one provider call. resp may be nil
+// when the call failed.
+func (s *LLMService) requestLog(req ports.LLMRequest, name string, decision RoutingDecision, resp *ports.LLMResponse, latency time.Duration) ports.RequestLog {
+	log := ports.RequestLog{
+		Prompt:          promptText(req),
+		Provider:        s.providers[name].Name(),
+		Model:           req.Model,
+		DurationMs:      latency.Milliseconds(),
+		UserID:          req.UserID,
+		RoutingStrategy: decision.Strategy,
+		RoutingReason:   decision.Reason,
+		TraceID:         req.TraceID,
+		TraceStep:       req.TraceStep,
+		Attachments:     attachmentMeta(req),
+		CreatedAt:       time.Now(),
 	}
-
-	// 5. Log Request (Async)
-	if s.repo != nil {
-		var promptTokens, completionTokens, totalTokens int32
-		var cost float64
+	if req.Template != nil {
+		log.TemplateName = req.Template.Name
+		log.TemplateVersion = req.Template.Version
+	}
+	if resp != nil {
+		log.Response = resp.Content
+		if resp.Model != "" {
+			log.Model = resp.Model
+		}
 		if resp.Usage != nil {
-			promptTokens = resp.Usage.PromptTokens
-			completionTokens = resp.Usage.CompletionTokens
-			totalTokens = resp.Usage.TotalTokens
-			cost = resp.Usage.CostUSD
-		}
-		go func() {
-			_ = s.repo.LogRequest(context.Background(), ports.RequestLog{
-				Prompt:           req.Prompt,
-				Provider:         provider.Name(),
-				Response:         resp.Content,
-				DurationMs:       duration,
-				UserID:           req.UserID,
-				PromptTokens:     promptTokens,
-				CompletionTokens: completionTokens,
-				TotalTokens:      totalTokens,
-				CostUSD:          cost,
-				CreatedAt:        time.Now(),
-			})
-		}()
-	}
-
-	return resp, provider.Name(), nil
+			log.PromptTokens = resp.Usage.PromptTokens
+			log.CompletionTokens = resp.Usage.CompletionTokens
+			log.TotalTokens = resp.Usage.TotalTokens
+			log.CostUSD = resp.Usage.CostUSD
+		}
+	}
+	return log
+}
+
+// promptText is the prompt as recorded in request logs. Conversations are
+// flattened to one "role: content" line per message.
+func promptText(req ports.LLMRequest) string {
+	if len(req.Messages) == 0 {
+		return req.Prompt
+	}
+	var b strings.Builder
+	for i, m := range req.Messages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.Role + ": " + messageText(m))
+	}
+	return b.String()
+}
+
+// messageText is a message's text with media replaced by placeholders;
+// attachments are logged separately as metadata.
+func messageText(m ports.Message) string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var b strings.Builder
+	for _, p := range m.Parts {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		if p.Type == ports.PartText {
+			b.WriteString(p.Text)
+		} else {
+			b.WriteString("[" + string(p.Type) + "]")
+		}
+	}
+	return b.String()
+}
+
+// logRequest persists a request log in the background, tagged with the
+// request ID in ctx. The write is traced as part of the request in ctx but
+// outlives it.
+func (s *LLMService) logRequest(ctx context.Context, log ports.RequestLog) {
+	if s.repo == nil {
+		return
+	}
+	log.RequestID = logging.RequestID(ctx)
+	log.Prompt = s.logRedactor.Apply(log.Prompt)
+	log.Response = s.logRedactor.Apply(log.Response)
+	ctx = trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	s.metrics.AddPendingLogs(1)
+	s.goBackground(func() {
+		defer s.metrics.AddPendingLogs(-1)
+		ctx, span := tracer.Start(ctx, "LLMService.logRequest")
+		endSpan(span, s.repo.LogRequest(ctx, log))
+	})
+}
+
+// goBackground runs fn on its own goroutine, tracked for Flush.
+func (s *LLMService) goBackground(fn func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn()
+	}()
+}
+
+// Flush waits for background request logs and cache writes to finish, or
+// for ctx to end. Call it once nothing can start new requests.
+func (s *LLMService) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("request logs and cache writes still pending: %w", ctx.Err())
+	}
+}
+
+var (
+	// ErrUnknownModel is returned when a request names a model that is neither in the catalog nor allow-listed.
+	ErrUnknownModel = ports.NewError(ports.ErrInvalidArgument, "unknown model")
+	// ErrModelProviderMismatch is returned when the requested provider cannot serve the requested model.
+	ErrModelProviderMismatch = ports.NewError(ports.ErrInvalidArgument, "model not served by provider")
+	// ErrModelNotAllowed is returned when a model is outside its provider's allow-list.
+	ErrModelNotAllowed = ports.NewError(ports.ErrInvalidArgument, "model not allowed")
+	// ErrUserNotFound is returned for requests billed to an unknown user.
+	ErrUserNotFound = ports.NewError(ports.ErrNotFound, "user not found")
+	// ErrUsersNotConfigured is returned when the service has no user storage.
+	ErrUsersNotConfigured = ports.NewError(ports.ErrNotConfigured, "user storage not configured")
+)
+
+// resolveModel maps a requested model (catalog alias, catalog ID or an
+// allow-listed upstream ID) to the provider that serves it and the upstream
+// model ID the adapter should send.
+func (s *LLMService) resolveModel(model, providerName string) (string, string, error) {
+	var provider, upstream string
+	if info, ok := s.catalog.Resolve(model); ok {
+		provider, upstream = info.Provider, info.UpstreamID
+	} else {
+		// Models outside the catalog are accepted only when a provider allow-lists them.
+		for _, name := range orderByPriority(s.providerNames()) {
+			if (providerName == "" || providerName == name) && slices.Contains(s.allowedModels[name], model) {
+				provider, upstream = name, model
+				break
+			}
+		}
+		if provider == "" {
+			return "", "", fmt.Errorf("%w: %q", ErrUnknownModel, model)
+		}
+	}
+
+	if providerName != "" && providerName != provider {
+		return "", "", fmt.Errorf("%w: model %s is served by %s, not %s", ErrModelProviderMismatch, model, provider, providerName)
+	}
+	if allowed := s.allowedModels[provider]; len(allowed) > 0 && !slices.Contains(allowed, upstream) {
+		return "", "", fmt.Errorf("%w: %s is not enabled for %s", ErrModelNotAllowed, upstream, provider)
+	}
+	return provider, upstream, nil
+}
+
+func (s *LLMService) providerNames() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	return names
 }
 
 func (s *LLMService) RegisterUser(ctx context.Context, name string) (*ports.User, error) {
 	if s.repo == nil {
-		return nil, fmt.Errorf("user storage not configured")
+		return nil, ErrUsersNotConfigured
 	}
 	if name == "" {
-		return nil, fmt.Errorf("name is required")
+		return nil, ports.NewError(ports.ErrInvalidArgument, "name is required")
 	}
 	return s.repo.CreateUser(ctx, name)
 }
 
-func (s *LLMService) ensureUser(ctx context.Context, userID string) error {
+func (s *LLMService) ensureUser(ctx context.Context, userID string) (err error) {
 	if userID == "" {
-		return fmt.Errorf("user_id is required")
+		return ports.NewError(ports.ErrInvalidArgument, "user_id is required")
 	}
 	if s.repo == nil {
-		return fmt.Errorf("user storage not configured")
+		return ErrUsersNotConfigured
+	}
+	ctx, span := tracer.Start(ctx, "LLMService.ensureUser")
+	defer func() { endSpan(span, err) }()
+	_, err = s.repo.GetUser(ctx, userID)
+	if errors.Is(err, ports.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
 	}
-	_, err := s.repo.GetUser(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to load user: %w", err)
 	}
 	return nil
 }
+
+// providerNotConfigured reports a request for a provider this server has no
+// credentials for.
+func providerNotConfigured(name string) error {
+	return &ports.ProviderError{Provider: name, Class: ports.ErrProviderUnavailable, Err: errors.New("not configured")}
+}
@@ -2,21 +2,71 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
 	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+	"github.com/willexm1/go-llm-nexus/internal/logging"
 )
 
 // Mocks
+
+// mockProvider is the fake provider shared by the service tests. It answers
+// "mock response from <name>" unless respond is set, which is given the
+// 1-based call number and may return (nil, nil) for the default answer. A
+// delay holds every answer back; a call cancelled meanwhile reports the usage
+// billed so far with its error. It counts calls, tracks the most in flight
+// at once and keeps the last request.
 type mockProvider struct {
-	name string
+	name    string
+	delay   time.Duration
+	respond func(call int, req ports.LLMRequest) (*ports.LLMResponse, error)
+
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	peak     atomic.Int32
+
+	mu   sync.Mutex
+	last ports.LLMRequest
 }
 
 func (m *mockProvider) Generate(ctx context.Context, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	call := int(m.calls.Add(1))
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	m.mu.Lock()
+	m.last = req
+	m.mu.Unlock()
+
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			// The prompt was billed before the call was cancelled.
+			return &ports.LLMResponse{Usage: &ports.UsageInfo{PromptTokens: 5, TotalTokens: 5, CostUSD: 0.0002}}, ctx.Err()
+		}
+	}
+	if m.respond != nil {
+		if resp, err := m.respond(call, req); resp != nil || err != nil {
+			return resp, err
+		}
+	}
 	return &ports.LLMResponse{
+		Model:   req.Model,
 		Content: "mock response from " + m.name,
 		Usage: &ports.UsageInfo{
 			PromptTokens:     5,
@@ -28,8 +78,24 @@ func (m *mockProvider) Generate(ctx context.Context, req ports.LLMRequest) (*por
 }
 func (m *mockProvider) Name() string { return m.name }
 
+// lastRequest returns the request of the latest call.
+func (m *mockProvider) lastRequest() ports.LLMRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// upstreamFailure fails every call the way an adapter reports an upstream
+// error, response body included.
+func upstreamFailure(name string, class error) func(int, ports.LLMRequest) (*ports.LLMResponse, error) {
+	return func(int, ports.LLMRequest) (*ports.LLMResponse, error) {
+		return nil, &ports.ProviderError{Provider: name, Class: class, Err: errors.New(`api error: {"error":"secret org-123"}`)}
+	}
+}
+
 type mockRepo struct {
 	users map[string]*ports.User
+	keys  map[string]string
 }
 
 func (m *mockRepo) LogRequest(ctx context.Context, log ports.RequestLog) error { return nil }
@@ -45,28 +111,117 @@ func (m *mockRepo) GetUser(ctx context.Context, id string) (*ports.User, error)
 	if user, ok := m.users[id]; ok {
 		return user, nil
 	}
-	return nil, fmt.Errorf("user not found")
+	return nil, fmt.Errorf("user %s: %w", id, ports.ErrNotFound)
+}
+func (m *mockRepo) CreateAPIKey(ctx context.Context, key ports.APIKey, keyHash string) (*ports.APIKey, error) {
+	if m.keys == nil {
+		m.keys = make(map[string]string)
+	}
+	m.keys[keyHash] = key.UserID
+	key.ID = "key-" + key.Prefix
+	return &key, nil
+}
+func (m *mockRepo) GetUserByAPIKey(ctx context.Context, keyHash string) (*ports.User, error) {
+	if userID, ok := m.keys[keyHash]; ok {
+		return m.GetUser(ctx, userID)
+	}
+	return nil, fmt.Errorf("api key: %w", ports.ErrNotFound)
 }
 
 type mockCache struct {
+	mu   sync.Mutex
 	data map[string]string
+	tags map[string][]string
 }
 
 func (m *mockCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if val, ok := m.data[key]; ok {
 		return val, nil
 	}
 	return "", nil
 }
-func (m *mockCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+func (m *mockCache) Set(ctx context.Context, key string, value string, ttl time.Duration, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.data[key] = value
+	if m.tags == nil {
+		m.tags = make(map[string][]string)
+	}
+	for _, tag := range tags {
+		m.tags[tag] = append(m.tags[tag], key)
+	}
+	return nil
+}
+func (m *mockCache) Delete(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if _, ok := m.data[key]; ok {
+			delete(m.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+func (m *mockCache) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var keys []string
+	m.mu.Lock()
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.Unlock()
+	return m.Delete(ctx, keys...)
+}
+func (m *mockCache) InvalidateTag(ctx context.Context, tag string) (int64, error) {
+	m.mu.Lock()
+	keys := m.tags[tag]
+	delete(m.tags, tag)
+	m.mu.Unlock()
+	return m.Delete(ctx, keys...)
+}
+func (m *mockCache) Count(ctx context.Context, prefix string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// loggingRepo is a mockRepo that passes every request log to logs.
+type loggingRepo struct {
+	mockRepo
+	logs chan ports.RequestLog
+}
+
+func (m *loggingRepo) LogRequest(ctx context.Context, log ports.RequestLog) error {
+	m.logs <- log
 	return nil
 }
 
+// newTestRepo returns a repository that knows user-123 and user-456 and
+// passes every request log to its logs channel.
+func newTestRepo(t *testing.T) *loggingRepo {
+	t.Helper()
+	return &loggingRepo{
+		mockRepo: mockRepo{users: map[string]*ports.User{
+			"user-123": {ID: "user-123", Name: "Test", CreatedAt: time.Now()},
+			"user-456": {ID: "user-456", Name: "Other", CreatedAt: time.Now()},
+		}},
+		logs: make(chan ports.RequestLog, 256),
+	}
+}
+
 func TestLLMService_ProcessRequest(t *testing.T) {
-	repo := &mockRepo{users: map[string]*ports.User{
-		"user-123": {ID: "user-123", Name: "Test", CreatedAt: time.Now()},
-	}}
+	repo := newTestRepo(t)
 	cache := &mockCache{data: make(map[string]string)}
 	cfg := &config.Config{}
 	svc := NewLLMService(cfg, repo, cache)
@@ -91,3 +246,432 @@ func TestLLMService_ProcessRequest(t *testing.T) {
 		t.Fatalf("expected usage data in response")
 	}
 }
+
+func TestLLMService_CacheModes(t *testing.T) {
+	repo := newTestRepo(t)
+	cache := &mockCache{data: make(map[string]string)}
+	svc := NewLLMService(&config.Config{}, repo, cache)
+	svc.providers = map[string]ports.LLMProvider{
+		"mock": &mockProvider{name: "mock"},
+	}
+
+	ctx := context.Background()
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Hello", CacheMode: ports.CacheOnly}
+
+	if _, _, err := svc.ProcessRequest(ctx, req, "mock"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("expected cache miss for cache-only request, got %v", err)
+	}
+
+	fingerprint := Fingerprint(req)
+	_ = cache.Set(ctx, cacheKey("mock", req.UserID, fingerprint), "cached answer", time.Minute, cacheTags("mock", req.UserID, fingerprint)...)
+
+	resp, provider, err := svc.ProcessRequest(ctx, req, "mock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != CachedProvider || resp.Content != "cached answer" {
+		t.Fatalf("expected cached answer, got %q from %s", resp.Content, provider)
+	}
+
+	req.CacheMode = ports.CacheBypass
+	_, provider, err = svc.ProcessRequest(ctx, req, "mock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "mock" {
+		t.Fatalf("expected bypass to reach the provider, got %s", provider)
+	}
+
+	req.CacheMode = "sometimes"
+	if _, _, err := svc.ProcessRequest(ctx, req, "mock"); !errors.Is(err, ErrInvalidCacheMode) {
+		t.Fatalf("expected invalid cache mode error, got %v", err)
+	}
+
+	stats, err := svc.CacheStats(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	deleted, err := svc.PurgeCache(ctx, CachePurge{UserID: "user-123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 entry purged, got %d", deleted)
+	}
+	if _, err := svc.PurgeCache(ctx, CachePurge{}); !errors.Is(err, ErrEmptyPurge) {
+		t.Fatalf("expected empty purge error, got %v", err)
+	}
+}
+
+func TestLLMService_PurgeCacheByUser(t *testing.T) {
+	cache := &mockCache{data: make(map[string]string)}
+	svc := NewLLMService(&config.Config{}, nil, cache, WithProvider("mock", &mockProvider{name: "mock"}))
+	ctx := context.Background()
+
+	// "user-1" is a prefix of "user-1:eu" up to the key's own separator.
+	for _, userID := range []string{"user-1", "user-1:eu"} {
+		_ = cache.Set(ctx, cacheKey("mock", userID, "abc"), "answer", time.Minute, cacheTags("mock", userID, "abc")...)
+	}
+	deleted, err := svc.PurgeCache(ctx, CachePurge{Provider: "mock", UserID: "user-1"})
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected only user-1's entry purged, got %d (%v)", deleted, err)
+	}
+	if _, ok := cache.data[cacheKey("mock", "user-1:eu", "abc")]; !ok {
+		t.Fatal("expected user-1:eu's entry to be kept")
+	}
+}
+
+func TestLLMService_Hedging(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil)
+	svc.providers = map[string]ports.LLMProvider{
+		"slow": &mockProvider{name: "slow", delay: time.Second},
+		"fast": &mockProvider{name: "fast"},
+	}
+
+	req := ports.LLMRequest{
+		UserID: "user-123",
+		Prompt: "Hello",
+		Hedge:  &ports.HedgePolicy{Mode: ports.HedgeDelay, Delay: 10 * time.Millisecond, Secondary: "fast"},
+	}
+	start := time.Now()
+	_, provider, err := svc.ProcessRequest(context.Background(), req, "slow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "fast" {
+		t.Fatalf("expected hedged secondary to win, got %s", provider)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("hedge did not cut latency: took %v", elapsed)
+	}
+
+	logs := make(map[string]ports.RequestLog)
+	for i := 0; i < 2; i++ {
+		select {
+		case log := <-repo.logs:
+			logs[log.HedgeRole] = log
+		case <-time.After(time.Second):
+			t.Fatal("expected both hedged attempts to be logged")
+		}
+	}
+	if !logs["secondary"].HedgeWinner || logs["secondary"].CostUSD == 0 {
+		t.Fatalf("expected winning secondary with usage, got %+v", logs["secondary"])
+	}
+	if logs["primary"].HedgeWinner || logs["primary"].Error == "" || logs["primary"].PromptTokens != 5 || logs["primary"].CostUSD == 0 {
+		t.Fatalf("expected cancelled primary with its partial usage, got %+v", logs["primary"])
+	}
+}
+
+func TestLLMService_HedgeSecondaryModel(t *testing.T) {
+	repo := newTestRepo(t)
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "slow-1", Provider: "slow"},
+		{ID: "broken-1", Provider: "broken"},
+	}, nil, map[string]string{"slow": "slow-1", "broken": "broken-1"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	svc := NewLLMService(&config.Config{}, repo, nil, WithCatalog(models),
+		WithProvider("slow", &mockProvider{name: "slow", delay: 20 * time.Millisecond}),
+		WithProvider("broken", &mockProvider{name: "broken", respond: upstreamFailure("broken", ports.ErrProviderUnavailable)}))
+
+	req := ports.LLMRequest{
+		UserID: "user-123",
+		Prompt: "Hello",
+		Hedge:  &ports.HedgePolicy{Mode: ports.HedgeRace, Secondary: "broken"},
+	}
+	if _, provider, err := svc.ProcessRequest(context.Background(), req, "slow"); err != nil || provider != "slow" {
+		t.Fatalf("expected the primary to answer, got %s (%v)", provider, err)
+	}
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if log := <-repo.logs; log.HedgeRole == "secondary" && log.Model != "broken-1" {
+			t.Fatalf("expected the secondary logged with its own model, got %q", log.Model)
+		}
+	}
+}
+
+func TestLLMService_ModelOverride(t *testing.T) {
+	repo := newTestRepo(t)
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			OpenAIModel:         "gpt-4o",
+			OpenAIAllowedModels: []string{"gpt-4o", "gpt-4o-mini"},
+		},
+	}
+	svc := NewLLMService(cfg, repo, nil)
+	svc.providers = map[string]ports.LLMProvider{
+		"openai": &mockProvider{name: "openai"},
+		"gemini": &mockProvider{name: "gemini"},
+	}
+	ctx := context.Background()
+
+	// Not in the catalog, but allow-listed for openai.
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Hello", Model: "gpt-4o-mini"}
+	resp, provider, err := svc.ProcessRequest(ctx, req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "openai" || resp.Model != "gpt-4o-mini" {
+		t.Fatalf("expected gpt-4o-mini on openai, got %s on %s", resp.Model, provider)
+	}
+	select {
+	case log := <-repo.logs:
+		if log.Model != "gpt-4o-mini" {
+			t.Fatalf("expected model in request log, got %q", log.Model)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not logged")
+	}
+
+	req.Model = "gpt-4-turbo"
+	if _, _, err := svc.ProcessRequest(ctx, req, "openai"); !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("expected unknown model error, got %v", err)
+	}
+	req.Model = "gpt-4o"
+	if _, _, err := svc.ProcessRequest(ctx, req, "gemini"); !errors.Is(err, ErrModelProviderMismatch) {
+		t.Fatalf("expected provider mismatch error, got %v", err)
+	}
+
+	svc.allowedModels["openai"] = []string{"gpt-4o-mini"}
+	if _, _, err := svc.ProcessRequest(ctx, req, ""); !errors.Is(err, ErrModelNotAllowed) {
+		t.Fatalf("expected model not allowed error, got %v", err)
+	}
+}
+
+func TestLLMService_ProcessStream(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil)
+	svc.providers = map[string]ports.LLMProvider{
+		"mock": &mockProvider{name: "mock"},
+	}
+
+	req := ports.LLMRequest{
+		UserID: "user-123",
+		Messages: []ports.Message{
+			{Role: ports.RoleSystem, Content: "Be brief."},
+			{Role: ports.RoleUser, Content: "Hello"},
+		},
+	}
+	var chunks []string
+	resp, provider, err := svc.ProcessStream(context.Background(), req, "mock", func(delta string) error {
+		chunks = append(chunks, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "mock" {
+		t.Fatalf("expected provider 'mock', got %s", provider)
+	}
+	if strings.Join(chunks, "") != resp.Content {
+		t.Fatalf("chunks %q do not add up to content %q", chunks, resp.Content)
+	}
+}
+
+func TestLLMService_APIKeys(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil)
+	ctx := context.Background()
+
+	plaintext, key, err := svc.CreateAPIKey(ctx, "user-123", "laptop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, key.Prefix) {
+		t.Fatalf("expected key %q to start with prefix %q", plaintext, key.Prefix)
+	}
+
+	user, err := svc.AuthenticateAPIKey(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.ID != "user-123" {
+		t.Fatalf("expected key to belong to user-123, got %s", user.ID)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, plaintext+"x"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected invalid api key error, got %v", err)
+	}
+}
+
+// callFirstTool asks for the request's first tool until it is sent a tool
+// result, then answers with it.
+func callFirstTool(_ int, req ports.LLMRequest) (*ports.LLMResponse, error) {
+	last := req.Conversation()[len(req.Conversation())-1]
+	if last.Role == ports.RoleTool {
+		return &ports.LLMResponse{Content: "It is " + last.Content, FinishReason: "stop"}, nil
+	}
+	return &ports.LLMResponse{
+		ToolCalls:    []ports.ToolCall{{ID: "call_1", Name: req.Tools[0].Name, Arguments: json.RawMessage(`{"city":"Lagos"}`)}},
+		FinishReason: "tool_calls",
+	}, nil
+}
+
+func TestLLMService_Tools(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil, WithProvider("tools", &mockProvider{name: "tools", respond: callFirstTool}))
+	ctx := context.Background()
+
+	weather := ports.Tool{
+		Name:       "get_weather",
+		Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+	}
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Weather in Lagos?", Tools: []ports.Tool{weather}}
+	resp, _, err := svc.ProcessRequest(ctx, req, "tools")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_weather" {
+		t.Fatalf("expected a get_weather call, got %+v", resp.ToolCalls)
+	}
+	if Fingerprint(req) == Fingerprint(ports.LLMRequest{UserID: "user-123", Prompt: "Weather in Lagos?"}) {
+		t.Fatalf("expected tools to change the fingerprint")
+	}
+
+	req.Messages = []ports.Message{
+		{Role: ports.RoleUser, Content: "Weather in Lagos?"},
+		{Role: ports.RoleAssistant, ToolCalls: resp.ToolCalls},
+		{Role: ports.RoleTool, ToolCallID: "call_1", Content: "sunny"},
+	}
+	resp, _, err = svc.ProcessRequest(ctx, req, "tools")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "It is sunny" {
+		t.Fatalf("expected the tool result to reach the provider, got %q", resp.Content)
+	}
+
+	invalid := []ports.LLMRequest{
+		{UserID: "user-123", Prompt: "hi", Tools: []ports.Tool{weather, weather}},
+		{UserID: "user-123", Prompt: "hi", Tools: []ports.Tool{{Name: "x", Parameters: json.RawMessage(`"nope"`)}}},
+		{UserID: "user-123", Prompt: "hi", ToolChoice: ports.ToolChoiceRequired},
+		{UserID: "user-123", Prompt: "hi", Tools: []ports.Tool{weather}, ToolChoice: "get_time"},
+		{UserID: "user-123", Messages: []ports.Message{{Role: ports.RoleTool, Content: "sunny"}}},
+	}
+	for i, r := range invalid {
+		if _, _, err := svc.ProcessRequest(ctx, r, "tools"); !errors.Is(err, ErrInvalidTools) {
+			t.Fatalf("case %d: expected invalid tools error, got %v", i, err)
+		}
+	}
+}
+
+// replies answers with the given contents in order, one per call, and keeps
+// repeating the last.
+func replies(contents ...string) func(int, ports.LLMRequest) (*ports.LLMResponse, error) {
+	return func(call int, _ ports.LLMRequest) (*ports.LLMResponse, error) {
+		return &ports.LLMResponse{Content: contents[min(call, len(contents))-1], Usage: &ports.UsageInfo{TotalTokens: 10}}, nil
+	}
+}
+
+func TestLLMService_StructuredOutput(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	format := &ports.ResponseFormat{
+		Type:   ports.ResponseFormatJSONSchema,
+		Schema: json.RawMessage(`{"type":"object","properties":{"age":{"type":"integer"}},"required":["age"]}`),
+	}
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "How old?", ResponseFormat: format}
+
+	provider := &mockProvider{name: "scripted", respond: replies("```json\n{\"age\": 42}\n```")}
+	svc := NewLLMService(&config.Config{}, repo, nil, WithProvider("scripted", provider))
+	resp, _, err := svc.ProcessRequest(ctx, req, "scripted")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.JSON) != `{"age":42}` {
+		t.Fatalf("expected fenced JSON to be parsed, got %s", resp.JSON)
+	}
+
+	provider = &mockProvider{name: "scripted", respond: replies(`{"age": "old"}`, `{"age": 42}`)}
+	svc = NewLLMService(&config.Config{}, repo, nil, WithProvider("scripted", provider))
+	if _, _, err := svc.ProcessRequest(ctx, req, "scripted"); !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Fatalf("expected invalid structured output without repair, got %v", err)
+	}
+
+	provider.calls.Store(0)
+	repaired := *format
+	repaired.Repair = true
+	req.ResponseFormat = &repaired
+	resp, _, err = svc.ProcessRequest(ctx, req, "scripted")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.JSON) != `{"age":42}` || provider.calls.Load() != 2 {
+		t.Fatalf("expected a repaired answer after 2 calls, got %s after %d", resp.JSON, provider.calls.Load())
+	}
+	if resp.Usage.TotalTokens != 20 {
+		t.Fatalf("expected usage of both attempts, got %d", resp.Usage.TotalTokens)
+	}
+
+	req.ResponseFormat = &ports.ResponseFormat{Type: ports.ResponseFormatJSONSchema, Schema: json.RawMessage(`{"type": 7}`)}
+	if _, _, err := svc.ProcessRequest(ctx, req, "scripted"); !errors.Is(err, ErrInvalidResponseFormat) {
+		t.Fatalf("expected invalid response format, got %v", err)
+	}
+	req.ResponseFormat = &ports.ResponseFormat{Type: "yaml"}
+	if _, _, err := svc.ProcessRequest(ctx, req, "scripted"); !errors.Is(err, ErrInvalidResponseFormat) {
+		t.Fatalf("expected invalid response format, got %v", err)
+	}
+}
+
+func TestLLMService_RequestLogRedaction(t *testing.T) {
+	repo := newTestRepo(t)
+	svc := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("mock", &mockProvider{name: "mock"}),
+		WithRequestLogRedaction(logging.Redactor{Policy: logging.RedactTruncate, Length: 3}))
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+
+	if _, _, err := svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "Hello world"}, "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case log := <-repo.logs:
+		if log.RequestID != "req-42" {
+			t.Fatalf("expected the request ID to be stored, got %q", log.RequestID)
+		}
+		if log.Prompt != "Hel… [11 chars]" {
+			t.Fatalf("expected a truncated prompt, got %q", log.Prompt)
+		}
+		if log.Response == "mock response from mock" {
+			t.Fatalf("expected the response to be redacted too")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not logged")
+	}
+}
+
+// blockingRepo holds request log writes until released.
+type blockingRepo struct {
+	mockRepo
+	release chan struct{}
+}
+
+func (m *blockingRepo) LogRequest(ctx context.Context, log ports.RequestLog) error {
+	<-m.release
+	return nil
+}
+
+func TestLLMService_Flush(t *testing.T) {
+	repo := &blockingRepo{mockRepo: newTestRepo(t).mockRepo, release: make(chan struct{})}
+	svc := NewLLMService(&config.Config{}, repo, nil, WithProvider("mock", &mockProvider{name: "mock"}))
+	ctx := context.Background()
+	if _, _, err := svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "Hello"}, "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := svc.Flush(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected flush to wait for the pending log, got %v", err)
+	}
+	close(repo.release)
+	if err := svc.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
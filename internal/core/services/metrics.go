@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// WithMetrics reports requests, cache lookups, rate limits and the log
+// backlog to m. Without it nothing is recorded.
+func WithMetrics(m ports.Metrics) Option {
+	return func(s *LLMService) {
+		s.metrics = m
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(ports.RequestMetric) {}
+func (nopMetrics) AddInFlight(int)                    {}
+func (nopMetrics) ObserveCacheLookup(bool)            {}
+func (nopMetrics) ObserveRateLimited(string)          {}
+func (nopMetrics) AddPendingLogs(int)                 {}
+
+// beginRequest counts a generation as in flight. The returned function
+// records its outcome; provider is the name ProcessRequest returns.
+func (s *LLMService) beginRequest() func(resp *ports.LLMResponse, provider string, err error) {
+	start := time.Now()
+	s.metrics.AddInFlight(1)
+	return func(resp *ports.LLMResponse, provider string, err error) {
+		s.metrics.AddInFlight(-1)
+		m := ports.RequestMetric{
+			Provider: provider,
+			Success:  err == nil,
+			CacheHit: provider == CachedProvider,
+			Duration: time.Since(start),
+		}
+		if resp != nil {
+			m.Model = resp.Model
+			m.Usage = resp.Usage
+		}
+		s.metrics.ObserveRequest(m)
+	}
+}
+
+// observeProviderError counts calls the provider refused for rate limits.
+func (s *LLMService) observeProviderError(name string, err error) {
+	if errors.Is(err, ports.ErrRateLimited) {
+		s.metrics.ObserveRateLimited(s.providers[name].Name())
+	}
+}
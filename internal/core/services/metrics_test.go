@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+type recordingMetrics struct {
+	mu          sync.Mutex
+	requests    []ports.RequestMetric
+	inFlight    int
+	hits        int
+	misses      int
+	rateLimited []string
+}
+
+func (m *recordingMetrics) ObserveRequest(r ports.RequestMetric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, r)
+}
+
+func (m *recordingMetrics) AddInFlight(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+}
+
+func (m *recordingMetrics) ObserveCacheLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+func (m *recordingMetrics) ObserveRateLimited(provider string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimited = append(m.rateLimited, provider)
+}
+
+func (m *recordingMetrics) AddPendingLogs(int) {}
+
+func TestLLMService_Metrics(t *testing.T) {
+	repo := newTestRepo(t)
+	cache := &mockCache{data: make(map[string]string)}
+	metrics := &recordingMetrics{}
+	svc := NewLLMService(&config.Config{}, repo, cache,
+		WithProvider("mock", &mockProvider{name: "mock"}),
+		WithProvider("limited", &mockProvider{name: "Limited", respond: upstreamFailure("Limited", ports.ErrRateLimited)}),
+		WithMetrics(metrics))
+	ctx := context.Background()
+
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Hello"}
+	if _, _, err := svc.ProcessRequest(ctx, req, "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fingerprint := Fingerprint(req)
+	_ = cache.Set(ctx, cacheKey("mock", req.UserID, fingerprint), "cached answer", time.Minute)
+	if _, _, err := svc.ProcessRequest(ctx, req, "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "Hi"}, "limited"); !errors.Is(err, ports.ErrRateLimited) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if len(metrics.requests) != 3 || metrics.inFlight != 0 {
+		t.Fatalf("expected 3 finished requests, got %+v (in flight %d)", metrics.requests, metrics.inFlight)
+	}
+	fresh, cached, failed := metrics.requests[0], metrics.requests[1], metrics.requests[2]
+	if !fresh.Success || fresh.CacheHit || fresh.Provider != "mock" || fresh.Usage == nil || fresh.Usage.CostUSD != 0.001 {
+		t.Fatalf("unexpected metric for a fresh answer: %+v", fresh)
+	}
+	if !cached.Success || !cached.CacheHit || cached.Usage != nil {
+		t.Fatalf("unexpected metric for a cached answer: %+v", cached)
+	}
+	if failed.Success || failed.Provider != "Limited" {
+		t.Fatalf("unexpected metric for a failure: %+v", failed)
+	}
+	if metrics.hits != 1 || metrics.misses != 2 {
+		t.Fatalf("expected 1 hit and 2 misses, got %d and %d", metrics.hits, metrics.misses)
+	}
+	if len(metrics.rateLimited) != 1 || metrics.rateLimited[0] != "Limited" {
+		t.Fatalf("expected one rate limit rejection, got %v", metrics.rateLimited)
+	}
+}
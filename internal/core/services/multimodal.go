@@ -0,0 +1,117 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	defaultMaxAttachmentBytes      = 5 << 20
+	defaultMaxAttachmentTotalBytes = 20 << 20
+)
+
+var (
+	// ErrInvalidContent is returned when a content part is malformed.
+	ErrInvalidContent = ports.NewError(ports.ErrInvalidArgument, "invalid content part")
+	// ErrVisionNotSupported is returned when a request with images or files
+	// would be served by a model without the vision capability.
+	ErrVisionNotSupported = ports.NewError(ports.ErrInvalidArgument, "model does not accept images or files")
+	// ErrAttachmentTooLarge is returned when inline media exceeds the configured limits.
+	ErrAttachmentTooLarge = ports.NewError(ports.ErrTooLarge, "attachment too large")
+)
+
+// validateContent checks every content part and enforces the size limits on
+// inline media.
+func (s *LLMService) validateContent(req ports.LLMRequest) error {
+	var total int64
+	for _, m := range req.Messages {
+		for _, p := range m.Parts {
+			switch p.Type {
+			case ports.PartText:
+			case ports.PartImage:
+				if (p.URL == "") == (len(p.Data) == 0) {
+					return fmt.Errorf("%w: an image needs exactly one of url or data", ErrInvalidContent)
+				}
+				if p.URL != "" {
+					if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+						return fmt.Errorf("%w: image url must be http or https", ErrInvalidContent)
+					}
+				}
+			case ports.PartFile:
+				// Neither provider fetches arbitrary document URLs, so files are inline only.
+				if len(p.Data) == 0 {
+					return fmt.Errorf("%w: a file needs data", ErrInvalidContent)
+				}
+			default:
+				return fmt.Errorf("%w: unknown type %q", ErrInvalidContent, p.Type)
+			}
+			if len(p.Data) > 0 && p.MIMEType == "" {
+				return fmt.Errorf("%w: inline data needs a mime_type", ErrInvalidContent)
+			}
+
+			size := int64(len(p.Data))
+			if size > s.maxAttachmentBytes {
+				return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrAttachmentTooLarge, size, s.maxAttachmentBytes)
+			}
+			total += size
+			if total > s.maxAttachmentTotal {
+				return fmt.Errorf("%w: attachments exceed the %d byte request limit", ErrAttachmentTooLarge, s.maxAttachmentTotal)
+			}
+		}
+	}
+	return nil
+}
+
+// supportsMedia reports whether provider's model (its default when upstream
+// is empty) is declared in the catalog with the vision capability. Models the
+// catalog does not describe are assumed to be text-only.
+func (s *LLMService) supportsMedia(provider, upstream string) bool {
+	var info ports.ModelInfo
+	var ok bool
+	if upstream != "" {
+		info, ok = s.catalog.Lookup(provider, upstream)
+	} else {
+		info, ok = s.catalog.Default(provider)
+	}
+	return ok && info.Capabilities.Vision
+}
+
+// mediaProviders keeps the providers whose default model accepts media.
+func (s *LLMService) mediaProviders(names []string) []string {
+	var out []string
+	for _, name := range names {
+		if s.supportsMedia(name, "") {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// attachmentMeta describes the request's media for request logs.
+func attachmentMeta(req ports.LLMRequest) []ports.AttachmentMeta {
+	var out []ports.AttachmentMeta
+	for _, m := range req.Messages {
+		for _, p := range m.Parts {
+			if !p.IsMedia() {
+				continue
+			}
+			meta := ports.AttachmentMeta{
+				Type:     string(p.Type),
+				MIMEType: p.MIMEType,
+				Filename: p.Filename,
+				URL:      p.URL,
+			}
+			if len(p.Data) > 0 {
+				sum := sha256.Sum256(p.Data)
+				meta.Size = len(p.Data)
+				meta.SHA256 = hex.EncodeToString(sum[:])
+			}
+			out = append(out, meta)
+		}
+	}
+	return out
+}
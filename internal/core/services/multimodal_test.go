@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestLLMService_Multimodal(t *testing.T) {
+	repo := newTestRepo(t)
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "gpt-4o", Provider: "openai", Capabilities: ports.ModelCapabilities{Vision: true}},
+		{ID: "gemini-text", Provider: "gemini"},
+	}, nil, map[string]string{"openai": "gpt-4o", "gemini": "gemini-text"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	cfg := &config.Config{Uploads: config.UploadConfig{MaxBytes: 8, MaxTotalBytes: 12}}
+	svc := NewLLMService(cfg, repo, nil, WithCatalog(models))
+	svc.providers = map[string]ports.LLMProvider{
+		"openai": &mockProvider{name: "openai"},
+		"gemini": &mockProvider{name: "gemini"},
+	}
+	ctx := context.Background()
+
+	withParts := func(parts ...ports.ContentPart) ports.LLMRequest {
+		return ports.LLMRequest{UserID: "user-123", Messages: []ports.Message{{Role: ports.RoleUser, Parts: parts}}}
+	}
+	text := ports.ContentPart{Type: ports.PartText, Text: "What is this?"}
+	image := ports.ContentPart{Type: ports.PartImage, Data: []byte("pngbytes"), MIMEType: "image/png"}
+
+	// Routing only considers providers whose default model has vision.
+	_, provider, err := svc.ProcessRequest(ctx, withParts(text, image), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "openai" {
+		t.Fatalf("expected the vision provider, got %s", provider)
+	}
+	select {
+	case log := <-repo.logs:
+		if len(log.Attachments) != 1 || log.Attachments[0].Size != 8 || log.Attachments[0].SHA256 == "" {
+			t.Fatalf("expected attachment metadata in request log, got %+v", log.Attachments)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not logged")
+	}
+
+	if _, _, err := svc.ProcessRequest(ctx, withParts(text, image), "gemini"); !errors.Is(err, ErrVisionNotSupported) {
+		t.Fatalf("expected vision error, got %v", err)
+	}
+	req := withParts(text, image)
+	req.Model = "gemini-text"
+	if _, _, err := svc.ProcessRequest(ctx, req, ""); !errors.Is(err, ErrVisionNotSupported) {
+		t.Fatalf("expected vision error for text-only model, got %v", err)
+	}
+
+	// Text-only parts need no vision.
+	if _, _, err := svc.ProcessRequest(ctx, withParts(text), "gemini"); err != nil {
+		t.Fatalf("unexpected error for text parts: %v", err)
+	}
+
+	large := ports.ContentPart{Type: ports.PartFile, Data: []byte("123456789"), MIMEType: "application/pdf"}
+	if _, _, err := svc.ProcessRequest(ctx, withParts(large), "openai"); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("expected per-attachment limit, got %v", err)
+	}
+	if _, _, err := svc.ProcessRequest(ctx, withParts(image, image), "openai"); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("expected total limit, got %v", err)
+	}
+
+	for name, part := range map[string]ports.ContentPart{
+		"url and data": {Type: ports.PartImage, URL: "https://example.com/a.png", Data: []byte("x"), MIMEType: "image/png"},
+		"bad scheme":   {Type: ports.PartImage, URL: "file:///etc/passwd"},
+		"no mime":      {Type: ports.PartImage, Data: []byte("x")},
+		"file by url":  {Type: ports.PartFile, URL: "https://example.com/a.pdf"},
+		"unknown type": {Type: "audio", Data: []byte("x"), MIMEType: "audio/wav"},
+	} {
+		if _, _, err := svc.ProcessRequest(ctx, withParts(part), "openai"); !errors.Is(err, ErrInvalidContent) {
+			t.Errorf("%s: expected invalid content error, got %v", name, err)
+		}
+	}
+}
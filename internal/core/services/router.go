@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	StrategyPriority      = "priority"
+	StrategyCheapest      = "cheapest"
+	StrategyLatency       = "latency"
+	StrategyWeighted      = "weighted"
+	StrategyLeastInFlight = "least_inflight"
+
+	// latencyWindow is how many recent calls feed the p95 estimate.
+	latencyWindow = 100
+	// failurePenalty is the latency a failed call counts as, so a provider
+	// failing more than one call in twenty ranks behind any that answers.
+	failurePenalty = time.Minute
+)
+
+var (
+	// ErrUnknownStrategy is returned when a request names a routing strategy that is not registered.
+	ErrUnknownStrategy = ports.NewError(ports.ErrInvalidArgument, "unknown routing strategy")
+	// ErrNoProviders is returned when there is nothing to route to.
+	ErrNoProviders = ports.NewError(ports.ErrProviderUnavailable, "no llm providers configured")
+)
+
+// providerPriority is the preference order used by the priority strategy and
+// to break ties in every other strategy.
+var providerPriority = []string{"openai", "gemini"}
+
+// RouteCandidate is a snapshot of what the router knows about one provider.
+type RouteCandidate struct {
+	Name       string
+	CostPer1K  float64
+	P95Latency time.Duration
+	Samples    int
+	InFlight   int64
+	Weight     int
+}
+
+// RoutingStrategy picks one of the candidates and explains why. Candidates
+// are always passed in priority order and are never empty.
+type RoutingStrategy interface {
+	Name() string
+	Select(candidates []RouteCandidate) (RouteCandidate, string)
+}
+
+// RoutingDecision records which provider was chosen and how.
+type RoutingDecision struct {
+	Provider string
+	Strategy string
+	Reason   string
+}
+
+type providerStats struct {
+	latencies []time.Duration
+	next      int
+	inFlight  int64
+}
+
+// Router chooses a provider when the caller did not name one and keeps the
+// rolling statistics the strategies need.
+type Router struct {
+	mu              sync.Mutex
+	strategies      map[string]RoutingStrategy
+	defaultStrategy string
+	prices          map[string]float64
+	weights         map[string]int
+	stats           map[string]*providerStats
+}
+
+// NewRouter creates a router with the built-in strategies registered. prices
+// holds the combined input+output USD cost per 1K tokens and weights the
+// relative share for weighted round-robin, both keyed by provider name.
+func NewRouter(defaultStrategy string, prices map[string]float64, weights map[string]int) *Router {
+	if defaultStrategy == "" {
+		defaultStrategy = StrategyPriority
+	}
+	r := &Router{
+		strategies:      make(map[string]RoutingStrategy),
+		defaultStrategy: defaultStrategy,
+		prices:          prices,
+		weights:         weights,
+		stats:           make(map[string]*providerStats),
+	}
+	r.Register(priorityStrategy{})
+	r.Register(cheapestStrategy{})
+	r.Register(latencyStrategy{})
+	r.Register(&weightedStrategy{current: make(map[string]int)})
+	r.Register(leastInFlightStrategy{})
+	return r
+}
+
+// Register adds or replaces a strategy under its name.
+func (r *Router) Register(s RoutingStrategy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strategies[s.Name()] = s
+}
+
+// Route picks one of the named providers using strategy, or the router's
+// default strategy when strategy is empty.
+func (r *Router) Route(strategy string, providers []string) (RoutingDecision, error) {
+	if strategy == "" {
+		strategy = r.defaultStrategy
+	}
+	if len(providers) == 0 {
+		return RoutingDecision{}, ErrNoProviders
+	}
+
+	r.mu.Lock()
+	s, ok := r.strategies[strategy]
+	candidates := r.candidates(providers)
+	r.mu.Unlock()
+	if !ok {
+		return RoutingDecision{}, fmt.Errorf("%w: %q", ErrUnknownStrategy, strategy)
+	}
+
+	chosen, reason := s.Select(candidates)
+	return RoutingDecision{Provider: chosen.Name, Strategy: strategy, Reason: reason}, nil
+}
+
+// Begin marks a call to provider as in flight. The returned function must be
+// called when the call finishes. Its latency feeds the latency window, or
+// failurePenalty if the provider failed it; calls cancelled or refused as
+// invalid say nothing about the provider and are not counted.
+func (r *Router) Begin(provider string) func(latency time.Duration, err error) {
+	r.mu.Lock()
+	st := r.statsFor(provider)
+	st.inFlight++
+	r.mu.Unlock()
+
+	return func(latency time.Duration, err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		st.inFlight--
+		switch {
+		case err == nil:
+			st.record(latency)
+		case errors.Is(err, context.Canceled) || errors.Is(err, ports.ErrInvalidArgument):
+		default:
+			st.record(max(latency, failurePenalty))
+		}
+	}
+}
+
+// candidates must be called with r.mu held.
+func (r *Router) candidates(providers []string) []RouteCandidate {
+	names := orderByPriority(providers)
+	out := make([]RouteCandidate, 0, len(names))
+	for _, name := range names {
+		st := r.statsFor(name)
+		weight, ok := r.weights[name]
+		if !ok {
+			weight = 1
+		}
+		out = append(out, RouteCandidate{
+			Name:       name,
+			CostPer1K:  r.prices[name],
+			P95Latency: st.p95(),
+			Samples:    len(st.latencies),
+			InFlight:   st.inFlight,
+			Weight:     weight,
+		})
+	}
+	return out
+}
+
+// statsFor must be called with r.mu held.
+func (r *Router) statsFor(provider string) *providerStats {
+	st, ok := r.stats[provider]
+	if !ok {
+		st = &providerStats{}
+		r.stats[provider] = st
+	}
+	return st
+}
+
+func (st *providerStats) record(latency time.Duration) {
+	if len(st.latencies) < latencyWindow {
+		st.latencies = append(st.latencies, latency)
+		return
+	}
+	st.latencies[st.next] = latency
+	st.next = (st.next + 1) % latencyWindow
+}
+
+func (st *providerStats) p95() time.Duration {
+	if len(st.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), st.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := (len(sorted)*95+99)/100 - 1
+	return sorted[idx]
+}
+
+// orderByPriority sorts provider names into preference order: the known
+// providers first, then everything else alphabetically.
+func orderByPriority(providers []string) []string {
+	rank := func(name string) int {
+		for i, p := range providerPriority {
+			if p == name {
+				return i
+			}
+		}
+		return len(providerPriority)
+	}
+	out := append([]string(nil), providers...)
+	sort.Slice(out, func(i, j int) bool {
+		ri, rj := rank(out[i]), rank(out[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+type priorityStrategy struct{}
+
+func (priorityStrategy) Name() string { return StrategyPriority }
+
+func (priorityStrategy) Select(c []RouteCandidate) (RouteCandidate, string) {
+	return c[0], "first configured provider in priority order"
+}
+
+type cheapestStrategy struct{}
+
+func (cheapestStrategy) Name() string { return StrategyCheapest }
+
+func (cheapestStrategy) Select(c []RouteCandidate) (RouteCandidate, string) {
+	best := c[0]
+	for _, cand := range c[1:] {
+		if cand.CostPer1K < best.CostPer1K {
+			best = cand
+		}
+	}
+	return best, fmt.Sprintf("lowest configured price ($%.6f per 1K tokens)", best.CostPer1K)
+}
+
+type latencyStrategy struct{}
+
+func (latencyStrategy) Name() string { return StrategyLatency }
+
+func (latencyStrategy) Select(c []RouteCandidate) (RouteCandidate, string) {
+	// Providers we have never measured are tried first so every candidate
+	// gets a latency estimate. Failures count as measurements, so a provider
+	// that never answers is only explored once.
+	for _, cand := range c {
+		if cand.Samples == 0 {
+			return cand, "no latency samples yet"
+		}
+	}
+	best := c[0]
+	for _, cand := range c[1:] {
+		if cand.P95Latency < best.P95Latency {
+			best = cand
+		}
+	}
+	return best, fmt.Sprintf("lowest rolling p95 latency (%dms over %d calls)", best.P95Latency.Milliseconds(), best.Samples)
+}
+
+// weightedStrategy implements smooth weighted round-robin, which spreads
+// picks evenly instead of sending bursts to the heaviest provider.
+type weightedStrategy struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func (*weightedStrategy) Name() string { return StrategyWeighted }
+
+func (s *weightedStrategy) Select(c []RouteCandidate) (RouteCandidate, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	best := -1
+	for i, cand := range c {
+		if cand.Weight <= 0 {
+			continue
+		}
+		total += cand.Weight
+		s.current[cand.Name] += cand.Weight
+		if best < 0 || s.current[cand.Name] > s.current[c[best].Name] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return c[0], "all weights are zero, using first provider"
+	}
+	s.current[c[best].Name] -= total
+	return c[best], fmt.Sprintf("weighted round-robin (weight %d of %d)", c[best].Weight, total)
+}
+
+type leastInFlightStrategy struct{}
+
+func (leastInFlightStrategy) Name() string { return StrategyLeastInFlight }
+
+func (leastInFlightStrategy) Select(c []RouteCandidate) (RouteCandidate, string) {
+	best := c[0]
+	for _, cand := range c[1:] {
+		if cand.InFlight < best.InFlight {
+			best = cand
+		}
+	}
+	return best, fmt.Sprintf("fewest in-flight requests (%d)", best.InFlight)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestRouter_Strategies(t *testing.T) {
+	providers := []string{"gemini", "openai", "mock"}
+	prices := map[string]float64{"openai": 0.002, "gemini": 0.0005, "mock": 0.001}
+
+	tests := []struct {
+		name     string
+		strategy string
+		setup    func(r *Router)
+		want     string
+	}{
+		{name: "priority prefers openai", strategy: StrategyPriority, want: "openai"},
+		{name: "cheapest by configured price", strategy: StrategyCheapest, want: "gemini"},
+		{
+			name:     "latency explores unmeasured providers",
+			strategy: StrategyLatency,
+			setup: func(r *Router) {
+				r.Begin("openai")(200*time.Millisecond, nil)
+				r.Begin("gemini")(50*time.Millisecond, nil)
+			},
+			want: "mock",
+		},
+		{
+			name:     "latency picks lowest p95",
+			strategy: StrategyLatency,
+			setup: func(r *Router) {
+				r.Begin("openai")(200*time.Millisecond, nil)
+				r.Begin("gemini")(50*time.Millisecond, nil)
+				r.Begin("mock")(90*time.Millisecond, nil)
+			},
+			want: "gemini",
+		},
+		{
+			name:     "latency ranks failing providers last",
+			strategy: StrategyLatency,
+			setup: func(r *Router) {
+				r.Begin("openai")(200*time.Millisecond, nil)
+				r.Begin("gemini")(50*time.Millisecond, errors.New("upstream unavailable"))
+				r.Begin("mock")(90*time.Millisecond, nil)
+			},
+			want: "mock",
+		},
+		{
+			name:     "latency ignores cancelled calls",
+			strategy: StrategyLatency,
+			setup: func(r *Router) {
+				r.Begin("openai")(200*time.Millisecond, nil)
+				r.Begin("gemini")(50*time.Millisecond, nil)
+				r.Begin("gemini")(time.Millisecond, context.Canceled)
+				r.Begin("mock")(90*time.Millisecond, nil)
+			},
+			want: "gemini",
+		},
+		{
+			name:     "least in-flight",
+			strategy: StrategyLeastInFlight,
+			setup: func(r *Router) {
+				r.Begin("openai")
+				r.Begin("gemini")
+			},
+			want: "mock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter("", prices, nil)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+			decision, err := r.Route(tt.strategy, providers)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision.Provider != tt.want {
+				t.Fatalf("expected %s, got %s (%s)", tt.want, decision.Provider, decision.Reason)
+			}
+			if decision.Reason == "" {
+				t.Fatalf("expected a routing reason")
+			}
+		})
+	}
+
+	if _, err := NewRouter("", nil, nil).Route("fastest", providers); !errors.Is(err, ErrUnknownStrategy) {
+		t.Fatalf("expected unknown strategy error, got %v", err)
+	}
+}
+
+func TestRouter_WeightedRoundRobin(t *testing.T) {
+	r := NewRouter(StrategyWeighted, nil, map[string]int{"openai": 3, "gemini": 1})
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		decision, err := r.Route("", []string{"openai", "gemini"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		counts[decision.Provider]++
+	}
+	if counts["openai"] != 6 || counts["gemini"] != 2 {
+		t.Fatalf("expected a 3:1 split, got %v", counts)
+	}
+}
+
+func TestLLMService_RoutesWithoutProvider(t *testing.T) {
+	repo := newTestRepo(t)
+	cfg := &config.Config{
+		LLM: config.LLMConfig{GeminiInputCostPer1K: 0.0001, OpenAIInputCostPer1K: 0.001},
+	}
+	svc := NewLLMService(cfg, repo, nil)
+	svc.providers = map[string]ports.LLMProvider{
+		"openai": &mockProvider{name: "openai"},
+		"gemini": &mockProvider{name: "gemini"},
+	}
+
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Hello", Routing: StrategyCheapest}
+	_, provider, err := svc.ProcessRequest(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider != "gemini" {
+		t.Fatalf("expected cheapest provider gemini, got %s", provider)
+	}
+
+	select {
+	case log := <-repo.logs:
+		if log.RoutingStrategy != StrategyCheapest || log.RoutingReason == "" {
+			t.Fatalf("expected routing decision in request log, got %+v", log)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not logged")
+	}
+}
+
+func TestLLMService_LatencyRoutingAvoidsFailingProvider(t *testing.T) {
+	repo := newTestRepo(t)
+	broken := &mockProvider{name: "openai", respond: upstreamFailure("openai", ports.ErrProviderUnavailable)}
+	svc := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("openai", broken), WithProvider("gemini", &mockProvider{name: "gemini"}))
+
+	// The first request explores the failing provider; the rest go elsewhere.
+	req := ports.LLMRequest{UserID: "user-123", Prompt: "Hello", Routing: StrategyLatency}
+	var failed int
+	for i := 0; i < 5; i++ {
+		if _, _, err := svc.ProcessRequest(context.Background(), req, ""); err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected one failed request, got %d", failed)
+	}
+	if calls := broken.calls.Load(); calls != 1 {
+		t.Fatalf("expected the failing provider to be explored once, got %d calls", calls)
+	}
+}
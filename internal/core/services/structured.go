@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+var (
+	// ErrInvalidResponseFormat is returned when a request's response format or schema is malformed.
+	ErrInvalidResponseFormat = ports.NewError(ports.ErrInvalidArgument, "invalid response format")
+	// ErrInvalidStructuredOutput is returned when the model's answer is not
+	// valid JSON for the requested format, after any repair attempt.
+	ErrInvalidStructuredOutput = ports.NewError(ports.ErrInvalidOutput, "model output does not match response format")
+)
+
+// compileResponseFormat checks format and compiles its schema, if any. A nil
+// schema with a nil error means no schema validation is needed.
+func compileResponseFormat(format *ports.ResponseFormat) (*jsonschema.Schema, error) {
+	if format == nil {
+		return nil, nil
+	}
+	switch format.Type {
+	case "", ports.ResponseFormatText:
+		return nil, nil
+	case ports.ResponseFormatJSONObject:
+		return nil, nil
+	case ports.ResponseFormatJSONSchema:
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidResponseFormat, format.Type)
+	}
+
+	if len(format.Schema) == 0 {
+		return nil, fmt.Errorf("%w: json_schema requires a schema", ErrInvalidResponseFormat)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(format.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema is not valid JSON: %v", ErrInvalidResponseFormat, err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("response.json", doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponseFormat, err)
+	}
+	schema, err := c.Compile("response.json")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponseFormat, err)
+	}
+	return schema, nil
+}
+
+// wantsJSON reports whether format asks for a JSON answer.
+func wantsJSON(format *ports.ResponseFormat) bool {
+	return format != nil && (format.Type == ports.ResponseFormatJSONObject || format.Type == ports.ResponseFormatJSONSchema)
+}
+
+// parseStructured extracts the JSON answer from content and validates it.
+// Models sometimes wrap JSON in a markdown code fence even in JSON mode, so a
+// surrounding fence is tolerated.
+func parseStructured(format *ports.ResponseFormat, schema *jsonschema.Schema, content string) (json.RawMessage, error) {
+	text := strings.TrimSpace(content)
+	if fenced, ok := strings.CutPrefix(text, "```"); ok {
+		fenced = strings.TrimPrefix(fenced, "json")
+		if body, ok := strings.CutSuffix(strings.TrimSpace(fenced), "```"); ok {
+			text = strings.TrimSpace(body)
+		}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(text)); err != nil {
+		return nil, fmt.Errorf("answer is not valid JSON: %v", err)
+	}
+	if format.Type == ports.ResponseFormatJSONObject && !strings.HasPrefix(text, "{") {
+		return nil, fmt.Errorf("answer is not a JSON object")
+	}
+	if schema != nil {
+		inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(compact.Bytes()))
+		if err != nil {
+			return nil, fmt.Errorf("answer is not valid JSON: %v", err)
+		}
+		if err := schema.Validate(inst); err != nil {
+			return nil, err
+		}
+	}
+	return json.RawMessage(compact.Bytes()), nil
+}
+
+// enforceFormat validates a fresh answer against the request's response
+// format, setting resp.JSON. When validation fails and the request allows
+// it, the provider that answered is asked once more with the errors shown.
+func (s *LLMService) enforceFormat(ctx context.Context, plan *requestPlan, resp *ports.LLMResponse, provider string) (*ports.LLMResponse, error) {
+	format := plan.req.ResponseFormat
+	if !wantsJSON(format) || len(resp.ToolCalls) > 0 {
+		return resp, nil
+	}
+	parsed, verr := parseStructured(format, plan.schema, resp.Content)
+	if verr == nil {
+		resp.JSON = parsed
+		return resp, nil
+	}
+	if !format.Repair {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, verr)
+	}
+
+	req := plan.req
+	if provider != plan.decision.Provider {
+		// A hedge secondary answered; the resolved model is not its own.
+		req.Model = ""
+	}
+	req.Messages = append(slices.Clone(req.Conversation()),
+		ports.Message{Role: ports.RoleAssistant, Content: resp.Content},
+		ports.Message{Role: ports.RoleUser, Content: fmt.Sprintf(
+			"Your previous reply was rejected: %v\nReply again with only the corrected JSON and nothing else.", verr)},
+	)
+	repaired, latency, err := s.generate(ctx, provider, req)
+	if err != nil {
+		return nil, fmt.Errorf("structured output repair failed: %w", err)
+	}
+	s.logRequest(ctx, s.requestLog(req, provider, RoutingDecision{
+		Provider: provider,
+		Strategy: "repair",
+		Reason:   "answer failed response format validation",
+	}, repaired, latency))
+
+	parsed, verr = parseStructured(format, plan.schema, repaired.Content)
+	if verr != nil {
+		return nil, fmt.Errorf("%w after repair: %v", ErrInvalidStructuredOutput, verr)
+	}
+	repaired.JSON = parsed
+	// The caller paid for both attempts.
+	if resp.Usage != nil && repaired.Usage != nil {
+		repaired.Usage.PromptTokens += resp.Usage.PromptTokens
+		repaired.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		repaired.Usage.TotalTokens += resp.Usage.TotalTokens
+		repaired.Usage.CostUSD += resp.Usage.CostUSD
+	}
+	return repaired, nil
+}
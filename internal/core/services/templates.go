@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+var (
+	// ErrTemplatesNotConfigured is returned when no template store is configured.
+	ErrTemplatesNotConfigured = ports.NewError(ports.ErrNotConfigured, "prompt templates not configured")
+	// ErrTemplateNotFound is returned for unknown template names or versions.
+	ErrTemplateNotFound = ports.NewError(ports.ErrNotFound, "template not found")
+	// ErrInvalidTemplate is returned when a template definition does not parse
+	// or uses variables it does not declare.
+	ErrInvalidTemplate = ports.NewError(ports.ErrInvalidArgument, "invalid template")
+	// ErrInvalidVariables is returned when a request's variables do not match
+	// the template's declarations.
+	ErrInvalidVariables = ports.NewError(ports.ErrInvalidArgument, "invalid template variables")
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+	variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// TemplateService manages versioned prompt templates and renders the
+// templated requests that reach its LLMService.
+type TemplateService struct {
+	store ports.TemplateStore
+}
+
+// NewTemplateService builds a template service on store and has llm render
+// templated requests with it. A nil store disables templates.
+func NewTemplateService(llm *LLMService, store ports.TemplateStore) *TemplateService {
+	t := &TemplateService{store: store}
+	llm.templates = t
+	return t
+}
+
+// ParseTemplateRef splits "name@version" into its parts. The version is
+// optional; without it the latest version is used.
+func ParseTemplateRef(ref string) (string, int, error) {
+	name, version, found := strings.Cut(ref, "@")
+	if !found {
+		return name, 0, nil
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil || v < 1 {
+		return "", 0, fmt.Errorf("%w: version in %q must be a positive number", ErrInvalidTemplate, ref)
+	}
+	return name, v, nil
+}
+
+// CreateTemplate validates t and stores it as the next version of its name.
+// A template is checked by rendering it once with every declared variable
+// set, so references to undeclared variables are caught here rather than
+// in the first request that uses it.
+func (s *TemplateService) CreateTemplate(ctx context.Context, t ports.PromptTemplate) (*ports.PromptTemplate, error) {
+	if s.store == nil {
+		return nil, ErrTemplatesNotConfigured
+	}
+	if !templateNamePattern.MatchString(t.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase letters, digits, '.', '_' or '-'", ErrInvalidTemplate)
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidTemplate)
+	}
+	sample := make(map[string]any, len(t.Variables))
+	for _, v := range t.Variables {
+		if !variableNamePattern.MatchString(v.Name) {
+			return nil, fmt.Errorf("%w: variable name %q is not an identifier", ErrInvalidTemplate, v.Name)
+		}
+		if _, dup := sample[v.Name]; dup {
+			return nil, fmt.Errorf("%w: variable %q is declared twice", ErrInvalidTemplate, v.Name)
+		}
+		if v.Default != "" && len(v.Enum) > 0 && !slices.Contains(v.Enum, v.Default) {
+			return nil, fmt.Errorf("%w: default of %q is not one of its enum values", ErrInvalidTemplate, v.Name)
+		}
+		sample[v.Name] = "sample"
+		if len(v.Enum) > 0 {
+			sample[v.Name] = v.Enum[0]
+		}
+	}
+	if _, _, err := renderTemplate(t, sample); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return s.store.CreateTemplateVersion(ctx, t)
+}
+
+// GetTemplate returns a version of a template, or its latest when version is 0.
+func (s *TemplateService) GetTemplate(ctx context.Context, name string, version int) (*ports.PromptTemplate, error) {
+	if s.store == nil {
+		return nil, ErrTemplatesNotConfigured
+	}
+	t, err := s.store.GetTemplate(ctx, name, version)
+	if errors.Is(err, ports.ErrNotFound) {
+		if version > 0 {
+			return nil, fmt.Errorf("%w: %s@%d", ErrTemplateNotFound, name, version)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, err
+}
+
+// ListTemplates returns the latest version of every template.
+func (s *TemplateService) ListTemplates(ctx context.Context) ([]ports.PromptTemplate, error) {
+	if s.store == nil {
+		return nil, ErrTemplatesNotConfigured
+	}
+	return s.store.ListTemplates(ctx)
+}
+
+// TemplateVersions returns every version of a template, oldest first.
+func (s *TemplateService) TemplateVersions(ctx context.Context, name string) ([]ports.PromptTemplate, error) {
+	if s.store == nil {
+		return nil, ErrTemplatesNotConfigured
+	}
+	versions, err := s.store.ListTemplateVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return versions, nil
+}
+
+// DeleteTemplate removes every version of a template. Request logs keep the
+// name and version they were rendered from.
+func (s *TemplateService) DeleteTemplate(ctx context.Context, name string) error {
+	if s.store == nil {
+		return ErrTemplatesNotConfigured
+	}
+	err := s.store.DeleteTemplate(ctx, name)
+	if errors.Is(err, ports.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return err
+}
+
+// applyTemplate renders a templated request into its messages and pins the
+// template reference to the version used, so the request log records it.
+// Requests rendered before are left alone.
+func (s *LLMService) applyTemplate(ctx context.Context, req ports.LLMRequest) (ports.LLMRequest, error) {
+	if req.Template == nil || req.Template.Rendered {
+		return req, nil
+	}
+	if s.templates == nil {
+		return req, ErrTemplatesNotConfigured
+	}
+	return s.templates.render(ctx, req)
+}
+
+// render fills in the template named by req.
+func (s *TemplateService) render(ctx context.Context, req ports.LLMRequest) (ports.LLMRequest, error) {
+	if req.Prompt != "" || len(req.Messages) > 0 {
+		return req, fmt.Errorf("%w: a templated request cannot also carry a prompt or messages", ErrInvalidVariables)
+	}
+	t, err := s.GetTemplate(ctx, req.Template.Name, req.Template.Version)
+	if err != nil {
+		return req, err
+	}
+	values, err := templateValues(t.Variables, req.Template.Variables)
+	if err != nil {
+		return req, err
+	}
+	system, body, err := renderTemplate(*t, values)
+	if err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidVariables, err)
+	}
+
+	ref := *req.Template
+	ref.Version = t.Version
+	ref.Rendered = true
+	req.Template = &ref
+	req.Prompt = body
+	if system != "" {
+		req.Messages = []ports.Message{
+			{Role: ports.RoleSystem, Content: system},
+			{Role: ports.RoleUser, Content: body},
+		}
+	}
+	return req, nil
+}
+
+// templateValues checks the supplied variables against the declarations and
+// fills in defaults.
+func templateValues(declared []ports.TemplateVariable, supplied map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(declared))
+	known := make(map[string]bool, len(declared))
+	for _, v := range declared {
+		known[v.Name] = true
+		value, ok := supplied[v.Name]
+		if !ok || value == nil || value == "" {
+			if v.Required && v.Default == "" {
+				return nil, fmt.Errorf("%w: %q is required", ErrInvalidVariables, v.Name)
+			}
+			value = v.Default
+		}
+		if len(v.Enum) > 0 {
+			str, isString := value.(string)
+			if !isString || !slices.Contains(v.Enum, str) {
+				return nil, fmt.Errorf("%w: %q must be one of %s", ErrInvalidVariables, v.Name, strings.Join(v.Enum, ", "))
+			}
+		}
+		values[v.Name] = value
+	}
+	for name := range supplied {
+		if !known[name] {
+			return nil, fmt.Errorf("%w: %q is not declared by the template", ErrInvalidVariables, name)
+		}
+	}
+	return values, nil
+}
+
+// renderTemplate executes the system and body templates. Referencing a
+// variable missing from values is an error rather than "<no value>".
+func renderTemplate(t ports.PromptTemplate, values map[string]any) (string, string, error) {
+	render := func(name, source string) (string, error) {
+		if source == "" {
+			return "", nil
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, values); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	system, err := render("system", t.System)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := render("body", t.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return system, body, nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// memoryTemplates is an in-process TemplateStore.
+type memoryTemplates struct {
+	mu       sync.Mutex
+	versions map[string][]ports.PromptTemplate
+}
+
+func (m *memoryTemplates) CreateTemplateVersion(ctx context.Context, t ports.PromptTemplate) (*ports.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.versions == nil {
+		m.versions = make(map[string][]ports.PromptTemplate)
+	}
+	t.Version = len(m.versions[t.Name]) + 1
+	t.CreatedAt = time.Now()
+	m.versions[t.Name] = append(m.versions[t.Name], t)
+	return &t, nil
+}
+
+func (m *memoryTemplates) GetTemplate(ctx context.Context, name string, version int) (*ports.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	versions := m.versions[name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return nil, fmt.Errorf("template %s: %w", name, ports.ErrNotFound)
+	}
+	t := versions[version-1]
+	return &t, nil
+}
+
+func (m *memoryTemplates) ListTemplates(ctx context.Context) ([]ports.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []ports.PromptTemplate
+	for _, versions := range m.versions {
+		out = append(out, versions[len(versions)-1])
+	}
+	return out, nil
+}
+
+func (m *memoryTemplates) ListTemplateVersions(ctx context.Context, name string) ([]ports.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ports.PromptTemplate(nil), m.versions[name]...), nil
+}
+
+func (m *memoryTemplates) DeleteTemplate(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.versions[name]; !ok {
+		return fmt.Errorf("template %s: %w", name, ports.ErrNotFound)
+	}
+	delete(m.versions, name)
+	return nil
+}
+
+func TestLLMService_Templates(t *testing.T) {
+	repo := newTestRepo(t)
+	provider := &mockProvider{name: "openai"}
+	svc := NewLLMService(&config.Config{}, repo, nil,
+		WithProvider("openai", provider),
+	)
+	templates := NewTemplateService(svc, &memoryTemplates{})
+	ctx := context.Background()
+
+	summarize := ports.PromptTemplate{
+		Name:   "summarize",
+		System: "You write in a {{.tone}} tone.",
+		Body:   "Summarize:\n{{.text}}",
+		Variables: []ports.TemplateVariable{
+			{Name: "text", Required: true},
+			{Name: "tone", Default: "neutral", Enum: []string{"neutral", "friendly"}},
+		},
+	}
+	if _, err := templates.CreateTemplate(ctx, summarize); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	summarize.Body = "Summarize in one sentence:\n{{.text}}"
+	v2, err := templates.CreateTemplate(ctx, summarize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v2.Version != 2 {
+		t.Fatalf("expected version 2, got %d", v2.Version)
+	}
+
+	undeclared := ports.PromptTemplate{Name: "broken", Body: "Hello {{.name}}"}
+	if _, err := templates.CreateTemplate(ctx, undeclared); !errors.Is(err, ErrInvalidTemplate) {
+		t.Fatalf("expected invalid template for an undeclared variable, got %v", err)
+	}
+
+	// Pinned to version 1, with the tone left to its default.
+	req := ports.LLMRequest{
+		UserID:   "user-123",
+		Template: &ports.TemplateRef{Name: "summarize", Version: 1, Variables: map[string]any{"text": "A long story."}},
+	}
+	if _, _, err := svc.ProcessRequest(ctx, req, "openai"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := provider.lastRequest().Messages
+	if len(sent) != 2 || sent[0].Content != "You write in a neutral tone." || sent[1].Content != "Summarize:\nA long story." {
+		t.Fatalf("unexpected rendered messages: %+v", sent)
+	}
+	log := <-repo.logs
+	if log.TemplateName != "summarize" || log.TemplateVersion != 1 {
+		t.Fatalf("expected the log to record summarize@1, got %s@%d", log.TemplateName, log.TemplateVersion)
+	}
+
+	// Without a version the latest is used and recorded.
+	req.Template = &ports.TemplateRef{Name: "summarize", Variables: map[string]any{"text": "x", "tone": "friendly"}}
+	if _, _, err := svc.ProcessRequest(ctx, req, "openai"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if log := <-repo.logs; log.TemplateVersion != 2 {
+		t.Fatalf("expected the latest version to be recorded, got %d", log.TemplateVersion)
+	}
+
+	invalid := []map[string]any{
+		{},                                 // missing required
+		{"text": "x", "tone": "angry"},     // outside the enum
+		{"text": "x", "audience": "execs"}, // undeclared
+	}
+	for _, vars := range invalid {
+		req.Template = &ports.TemplateRef{Name: "summarize", Variables: vars}
+		if _, _, err := svc.ProcessRequest(ctx, req, "openai"); !errors.Is(err, ErrInvalidVariables) {
+			t.Fatalf("expected invalid variables for %v, got %v", vars, err)
+		}
+	}
+
+	req.Template = &ports.TemplateRef{Name: "summarize", Version: 9}
+	if _, _, err := svc.ProcessRequest(ctx, req, "openai"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected template not found, got %v", err)
+	}
+
+	if err := templates.DeleteTemplate(ctx, "summarize"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := templates.GetTemplate(ctx, "summarize", 0); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected template not found after delete, got %v", err)
+	}
+}
+
+func TestLLMService_TemplateFingerprint(t *testing.T) {
+	repo := newTestRepo(t)
+	cache := &mockCache{data: make(map[string]string)}
+	svc := NewLLMService(&config.Config{}, repo, cache,
+		WithProvider("openai", &mockProvider{name: "openai"}),
+	)
+	templates := NewTemplateService(svc, &memoryTemplates{})
+	ctx := context.Background()
+	if _, err := templates.CreateTemplate(ctx, ports.PromptTemplate{
+		Name:      "greet",
+		Body:      "Say hello to {{.name}}.",
+		Variables: []ports.TemplateVariable{{Name: "name", Required: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := ports.LLMRequest{UserID: "user-123", Template: &ports.TemplateRef{Name: "greet", Variables: map[string]any{"name": "Ada"}}}
+	resp, _, err := svc.ProcessRequest(ctx, req, "openai")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.Flush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The fingerprint is of the rendered prompt, the one the answer is cached under.
+	if resp.Fingerprint == "" || resp.Fingerprint == Fingerprint(req) {
+		t.Fatalf("expected the fingerprint of the rendered request, got %q", resp.Fingerprint)
+	}
+	cached, provider, err := svc.ProcessRequest(ctx, req, "openai")
+	if err != nil || provider != CachedProvider || cached.Fingerprint != resp.Fingerprint {
+		t.Fatalf("expected a cache hit with the same fingerprint, got %q from %s (%v)", cached.Fingerprint, provider, err)
+	}
+	deleted, err := svc.PurgeCache(ctx, CachePurge{Fingerprint: resp.Fingerprint})
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected the answer purged by its fingerprint, got %d (%v)", deleted, err)
+	}
+}
+
+func TestParseTemplateRef(t *testing.T) {
+	if name, version, err := ParseTemplateRef("summarize@3"); err != nil || name != "summarize" || version != 3 {
+		t.Fatalf("unexpected parse: %s %d %v", name, version, err)
+	}
+	if name, version, err := ParseTemplateRef("summarize"); err != nil || name != "summarize" || version != 0 {
+		t.Fatalf("unexpected parse: %s %d %v", name, version, err)
+	}
+	if _, _, err := ParseTemplateRef("summarize@latest"); !errors.Is(err, ErrInvalidTemplate) {
+		t.Fatalf("expected invalid template, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	// OverflowReject refuses prompts that do not fit the context window.
+	OverflowReject = "reject"
+	// OverflowTruncate drops the oldest messages of a prompt that does not
+	// fit, then the end of what is left.
+	OverflowTruncate = "truncate"
+
+	// Chat formats wrap every message, and prime the reply, with a few
+	// tokens of their own.
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// ErrOverBudget is returned when a request's estimated cost exceeds its MaxCostUSD.
+var ErrOverBudget = ports.NewError(ports.ErrBudgetExceeded, "estimated cost exceeds the request budget")
+
+// WithTokenizer sets how prompts are counted. By default OpenAI models are
+// counted with their encodings and other models estimated.
+func WithTokenizer(t ports.Tokenizer) Option {
+	return func(s *LLMService) {
+		s.tokenizer = t
+	}
+}
+
+// TokenEstimate is the size and cost of a request's prompt, counted before
+// it is sent.
+type TokenEstimate struct {
+	Provider string
+	// Model is the catalog ID of the model, or its upstream ID outside the catalog.
+	Model string
+	ports.TokenCount
+	ContextWindow int32
+	// CostUSD prices the prompt and max_tokens of output.
+	CostUSD float64
+}
+
+// Tokenize counts the prompt of req for the model that would serve it,
+// without sending it. Without a model or provider the highest-priority
+// configured provider's default model is assumed.
+func (s *LLMService) Tokenize(req ports.LLMRequest, providerName string) (*TokenEstimate, error) {
+	if err := s.validateRequest(req); err != nil {
+		return nil, err
+	}
+	var upstream string
+	switch {
+	case req.Model != "":
+		var err error
+		if providerName, upstream, err = s.resolveModel(req.Model, providerName); err != nil {
+			return nil, err
+		}
+	case providerName != "":
+		if _, ok := s.catalog.Default(providerName); !ok && !s.HasProvider(providerName) {
+			return nil, providerNotConfigured(providerName)
+		}
+	default:
+		names := orderByPriority(s.providerNames())
+		if len(names) == 0 {
+			return nil, ErrNoProviders
+		}
+		providerName = names[0]
+	}
+	return s.estimate(req, s.servingModel(providerName, upstream)), nil
+}
+
+// estimate counts the prompt of req, tool definitions included, as info
+// would see it.
+func (s *LLMService) estimate(req ports.LLMRequest, info ports.ModelInfo) *TokenEstimate {
+	est := &TokenEstimate{
+		Provider:      info.Provider,
+		Model:         modelName(info),
+		TokenCount:    s.tokenizer.Count(info.Provider, info.UpstreamID, ""),
+		ContextWindow: info.ContextWindow,
+	}
+	est.Tokens = tokensPerReply
+	for _, m := range req.Conversation() {
+		est.Tokens += s.messageTokens(m, info)
+	}
+	for _, t := range req.Tools {
+		est.Tokens += s.countTokens(t.Name+"\n"+t.Description+"\n"+string(t.Parameters), info)
+	}
+	est.CostUSD = info.Cost(ports.UsageInfo{PromptTokens: int32(est.Tokens), CompletionTokens: max(req.MaxTokens, 0)})
+	return est
+}
+
+func (s *LLMService) messageTokens(m ports.Message, info ports.ModelInfo) int {
+	n := tokensPerMessage + s.countTokens(messageText(m), info)
+	for _, call := range m.ToolCalls {
+		n += s.countTokens(call.Name+"\n"+string(call.Arguments), info)
+	}
+	return n
+}
+
+func (s *LLMService) countTokens(text string, info ports.ModelInfo) int {
+	return s.tokenizer.Count(info.Provider, info.UpstreamID, text).Tokens
+}
+
+// fitContext checks that the prompt of req and max_tokens of output fit the
+// context window of info, truncating the prompt first when configured to.
+func (s *LLMService) fitContext(req ports.LLMRequest, info ports.ModelInfo) (ports.LLMRequest, *TokenEstimate, error) {
+	est := s.estimate(req, info)
+	window := int(info.ContextWindow)
+	reserved := int(max(req.MaxTokens, 0))
+	if window == 0 || est.Tokens+reserved <= window {
+		return req, est, nil
+	}
+	if s.contextOverflow == OverflowTruncate {
+		fitted := s.truncate(req, info, est.Tokens, window-reserved)
+		if fittedEst := s.estimate(fitted, info); fittedEst.Tokens+reserved <= window {
+			return fitted, fittedEst, nil
+		}
+	}
+
+	var errs fieldErrors
+	field := "prompt"
+	if len(req.Messages) > 0 {
+		field = "messages"
+	}
+	if reserved > 0 {
+		errs.add(field, "is %d tokens, which with max_tokens of %d exceeds the %d token context window of %s", est.Tokens, reserved, window, modelName(info))
+	} else {
+		errs.add(field, "is %d tokens, which exceeds the %d token context window of %s", est.Tokens, window, modelName(info))
+	}
+	return req, est, errs.err()
+}
+
+// truncate cuts a prompt of total tokens down to budget. Conversations lose
+// their oldest messages first, keeping leading system messages and the last
+// message; tool results go with the call they answer. Whatever is still
+// over is cut from the end of the last message's text.
+func (s *LLMService) truncate(req ports.LLMRequest, info ports.ModelInfo, total, budget int) ports.LLMRequest {
+	msgs := slices.Clone(req.Conversation())
+	counts := make([]int, len(msgs))
+	for i, m := range msgs {
+		counts[i] = s.messageTokens(m, info)
+	}
+	first := 0
+	for first < len(msgs)-1 && msgs[first].Role == ports.RoleSystem {
+		first++
+	}
+	for total > budget && len(msgs) > first+1 {
+		end := first + 1
+		for end < len(msgs)-1 && msgs[end].Role == ports.RoleTool {
+			end++
+		}
+		for _, n := range counts[first:end] {
+			total -= n
+		}
+		msgs = slices.Delete(msgs, first, end)
+		counts = slices.Delete(counts, first, end)
+	}
+
+	// Only plain text can be cut, and only while some of it is left; a
+	// prompt that still does not fit is rejected.
+	last := &msgs[len(msgs)-1]
+	if total > budget && len(last.Parts) == 0 {
+		if keep := s.countTokens(last.Content, info) - (total - budget); keep > 0 {
+			last.Content = s.tokenizer.Truncate(info.Provider, info.UpstreamID, last.Content, keep)
+		}
+	}
+	if len(req.Messages) == 0 {
+		req.Prompt = last.Content
+	} else {
+		req.Messages = msgs
+	}
+	return req
+}
+
+// checkBudget refuses a request whose estimated cost is above its MaxCostUSD.
+func checkBudget(req ports.LLMRequest, est *TokenEstimate) error {
+	if req.MaxCostUSD > 0 && est.CostUSD > req.MaxCostUSD {
+		return fmt.Errorf("%w: $%.6f estimated, $%.6f allowed", ErrOverBudget, est.CostUSD, req.MaxCostUSD)
+	}
+	return nil
+}
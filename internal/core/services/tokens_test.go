@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// wordTokenizer counts one token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Count(provider, model, text string) ports.TokenCount {
+	return ports.TokenCount{Tokens: len(strings.Fields(text)), Encoding: "words", Exact: true}
+}
+
+func (wordTokenizer) Truncate(provider, model, text string, maxTokens int) string {
+	words := strings.Fields(text)
+	return strings.Join(words[:min(maxTokens, len(words))], " ")
+}
+
+func TestLLMService_ContextWindow(t *testing.T) {
+	repo := newTestRepo(t)
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "small", Provider: "gemini", ContextWindow: 40, InputCostPer1K: 1, OutputCostPer1K: 2},
+	}, nil, map[string]string{"gemini": "small"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	provider := &mockProvider{name: "gemini"}
+	newService := func(overflow string) *LLMService {
+		cfg := &config.Config{Limits: config.LimitConfig{ContextOverflow: overflow}}
+		return NewLLMService(cfg, repo, nil, WithCatalog(models), WithTokenizer(wordTokenizer{}), WithProvider("gemini", provider))
+	}
+	ctx := context.Background()
+
+	// 25 tokens: 3 per message, 3 for the reply and one per word.
+	conversation := []ports.Message{
+		{Role: ports.RoleSystem, Content: "be brief"},
+		{Role: ports.RoleUser, Content: "first question here"},
+		{Role: ports.RoleAssistant, Content: "first answer here"},
+		{Role: ports.RoleUser, Content: "second question"},
+	}
+	req := ports.LLMRequest{UserID: "user-123", Messages: conversation, MaxTokens: 24}
+
+	_, _, err = newService("").ProcessRequest(ctx, req, "gemini")
+	var invalid *ports.ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	want := []ports.FieldError{{Field: "messages", Message: "is 25 tokens, which with max_tokens of 24 exceeds the 40 token context window of small"}}
+	if !reflect.DeepEqual(invalid.Fields, want) {
+		t.Fatalf("expected %+v, got %+v", want, invalid.Fields)
+	}
+
+	svc := newService(OverflowTruncate)
+	if _, _, err := svc.ProcessRequest(ctx, req, "gemini"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := provider.lastRequest().Messages; !reflect.DeepEqual(got, []ports.Message{conversation[0], conversation[3]}) {
+		t.Fatalf("expected the oldest turns dropped, got %+v", got)
+	}
+
+	prompt := strings.Repeat("word ", 50)
+	if _, _, err := svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: prompt}, "gemini"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(strings.Fields(provider.lastRequest().Prompt)); got != 34 {
+		t.Fatalf("expected the prompt cut to 34 words, got %d", got)
+	}
+
+	// 9 prompt tokens at $1/1K and 30 output tokens at $2/1K.
+	_, _, err = svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "one two three", MaxTokens: 30, MaxCostUSD: 0.05}, "gemini")
+	if !errors.Is(err, ErrOverBudget) || !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Fatalf("expected the estimate to exceed the budget, got %v", err)
+	}
+}
+
+func TestLLMService_Tokenize(t *testing.T) {
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "small", Provider: "gemini", ContextWindow: 40, InputCostPer1K: 1, OutputCostPer1K: 2},
+	}, map[string]string{"fast": "small"}, map[string]string{"gemini": "small"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	svc := NewLLMService(&config.Config{}, nil, nil, WithCatalog(models), WithTokenizer(wordTokenizer{}), WithProvider("gemini", &mockProvider{name: "gemini"}))
+
+	req := ports.LLMRequest{Prompt: "one two three", MaxTokens: 1000}
+	for _, model := range []string{"", "fast"} {
+		req.Model = model
+		est, err := svc.Tokenize(req, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := &TokenEstimate{
+			Provider:      "gemini",
+			Model:         "small",
+			TokenCount:    ports.TokenCount{Tokens: 9, Encoding: "words", Exact: true},
+			ContextWindow: 40,
+			CostUSD:       0.009 + 2,
+		}
+		if !reflect.DeepEqual(est, want) {
+			t.Fatalf("model %q: expected %+v, got %+v", model, want, est)
+		}
+	}
+
+	if _, err := svc.Tokenize(ports.LLMRequest{Prompt: "hi", Model: "huge"}, ""); !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("expected unknown model error, got %v", err)
+	}
+	if _, err := svc.Tokenize(ports.LLMRequest{}, ""); !errors.Is(err, ports.ErrInvalidArgument) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
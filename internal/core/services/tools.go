@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// ErrInvalidTools is returned when a request's tools, tool choice or tool
+// messages are malformed.
+var ErrInvalidTools = ports.NewError(ports.ErrInvalidArgument, "invalid tools")
+
+// validateTools checks the parts of a request that providers would otherwise
+// reject with less helpful errors, so both providers fail the same way.
+func validateTools(req ports.LLMRequest) error {
+	names := make(map[string]bool, len(req.Tools))
+	for _, tool := range req.Tools {
+		if tool.Name == "" {
+			return fmt.Errorf("%w: every tool needs a name", ErrInvalidTools)
+		}
+		if names[tool.Name] {
+			return fmt.Errorf("%w: duplicate tool %q", ErrInvalidTools, tool.Name)
+		}
+		names[tool.Name] = true
+		if len(tool.Parameters) > 0 {
+			var schema map[string]any
+			if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+				return fmt.Errorf("%w: parameters of %q must be a JSON Schema object", ErrInvalidTools, tool.Name)
+			}
+		}
+	}
+
+	switch req.ToolChoice {
+	case "", ports.ToolChoiceAuto, ports.ToolChoiceNone:
+	case ports.ToolChoiceRequired:
+		if len(req.Tools) == 0 {
+			return fmt.Errorf("%w: tool_choice %q needs tools", ErrInvalidTools, req.ToolChoice)
+		}
+	default:
+		if !names[req.ToolChoice] {
+			return fmt.Errorf("%w: tool_choice %q is not a declared tool", ErrInvalidTools, req.ToolChoice)
+		}
+	}
+
+	for _, m := range req.Messages {
+		if m.Role == ports.RoleTool && m.ToolCallID == "" {
+			return fmt.Errorf("%w: tool messages need a tool_call_id", ErrInvalidTools)
+		}
+		for _, call := range m.ToolCalls {
+			if call.ID == "" || call.Name == "" {
+				return fmt.Errorf("%w: tool calls need an id and a name", ErrInvalidTools)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// tracer reports to whichever provider main installed; without one spans
+// cost next to nothing.
+var tracer = otel.Tracer("github.com/willexm1/go-llm-nexus/internal/core/services")
+
+// endSpan records err, if any, and ends span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startProviderSpan opens a client span for a call to provider name,
+// following the OpenTelemetry GenAI semantic conventions.
+func (s *LLMService) startProviderSpan(ctx context.Context, name string, req ports.LLMRequest) (context.Context, trace.Span) {
+	spanName := "chat"
+	if req.Model != "" {
+		spanName += " " + req.Model
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("gen_ai.system", name),
+		attribute.String("gen_ai.operation.name", "chat"),
+	}
+	if req.Model != "" {
+		attrs = append(attrs, attribute.String("gen_ai.request.model", req.Model))
+	}
+	if req.MaxTokens > 0 {
+		attrs = append(attrs, attribute.Int("gen_ai.request.max_tokens", int(req.MaxTokens)))
+	}
+	if req.Temperature > 0 {
+		attrs = append(attrs, attribute.Float64("gen_ai.request.temperature", float64(req.Temperature)))
+	}
+	return tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endProviderSpan adds the response model and token usage to a span from
+// startProviderSpan and ends it.
+func endProviderSpan(span trace.Span, resp *ports.LLMResponse, err error) {
+	if resp != nil {
+		if resp.Model != "" {
+			span.SetAttributes(attribute.String("gen_ai.response.model", resp.Model))
+		}
+		if resp.FinishReason != "" {
+			span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", []string{resp.FinishReason}))
+		}
+		if u := resp.Usage; u != nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", int(u.PromptTokens)),
+				attribute.Int("gen_ai.usage.output_tokens", int(u.CompletionTokens)),
+			)
+		}
+	}
+	endSpan(span, err)
+}
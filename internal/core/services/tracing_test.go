@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestLLMService_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	repo := newTestRepo(t)
+	cache := &mockCache{data: make(map[string]string)}
+	svc := NewLLMService(&config.Config{}, repo, cache, WithProvider("mock", &mockProvider{name: "mock"}))
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	if _, _, err := svc.ProcessRequest(ctx, ports.LLMRequest{UserID: "user-123", Prompt: "Hello", MaxTokens: 64}, "mock"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root.End()
+
+	// The request log is written in the background.
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for _, s := range recorder.Ended() {
+			spans[s.Name()] = s
+		}
+		if spans["LLMService.logRequest"] != nil {
+			break
+		}
+	}
+
+	process := spans["LLMService.ProcessRequest"]
+	if process == nil || process.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Fatalf("expected ProcessRequest to continue the caller's trace, got %v", spans)
+	}
+	for _, name := range []string{"LLMService.ensureUser", "LLMService.lookupCache", "chat", "LLMService.logRequest"} {
+		s := spans[name]
+		if s == nil {
+			t.Fatalf("missing span %s", name)
+		}
+		if s.Parent().SpanID() != process.SpanContext().SpanID() {
+			t.Fatalf("expected %s to be a child of ProcessRequest", name)
+		}
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans["chat"].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["gen_ai.system"].AsString() != "mock" || attrs["gen_ai.request.max_tokens"].AsInt64() != 64 ||
+		attrs["gen_ai.usage.input_tokens"].AsInt64() != 5 || attrs["gen_ai.usage.output_tokens"].AsInt64() != 10 {
+		t.Fatalf("unexpected provider span attributes: %v", attrs)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const defaultMaxPromptBytes = 1 << 20
+
+// providerMaxTemperature is the highest temperature each provider's API
+// accepts. Catalog models may lower it with max_temperature.
+var providerMaxTemperature = map[string]float32{
+	"openai": 2,
+	"gemini": 2,
+}
+
+// fieldErrors collects the invalid fields of a request.
+type fieldErrors []ports.FieldError
+
+func (f *fieldErrors) add(field, format string, args ...any) {
+	*f = append(*f, ports.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &ports.ValidationError{Fields: f}
+}
+
+// validateRequest checks what can be checked before a model is chosen: that
+// there is a prompt within the size limit, and that the sampling settings
+// are not negative.
+func (s *LLMService) validateRequest(req ports.LLMRequest) error {
+	var errs fieldErrors
+	field := "prompt"
+	if len(req.Messages) > 0 {
+		field = "messages"
+	}
+	text := promptText(req)
+	switch {
+	case len(req.Messages) == 0 && req.Prompt == "":
+		errs.add(field, "is required")
+	case len(text) > s.maxPromptBytes:
+		errs.add(field, "exceeds %d bytes", s.maxPromptBytes)
+	}
+	if req.Temperature < 0 {
+		errs.add("temperature", "must not be negative")
+	}
+	if req.MaxTokens < 0 {
+		errs.add("max_tokens", "must not be negative")
+	}
+	return errs.err()
+}
+
+// validateForModel checks the sampling settings against the limits of the
+// model that will serve the request.
+func (s *LLMService) validateForModel(req ports.LLMRequest, info ports.ModelInfo) error {
+	var errs fieldErrors
+	maxTemperature := providerMaxTemperature[info.Provider]
+	if info.MaxTemperature > 0 {
+		maxTemperature = info.MaxTemperature
+	}
+	if maxTemperature > 0 && req.Temperature > maxTemperature {
+		errs.add("temperature", "must be between 0 and %g for %s", maxTemperature, modelName(info))
+	}
+	switch {
+	case info.MaxOutputTokens > 0 && req.MaxTokens > info.MaxOutputTokens:
+		errs.add("max_tokens", "must be at most %d for %s", info.MaxOutputTokens, modelName(info))
+	case info.ContextWindow > 0 && req.MaxTokens > info.ContextWindow:
+		errs.add("max_tokens", "exceeds the %d token context window of %s", info.ContextWindow, modelName(info))
+	}
+	return errs.err()
+}
+
+// servingModel describes the model upstream on provider, or the provider's
+// default model when upstream is empty. Models outside the catalog are
+// described by their IDs alone.
+func (s *LLMService) servingModel(provider, upstream string) ports.ModelInfo {
+	info, ok := s.catalog.Lookup(provider, upstream)
+	if upstream == "" {
+		info, ok = s.catalog.Default(provider)
+	}
+	if !ok {
+		info = ports.ModelInfo{ID: upstream, Provider: provider, UpstreamID: upstream}
+	}
+	return info
+}
+
+// modelName names a model in validation messages.
+func modelName(info ports.ModelInfo) string {
+	if info.ID == "" {
+		return info.Provider
+	}
+	return info.ID
+}
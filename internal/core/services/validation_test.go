@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+	"github.com/willexm1/go-llm-nexus/internal/core/catalog"
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestLLMService_Validation(t *testing.T) {
+	repo := newTestRepo(t)
+	models, err := catalog.New([]ports.ModelInfo{
+		{ID: "gpt-4o", Provider: "openai", ContextWindow: 128000, MaxOutputTokens: 16384},
+		{ID: "careful", Provider: "openai", MaxTemperature: 1},
+		{ID: "gemini-flash", Provider: "gemini", ContextWindow: 8192},
+	}, nil, map[string]string{"openai": "gpt-4o", "gemini": "gemini-flash"})
+	if err != nil {
+		t.Fatalf("failed to build catalog: %v", err)
+	}
+	cfg := &config.Config{Limits: config.LimitConfig{MaxPromptBytes: 16}}
+	svc := NewLLMService(cfg, repo, nil, WithCatalog(models),
+		WithProvider("openai", &mockProvider{name: "openai"}),
+		WithProvider("gemini", &mockProvider{name: "gemini"}))
+
+	tests := []struct {
+		name     string
+		req      ports.LLMRequest
+		provider string
+		want     []ports.FieldError
+	}{
+		{name: "valid", req: ports.LLMRequest{Prompt: "Hello", Temperature: 2, MaxTokens: 16384}, provider: "openai"},
+		{name: "empty prompt", req: ports.LLMRequest{}, want: []ports.FieldError{
+			{Field: "prompt", Message: "is required"},
+		}},
+		{name: "prompt too large", req: ports.LLMRequest{Prompt: "a prompt that is far too long"}, want: []ports.FieldError{
+			{Field: "prompt", Message: "exceeds 16 bytes"},
+		}},
+		{name: "negative settings", req: ports.LLMRequest{Prompt: "Hello", Temperature: -1, MaxTokens: -5}, want: []ports.FieldError{
+			{Field: "temperature", Message: "must not be negative"},
+			{Field: "max_tokens", Message: "must not be negative"},
+		}},
+		{name: "over the provider range", req: ports.LLMRequest{Prompt: "Hello", Temperature: 2.5}, provider: "gemini", want: []ports.FieldError{
+			{Field: "temperature", Message: "must be between 0 and 2 for gemini-flash"},
+		}},
+		{name: "over the model range", req: ports.LLMRequest{Prompt: "Hello", Model: "careful", Temperature: 1.5}, want: []ports.FieldError{
+			{Field: "temperature", Message: "must be between 0 and 1 for careful"},
+		}},
+		{name: "over the output limit", req: ports.LLMRequest{Prompt: "Hello", MaxTokens: 20000}, provider: "openai", want: []ports.FieldError{
+			{Field: "max_tokens", Message: "must be at most 16384 for gpt-4o"},
+		}},
+		{name: "over the context window", req: ports.LLMRequest{Prompt: "Hello", MaxTokens: 10000}, provider: "gemini", want: []ports.FieldError{
+			{Field: "max_tokens", Message: "exceeds the 8192 token context window of gemini-flash"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.UserID = "user-123"
+			_, _, err := svc.ProcessRequest(context.Background(), tt.req, tt.provider)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *ports.ValidationError
+			if !errors.As(err, &invalid) || !errors.Is(err, ports.ErrInvalidArgument) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(invalid.Fields, tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, invalid.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// checkWebhookURL refuses webhook URLs that are not https, not on the
+// allow-list, or that resolve to an address inside the network, so jobs
+// cannot be used to probe it.
+func (s *JobService) checkWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: webhook_url must be an absolute https URL", ErrInvalidJob)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(s.webhookAllowedHosts) > 0 && !slices.Contains(s.webhookAllowedHosts, host) {
+		return fmt.Errorf("%w: webhook host %q is not on the allow-list", ErrInvalidJob, host)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: webhook host %q does not resolve", ErrInvalidJob, host)
+	}
+	for _, addr := range addrs {
+		if !s.webhookAddrAllowed(addr) {
+			return fmt.Errorf("%w: webhook host %q is not a public address", ErrInvalidJob, host)
+		}
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is reachable on the internet, as opposed
+// to loopback, private, link-local or otherwise reserved.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast()
+}
+
+// newWebhookClient builds the client webhooks are sent with. Addresses are
+// checked again as they are dialled, since DNS may answer differently than
+// it did at submission, and redirects are not followed.
+func (s *JobService) newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !s.webhookAddrAllowed(addrPort.Addr()) {
+				return errors.New("webhook address is not public")
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled instead of the webhook, bypassing the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
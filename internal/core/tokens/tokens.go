@@ -0,0 +1,145 @@
+// Package tokens counts the tokens of prompts before they are sent. OpenAI
+// models are counted with their BPE encodings; other providers do not publish
+// their tokenizers, so their counts are estimated from the text.
+package tokens
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tiktoken-go/tokenizer"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+const (
+	EncodingO200k  = string(tokenizer.O200kBase)
+	EncodingCl100k = string(tokenizer.Cl100kBase)
+	// EncodingEstimate marks heuristic counts.
+	EncodingEstimate = "estimate"
+)
+
+// openAIEncodings maps OpenAI model ID prefixes to their encodings. Longer
+// prefixes come first so "gpt-4o" is not taken for "gpt-4".
+var openAIEncodings = []struct {
+	prefix   string
+	encoding tokenizer.Encoding
+}{
+	{"gpt-4o", tokenizer.O200kBase},
+	{"chatgpt-4o", tokenizer.O200kBase},
+	{"gpt-4.1", tokenizer.O200kBase},
+	{"gpt-4.5", tokenizer.O200kBase},
+	{"gpt-5", tokenizer.O200kBase},
+	{"o1", tokenizer.O200kBase},
+	{"o3", tokenizer.O200kBase},
+	{"o4", tokenizer.O200kBase},
+	{"gpt-4", tokenizer.Cl100kBase},
+	{"gpt-3.5", tokenizer.Cl100kBase},
+	{"gpt-35", tokenizer.Cl100kBase},
+	{"text-embedding-", tokenizer.Cl100kBase},
+}
+
+// Counter is a ports.Tokenizer. Encodings are loaded on first use, which
+// takes a moment for their vocabularies.
+type Counter struct {
+	mu     sync.Mutex
+	codecs map[tokenizer.Encoding]tokenizer.Codec
+}
+
+func New() *Counter {
+	return &Counter{codecs: make(map[tokenizer.Encoding]tokenizer.Codec)}
+}
+
+func (c *Counter) Count(provider, model, text string) ports.TokenCount {
+	enc, exact := encodingFor(provider, model)
+	if enc != "" {
+		if n, err := c.codec(enc).Count(text); err == nil {
+			return ports.TokenCount{Tokens: n, Encoding: string(enc), Exact: exact}
+		}
+	}
+	return ports.TokenCount{Tokens: estimate(text), Encoding: EncodingEstimate}
+}
+
+func (c *Counter) Truncate(provider, model, text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	enc, _ := encodingFor(provider, model)
+	if enc == "" {
+		return truncateEstimate(text, maxTokens)
+	}
+	_, pieces, err := c.codec(enc).Encode(text)
+	if err != nil {
+		return truncateEstimate(text, maxTokens)
+	}
+	if len(pieces) <= maxTokens {
+		return text
+	}
+	// Tokens are consecutive byte ranges of text, but may end inside a
+	// character; a partial character is dropped.
+	n := 0
+	for _, p := range pieces[:maxTokens] {
+		n += len(p)
+	}
+	return strings.ToValidUTF8(text[:n], "")
+}
+
+func (c *Counter) codec(enc tokenizer.Encoding) tokenizer.Codec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	codec, ok := c.codecs[enc]
+	if !ok {
+		// Only encodings from openAIEncodings are requested, and all exist.
+		codec, _ = tokenizer.Get(enc)
+		c.codecs[enc] = codec
+	}
+	return codec
+}
+
+// encodingFor returns the encoding of an OpenAI model and whether it is
+// known to be the model's. Unknown OpenAI models are counted with the
+// newest encoding; other providers have none.
+func encodingFor(provider, model string) (tokenizer.Encoding, bool) {
+	if provider != "openai" {
+		return "", false
+	}
+	model = strings.TrimPrefix(model, "ft:")
+	for _, e := range openAIEncodings {
+		if strings.HasPrefix(model, e.prefix) {
+			return e.encoding, true
+		}
+	}
+	return tokenizer.O200kBase, false
+}
+
+// estimate assumes four ASCII characters per token, as is typical of
+// English text, and one token per other character, which errs high for
+// accented Latin text and is close for CJK.
+func estimate(text string) int {
+	units := 0
+	for _, r := range text {
+		units += runeUnits(r)
+	}
+	return (units + 3) / 4
+}
+
+func truncateEstimate(text string, maxTokens int) string {
+	units := 0
+	for i, r := range text {
+		units += runeUnits(r)
+		if units > maxTokens*4 {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// runeUnits is the share of a token a character is estimated at, in
+// quarters.
+func runeUnits(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 4
+}
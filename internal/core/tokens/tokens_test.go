@@ -0,0 +1,51 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+func TestCounter_Count(t *testing.T) {
+	c := New()
+	tests := []struct {
+		provider, model, text string
+		want                  ports.TokenCount
+	}{
+		{"openai", "gpt-4", "tiktoken is great!", ports.TokenCount{Tokens: 6, Encoding: EncodingCl100k, Exact: true}},
+		{"openai", "gpt-3.5-turbo", "hello world", ports.TokenCount{Tokens: 2, Encoding: EncodingCl100k, Exact: true}},
+		{"openai", "gpt-4o-mini", "日本語のテキスト", ports.TokenCount{Tokens: 6, Encoding: EncodingO200k, Exact: true}},
+		{"openai", "ft:gpt-4o-mini:acme::abc123", "hello world", ports.TokenCount{Tokens: 2, Encoding: EncodingO200k, Exact: true}},
+		{"openai", "some-future-model", "hello world", ports.TokenCount{Tokens: 2, Encoding: EncodingO200k}},
+		{"gemini", "gemini-2.0-flash", "abcdefgh", ports.TokenCount{Tokens: 2, Encoding: EncodingEstimate}},
+		{"gemini", "gemini-2.0-flash", "abcdefghi", ports.TokenCount{Tokens: 3, Encoding: EncodingEstimate}},
+		{"gemini", "gemini-2.0-flash", "日本", ports.TokenCount{Tokens: 2, Encoding: EncodingEstimate}},
+	}
+	for _, tt := range tests {
+		if got := c.Count(tt.provider, tt.model, tt.text); got != tt.want {
+			t.Errorf("Count(%s, %s, %q) = %+v, want %+v", tt.provider, tt.model, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCounter_Truncate(t *testing.T) {
+	c := New()
+	tests := []struct {
+		provider, model, text string
+		max                   int
+		want                  string
+	}{
+		{"openai", "gpt-4", "tiktoken is great!", 3, "tiktoken"},
+		{"openai", "gpt-4", "tiktoken is great!", 6, "tiktoken is great!"},
+		// The third token ends inside a character, which is dropped.
+		{"openai", "gpt-4", "日本語のテキスト", 3, "日本"},
+		{"gemini", "", "abcdefghij", 2, "abcdefgh"},
+		{"gemini", "", "日本語", 2, "日本"},
+		{"gemini", "", "anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := c.Truncate(tt.provider, tt.model, tt.text, tt.max); got != tt.want {
+			t.Errorf("Truncate(%s, %q, %d) = %q, want %q", tt.provider, tt.text, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// Calculator evaluates arithmetic expressions so the model does not have to.
+type Calculator struct{}
+
+func (Calculator) Schema() ports.Tool {
+	return ports.Tool{
+		Name:        "calculator",
+		Description: "Evaluate an arithmetic expression. Supports + - * / %, parentheses and sqrt, pow, abs, min, max, floor, ceil, round.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"expression":{"type":"string","description":"For example (3 + 4) * pow(2, 10)"}},"required":["expression"]}`),
+	}
+}
+
+func (Calculator) Invoke(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil || in.Expression == "" {
+		return "", fmt.Errorf("expression is required")
+	}
+	// Go's expression grammar covers arithmetic; evaluating the tree
+	// ourselves keeps anything but numbers and the functions above out.
+	expr, err := parser.ParseExpr(in.Expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid expression: %w", err)
+	}
+	v, err := eval(expr)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64), nil
+}
+
+func eval(expr ast.Expr) (float64, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return 0, fmt.Errorf("unsupported literal %s", e.Value)
+		}
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.ParenExpr:
+		return eval(e.X)
+	case *ast.UnaryExpr:
+		x, err := eval(e.X)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x, nil
+		case token.SUB:
+			return -x, nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", e.Op)
+	case *ast.BinaryExpr:
+		x, err := eval(e.X)
+		if err != nil {
+			return 0, err
+		}
+		y, err := eval(e.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return x / y, nil
+		case token.REM:
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return math.Mod(x, y), nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", e.Op)
+	case *ast.CallExpr:
+		name, ok := e.Fun.(*ast.Ident)
+		if !ok {
+			return 0, fmt.Errorf("unsupported function call")
+		}
+		args := make([]float64, len(e.Args))
+		for i, a := range e.Args {
+			v, err := eval(a)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		return call(name.Name, args)
+	}
+	return 0, fmt.Errorf("unsupported expression")
+}
+
+func call(name string, args []float64) (float64, error) {
+	unary := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+	}
+	binary := map[string]func(float64, float64) float64{
+		"pow": math.Pow,
+		"min": math.Min,
+		"max": math.Max,
+	}
+	if f, ok := unary[name]; ok {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s takes 1 argument", name)
+		}
+		return f(args[0]), nil
+	}
+	if f, ok := binary[name]; ok {
+		if len(args) != 2 {
+			return 0, fmt.Errorf("%s takes 2 arguments", name)
+		}
+		return f(args[0], args[1]), nil
+	}
+	return 0, fmt.Errorf("unknown function %s", name)
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// Clock tells the model the current date and time, which it cannot know.
+type Clock struct {
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+func (Clock) Schema() ports.Tool {
+	return ports.Tool{
+		Name:        "clock",
+		Description: "Current date and time, in UTC or an IANA time zone.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"timezone":{"type":"string","description":"IANA zone such as Africa/Lagos; defaults to UTC"}}}`),
+	}
+}
+
+func (c Clock) Invoke(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Timezone string `json:"timezone"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &in); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	loc := time.UTC
+	if in.Timezone != "" {
+		l, err := time.LoadLocation(in.Timezone)
+		if err != nil {
+			return "", fmt.Errorf("unknown timezone %q", in.Timezone)
+		}
+		loc = l
+	}
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	t := now().In(loc)
+	return fmt.Sprintf("%s (%s)", t.Format(time.RFC3339), t.Weekday()), nil
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// maxFetchBytes caps how much of a page is handed back to the model.
+const maxFetchBytes = 64 * 1024
+
+// HTTPFetch lets the model GET pages from an allow-list of hosts. Anything
+// else, including redirects off the list, is refused.
+type HTTPFetch struct {
+	allowedHosts []string
+	client       *http.Client
+}
+
+// NewHTTPFetch allows the given hosts (exact matches, compared without port
+// and case-insensitively).
+func NewHTTPFetch(allowedHosts []string) *HTTPFetch {
+	f := &HTTPFetch{}
+	for _, h := range allowedHosts {
+		f.allowedHosts = append(f.allowedHosts, strings.ToLower(h))
+	}
+	f.client = &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return f.check(req.URL)
+		},
+	}
+	return f
+}
+
+func (f *HTTPFetch) Schema() ports.Tool {
+	return ports.Tool{
+		Name:        "http_fetch",
+		Description: "Fetch a web page with GET. Only these hosts are allowed: " + strings.Join(f.allowedHosts, ", "),
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"Absolute http or https URL"}},"required":["url"]}`),
+	}
+}
+
+func (f *HTTPFetch) Invoke(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil || in.URL == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(in.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if err := f.check(u); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, body), nil
+}
+
+func (f *HTTPFetch) check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed", u.Scheme)
+	}
+	if !slices.Contains(f.allowedHosts, strings.ToLower(u.Hostname())) {
+		return fmt.Errorf("host %q is not on the allow-list", u.Hostname())
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package tools holds the tools the gateway can run itself during agent
+// runs, as opposed to tool calls that are handed back to the caller.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/willexm1/go-llm-nexus/internal/core/ports"
+)
+
+// ErrUnknownTool is returned when a tool is not in the registry.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Tool is a server-side tool. Schema describes it to the model; Invoke runs
+// it with the model's JSON arguments and returns the text sent back as the
+// tool result.
+type Tool interface {
+	Schema() ports.Tool
+	Invoke(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Registry is the set of tools available to agent runs, keyed by name.
+type Registry struct {
+	tools map[string]Tool
+}
+
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool)}
+	for _, t := range tools {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register adds t, replacing any tool with the same name.
+func (r *Registry) Register(t Tool) {
+	r.tools[t.Schema().Name] = t
+}
+
+func (r *Registry) Get(name string) (Tool, bool) {
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Definitions returns the schemas of the named tools, or of every tool when
+// no names are given, sorted by name.
+func (r *Registry) Definitions(names ...string) ([]ports.Tool, error) {
+	if len(names) == 0 {
+		for name := range r.tools {
+			names = append(names, name)
+		}
+	}
+	defs := make([]ports.Tool, 0, len(names))
+	for _, name := range names {
+		t, ok := r.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTool, name)
+		}
+		defs = append(defs, t.Schema())
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
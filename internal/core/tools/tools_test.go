@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalculator(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{expr: "(3 + 4) * 2", want: "14"},
+		{expr: "-2.5 + pow(2, 10)", want: "1021.5"},
+		{expr: "sqrt(16) / 8 % 3", want: "0.5"},
+		{expr: "max(1, min(5, 3))", want: "3"},
+		{expr: "1 / 0", wantErr: true},
+		{expr: "os.Exit(1)", wantErr: true},
+		{expr: `"hi"`, wantErr: true},
+		{expr: "1 +", wantErr: true},
+	}
+	for _, tt := range tests {
+		args, _ := json.Marshal(map[string]string{"expression": tt.expr})
+		got, err := Calculator{}.Invoke(context.Background(), args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.expr, tt.want, got, err)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := Clock{Now: func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) }}
+	got, err := clock.Invoke(context.Background(), json.RawMessage(`{"timezone":"Africa/Lagos"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "2025-03-01T13:00:00+01:00 (Saturday)" {
+		t.Fatalf("unexpected time: %s", got)
+	}
+	if _, err := clock.Invoke(context.Background(), json.RawMessage(`{"timezone":"Mars/Olympus"}`)); err == nil {
+		t.Fatalf("expected an error for an unknown zone")
+	}
+}
+
+func TestHTTPFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://example.invalid/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+	host, _ := url.Parse(srv.URL)
+
+	fetch := NewHTTPFetch([]string{host.Hostname()})
+	invoke := func(u string) (string, error) {
+		args, _ := json.Marshal(map[string]string{"url": u})
+		return fetch.Invoke(context.Background(), args)
+	}
+
+	got, err := invoke(srv.URL + "/page")
+	if err != nil || !strings.Contains(got, "HTTP 200") || !strings.Contains(got, "hello") {
+		t.Fatalf("expected the page, got %q (%v)", got, err)
+	}
+	if _, err := invoke("http://example.invalid/"); err == nil {
+		t.Fatalf("expected hosts off the allow-list to be refused")
+	}
+	if _, err := invoke("file:///etc/passwd"); err == nil {
+		t.Fatalf("expected non-http schemes to be refused")
+	}
+	if _, err := invoke(srv.URL + "/redirect"); err == nil {
+		t.Fatalf("expected redirects off the allow-list to be refused")
+	}
+}
+
+func TestRegistry_Definitions(t *testing.T) {
+	r := NewRegistry(Clock{}, Calculator{})
+	defs, err := r.Definitions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(defs) != 2 || defs[0].Name != "calculator" || defs[1].Name != "clock" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+	if _, err := r.Definitions("clock", "teleport"); err == nil {
+		t.Fatalf("expected an unknown tool error")
+	}
+}
@@ -0,0 +1,167 @@
+// Package logging configures the process-wide slog logger: its level and
+// format, the request ID carried by contexts, and how user content in log
+// lines is redacted.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+// Policy decides how prompt and response text appears in logs.
+type Policy string
+
+const (
+	// RedactNone keeps text as it is.
+	RedactNone Policy = "none"
+	// RedactTruncate keeps the first characters of the text.
+	RedactTruncate Policy = "truncate"
+	// RedactHash replaces the text with a hash, which still shows whether
+	// two requests carried the same text.
+	RedactHash Policy = "hash"
+)
+
+const defaultTruncateLength = 50
+
+// Redactor applies a Policy.
+type Redactor struct {
+	Policy Policy
+	// Length is how many characters RedactTruncate keeps.
+	Length int
+}
+
+// NewRedactor checks policy, which may be empty for RedactNone.
+func NewRedactor(policy string, length int) (Redactor, error) {
+	p := Policy(policy)
+	switch p {
+	case "":
+		p = RedactNone
+	case RedactNone, RedactTruncate, RedactHash:
+	default:
+		return Redactor{}, fmt.Errorf("unknown redaction policy %q: expected none, truncate or hash", policy)
+	}
+	if length <= 0 {
+		length = defaultTruncateLength
+	}
+	return Redactor{Policy: p, Length: length}, nil
+}
+
+// Apply redacts text. Empty text stays empty under every policy.
+func (r Redactor) Apply(text string) string {
+	if text == "" {
+		return ""
+	}
+	switch r.Policy {
+	case RedactTruncate:
+		length := r.Length
+		if length <= 0 {
+			length = defaultTruncateLength
+		}
+		if utf8.RuneCountInString(text) <= length {
+			return text
+		}
+		runes := []rune(text)
+		return fmt.Sprintf("%s… [%d chars]", string(runes[:length]), len(runes))
+	case RedactHash:
+		sum := sha256.Sum256([]byte(text))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return text
+}
+
+// content is user-supplied text awaiting redaction by the handler.
+type content string
+
+// Content is an attribute for prompt or response text. The logger built
+// by New redacts it according to LOG_REDACTION.
+func Content(key, text string) slog.Attr {
+	return slog.Any(key, content(text))
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log lines carry id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New builds a logger writing to w as cfg describes.
+func New(w io.Writer, cfg config.LogConfig) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", cfg.Level, err)
+		}
+	}
+	redactor, err := NewRedactor(cfg.Redaction, cfg.RedactLength)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_REDACTION: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT %q: expected json or text", cfg.Format)
+	}
+	return slog.New(&handler{next: h, redactor: redactor}), nil
+}
+
+// handler adds the context's request ID to every record and redacts
+// Content attributes.
+type handler struct {
+	next     slog.Handler
+	redactor Redactor
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if id := RequestID(ctx); id != "" {
+		out.AddAttrs(slog.String("request_id", id))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &handler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *handler) redact(a slog.Attr) slog.Attr {
+	if c, ok := a.Value.Any().(content); ok {
+		return slog.String(a.Key, h.redactor.Apply(string(c)))
+	}
+	return a
+}
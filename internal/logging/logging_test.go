@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/willexm1/go-llm-nexus/internal/config"
+)
+
+func TestRedactor(t *testing.T) {
+	text := strings.Repeat("é", 60)
+	tests := []struct {
+		policy string
+		want   func(string) bool
+	}{
+		{"none", func(got string) bool { return got == text }},
+		{"truncate", func(got string) bool {
+			return strings.HasPrefix(got, strings.Repeat("é", 10)+"…") && strings.HasSuffix(got, "[60 chars]")
+		}},
+		{"hash", func(got string) bool { return strings.HasPrefix(got, "sha256:") && len(got) == len("sha256:")+16 }},
+	}
+	for _, tt := range tests {
+		r, err := NewRedactor(tt.policy, 10)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.policy, err)
+		}
+		if got := r.Apply(text); !tt.want(got) {
+			t.Fatalf("%s: unexpected redaction %q", tt.policy, got)
+		}
+		if got := r.Apply(""); got != "" {
+			t.Fatalf("%s: expected empty text to stay empty, got %q", tt.policy, got)
+		}
+	}
+	if _, err := NewRedactor("mask", 0); err == nil {
+		t.Fatal("expected an unknown policy to be rejected")
+	}
+	short, _ := NewRedactor("truncate", 10)
+	if got := short.Apply("hello"); got != "hello" {
+		t.Fatalf("expected short text to be kept, got %q", got)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, config.LogConfig{Level: "warn", Format: "json", Redaction: "hash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	logger.InfoContext(ctx, "dropped below the level")
+	logger.With(Content("system", "be brief")).WarnContext(ctx, "slow request", Content("prompt", "my secret"), slog.Int("attempt", 2))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one line, got %q", buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("expected JSON output: %v", err)
+	}
+	if entry["request_id"] != "req-1" || entry["attempt"] != float64(2) {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+	for _, key := range []string{"prompt", "system"} {
+		if v, _ := entry[key].(string); !strings.HasPrefix(v, "sha256:") {
+			t.Fatalf("expected %s to be hashed, got %v", key, entry[key])
+		}
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatal("prompt text leaked into the log")
+	}
+
+	if _, err := New(&buf, config.LogConfig{Level: "loud"}); err == nil {
+		t.Fatal("expected an invalid level to be rejected")
+	}
+}